@@ -2,12 +2,24 @@ package main
 
 import (
 	"context"
+	"errors"
 	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"teslamate-cyberui/internal/alert"
 	"teslamate-cyberui/internal/config"
+	"teslamate-cyberui/internal/digest"
 	"teslamate-cyberui/internal/handler"
 	"teslamate-cyberui/internal/logger"
 	"teslamate-cyberui/internal/middleware"
 	"teslamate-cyberui/internal/mqtt"
+	"teslamate-cyberui/internal/notify"
 	"teslamate-cyberui/internal/repository"
 
 	"github.com/gin-contrib/cors"
@@ -42,43 +54,50 @@ func main() {
 		applog.Info("Mock data is ENABLED. Skipping database connection.")
 	}
 
+	// 收到 SIGINT / SIGTERM 时取消 ctx，依次停止 HTTP 服务和 MQTT 客户端
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// 后台任务，关闭时等待它们退出
+	var workers sync.WaitGroup
+
 	// 初始化 MQTT
 	if !cfg.Server.EnableMock && repo != nil {
 		mqttClient, err := mqtt.NewClient(cfg.MQTT)
 		if err != nil {
 			applog.Errorf("Failed to initialize MQTT client: %v", err)
 		} else {
-			// 在后台获取车辆列表并订阅
+			// 在后台维护车辆订阅：启动时订阅全部车辆，之后定期同步新增/删除的车辆，
+			// ctx 结束后断开连接
+			workers.Add(1)
 			go func() {
-				defer mqttClient.Disconnect() // 实际上可以按需管理生命周期，此处仅起隔离作用
-
-				// 暂时简单的延时等待 DB 和其它模块准备好 (可选)
-				// time.Sleep(2 * time.Second)
-
-				// 获取所有车辆的 ID 以便订阅对应的 MQTT topic
-				// 必须通过新的 context.Background()，不要用 gin context
-				cars, err := repo.Car.GetAll(context.Background())
-				if err != nil {
-					applog.Errorf("Failed to get cars for MQTT subscription: %v", err)
-					return
-				}
-
-				var carIDs []int16
-				for _, car := range cars {
-					carIDs = append(carIDs, car.ID)
-				}
-
-				if len(carIDs) > 0 {
-					mqttClient.SubscribeCars(carIDs)
-				}
-				// 阻塞保持协程运行，如果不执行 Disconnect，其实客户端内部有自动重连和保持
-				select {}
+				defer workers.Done()
+				mqttClient.Run(ctx, repo.Car, cfg.MQTT.SyncInterval)
 			}()
 		}
 	}
 
+	// 初始化告警引擎和摘要调度器
+	var alertEngine *alert.Engine
+	if !cfg.Server.EnableMock && repo != nil {
+		dispatcher := notify.NewDispatcher(repo.Notify)
+		alertEngine = alert.NewEngine(repo.Alert, mqtt.GlobalCache, dispatcher)
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			alertEngine.Run(ctx)
+		}()
+
+		digestScheduler := digest.NewScheduler(repo, dispatcher)
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			digestScheduler.Run(ctx)
+		}()
+	}
+
 	// 初始化处理器
-	h := handler.NewHandler(repo)
+	h := handler.NewHandler(repo, alertEngine)
 
 	// 设置Gin模式
 	if cfg.Server.Mode == "release" {
@@ -95,7 +114,7 @@ func main() {
 	// CORS配置：如果配置了具体的Origin则使用，否则允许所有
 	corsConfig := cors.Config{
 		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization", "X-API-Key"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization", "X-API-Key", "X-Admin-Key"},
 		ExposeHeaders:    []string{"Content-Length"},
 		AllowCredentials: true,
 	}
@@ -116,32 +135,103 @@ func main() {
 		applog.Info("Mock data is ENABLED")
 	}
 	api.Use(middleware.MockData(cfg.Server.EnableMock))
+	if cfg.Server.AdminKey != "" {
+		applog.Info("Write mode is ENABLED for TeslaMate geofences and charge costs")
+	}
+	adminAuth := middleware.AdminKeyAuth(cfg.Server.AdminKey)
 	{
 		// 车辆相关
 		api.GET("/cars", h.GetCars)
 		api.GET("/cars/:id/status", h.GetCarStatus)
+		api.GET("/cars/:id/live", h.StreamCarLive)
+		api.GET("/cars/:id/live/history", h.GetCarLiveHistory)
+		api.GET("/cars/:id/updates", h.GetCarUpdates)
+		api.GET("/cars/:id/locations/places", h.GetVisitedPlaces)
+		api.GET("/cars/:id/locations/heatmap", h.GetLocationHeatmap)
+		api.GET("/cars/:id/locations/regions", h.GetVisitedRegions)
+		api.GET("/live/ws", h.LiveWebSocket)
 
 		// 充电相关
 		api.GET("/cars/:id/charges", h.GetCharges)
 		api.GET("/charges/:id", h.GetChargeDetail)
 		api.GET("/charges/:id/stats", h.GetChargeStats)
+		api.GET("/charges/:id/export", h.ExportChargeCurve)
 		api.GET("/cars/:id/charges/stats_summary", h.GetChargeStatsSummary)
+		api.GET("/cars/:id/charges/export", h.ExportCharges)
+		api.GET("/charges/:id/cost", h.GetChargeCostEstimate)
+		api.PUT("/charges/:id/cost", adminAuth, h.UpdateChargeCost)
+		api.GET("/cars/:id/charges/costs", h.PreviewChargeCosts)
+		api.POST("/cars/:id/charges/costs", adminAuth, h.ApplyChargeCosts)
+
+		// 电价方案相关
+		api.GET("/tariffs", h.GetTariffs)
+		api.POST("/tariffs", h.CreateTariff)
+		api.GET("/tariffs/:id", h.GetTariff)
+		api.PUT("/tariffs/:id", h.UpdateTariff)
+		api.DELETE("/tariffs/:id", h.DeleteTariff)
 
 		// 驾驶相关
 		api.GET("/cars/:id/drives", h.GetDrives)
 		api.GET("/cars/:id/drives/stats_summary", h.GetDriveStatsSummary)
 		api.GET("/cars/:id/drives/speed_histogram", h.GetSpeedHistogram)
 		api.GET("/cars/:id/drives/positions", h.GetAllDrivesPositions)
+		api.GET("/cars/:id/drives/export", h.ExportDrives)
 		api.GET("/drives/:id", h.GetDriveDetail)
 		api.GET("/drives/:id/positions", h.GetDrivePositions)
 		api.GET("/drives/:id/speed_histogram", h.GetDriveSpeedHistogram)
+		api.GET("/drives/:id/analysis", h.GetDriveAnalysis)
+		api.GET("/drives/:id/export", h.ExportDrive)
+		api.GET("/drives/:id/tag", h.GetDriveTag)
+		api.PUT("/drives/:id/tag", h.UpdateDriveTag)
+		api.DELETE("/drives/:id/tag", h.DeleteDriveTag)
+		api.PUT("/drives/tags", h.BatchUpdateDriveTags)
+
+		// 旅程相关
+		api.GET("/cars/:id/trips", h.GetTrips)
+		api.GET("/trips/:id", h.GetTrip)
+
+		// 报表相关
+		api.GET("/cars/:id/reports/mileage", h.GetMileageLog)
 
 		// 统计相关
 		api.GET("/cars/:id/stats/overview", h.GetOverviewStats)
 		api.GET("/cars/:id/stats/efficiency", h.GetEfficiencyStats)
+		api.GET("/cars/:id/stats/efficiency-factors", h.GetEfficiencyFactors)
 		api.GET("/cars/:id/stats/battery", h.GetBatteryStats)
 		api.GET("/cars/:id/stats/soc-history", h.GetSocHistory)
 		api.GET("/cars/:id/stats/states-timeline", h.GetStatesTimeline)
+		api.GET("/cars/:id/stats/vampire-drain", h.GetVampireDrain)
+		api.GET("/cars/:id/stats/projected-range", h.GetProjectedRange)
+		api.GET("/cars/:id/stats/battery-health", h.GetBatteryHealth)
+
+		// 地理围栏相关，写入接口需要管理密钥
+		api.GET("/geofences", h.GetGeofences)
+		api.GET("/geofences/:id", h.GetGeofence)
+		api.POST("/geofences", adminAuth, h.CreateGeofence)
+		api.PUT("/geofences/:id", adminAuth, h.UpdateGeofence)
+		api.DELETE("/geofences/:id", adminAuth, h.DeleteGeofence)
+
+		// 周期摘要相关
+		api.GET("/cars/:id/digest", h.GetCarDigest)
+		api.GET("/digest/schedules", h.GetDigestSchedules)
+		api.PUT("/digest/schedules", h.UpdateDigestSchedules)
+
+		// 告警相关
+		api.GET("/alerts/rules", h.GetAlertRules)
+		api.POST("/alerts/rules", h.CreateAlertRule)
+		api.GET("/alerts/rules/:id", h.GetAlertRule)
+		api.PUT("/alerts/rules/:id", h.UpdateAlertRule)
+		api.DELETE("/alerts/rules/:id", h.DeleteAlertRule)
+		api.GET("/alerts/events", h.GetAlertEvents)
+		api.DELETE("/alerts/events/:id", h.DeleteAlertEvent)
+
+		// 通知渠道相关
+		api.GET("/notification-channels", h.GetNotificationChannels)
+		api.POST("/notification-channels", h.CreateNotificationChannel)
+		api.GET("/notification-channels/:id", h.GetNotificationChannel)
+		api.PUT("/notification-channels/:id", h.UpdateNotificationChannel)
+		api.DELETE("/notification-channels/:id", h.DeleteNotificationChannel)
+		api.POST("/notification-channels/:id/test", h.TestNotificationChannel)
 
 		// UI设置相关
 		api.GET("/settings", h.GetUISettings)
@@ -166,8 +256,28 @@ func main() {
 
 	// 启动服务
 	addr := cfg.Server.Host + ":" + cfg.Server.Port
-	applog.Infof("Server starting on %s", addr)
-	if err := r.Run(addr); err != nil {
-		applog.Fatalf("Failed to start server: %v", err)
+	srv := &http.Server{
+		Addr:    addr,
+		Handler: r,
+		// 请求 context 继承自 ctx，关闭时 SSE / WebSocket 等长连接可以及时退出
+		BaseContext: func(net.Listener) context.Context { return ctx },
+	}
+
+	go func() {
+		applog.Infof("Server starting on %s", addr)
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			applog.Fatalf("Failed to start server: %v", err)
+		}
+	}()
+
+	<-ctx.Done()
+	applog.Info("Shutting down server...")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		applog.Errorf("Server forced to shutdown: %v", err)
 	}
+	workers.Wait()
+	applog.Info("Server exited")
 }
@@ -0,0 +1,343 @@
+package alert
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"teslamate-cyberui/internal/logger"
+	"teslamate-cyberui/internal/model"
+	"teslamate-cyberui/internal/mqtt"
+	"teslamate-cyberui/internal/notify"
+	"teslamate-cyberui/internal/repository"
+)
+
+const (
+	// tickInterval 定期评估的间隔：处理防抖到期、时间窗口切换以及轮询新结束的充电/行程
+	tickInterval = 15 * time.Second
+	// updateBuffer 订阅 MQTT 缓存变更的缓冲区大小
+	updateBuffer = 256
+	// writeTimeout 写入告警历史的超时时间
+	writeTimeout = 10 * time.Second
+	// notifyTimeout 推送一条告警到全部渠道的超时时间
+	notifyTimeout = time.Minute
+)
+
+// stateKey 规则在某辆车上的状态索引
+type stateKey struct {
+	ruleID int64
+	carID  int16
+}
+
+// ruleState 规则在某辆车上的防抖和冷却状态
+type ruleState struct {
+	// since 条件开始持续满足的时间，零值表示当前不满足
+	since time.Time
+	// fired 本轮满足期间是否已经触发过，条件恢复后重置
+	fired bool
+	// lastFired 最近一次触发时间，用于冷却
+	lastFired time.Time
+}
+
+// Engine 告警引擎
+// MQTT 规则在缓存变更和定时器上评估：条件持续满足 debounce 秒后触发一次，
+// 条件恢复前不会重复触发，两次触发之间至少间隔 cooldown 秒。
+// 充电/行程规则定时轮询新结束的记录，记录结束 debounce 秒后（等待 TeslaMate 补全费用等字段）再评估。
+// 所有状态只在 Run 所在的协程中读写。
+type Engine struct {
+	repo     repository.AlertRepository
+	cache    *mqtt.Cache
+	notifier *notify.Dispatcher
+
+	reload chan struct{}
+
+	rules []model.AlertRule
+	// versions 记录规则的 UpdatedAt，规则被修改后重置其防抖状态
+	versions map[int64]time.Time
+	states   map[stateKey]*ruleState
+	// watermarks 充电/行程规则已经检查到的记录结束时间
+	watermarks map[int64]time.Time
+}
+
+// NewEngine 创建告警引擎，notifier 为 nil 时只记录告警历史
+func NewEngine(repo repository.AlertRepository, cache *mqtt.Cache, notifier *notify.Dispatcher) *Engine {
+	return &Engine{
+		repo:       repo,
+		cache:      cache,
+		notifier:   notifier,
+		reload:     make(chan struct{}, 1),
+		versions:   make(map[int64]time.Time),
+		states:     make(map[stateKey]*ruleState),
+		watermarks: make(map[int64]time.Time),
+	}
+}
+
+// Reload 通知引擎重新加载规则（规则增删改后调用），不会阻塞
+func (e *Engine) Reload() {
+	select {
+	case e.reload <- struct{}{}:
+	default:
+	}
+}
+
+// Run 运行告警引擎直到 ctx 结束
+func (e *Engine) Run(ctx context.Context) {
+	e.loadRules(ctx)
+	e.loadLastTriggered(ctx)
+
+	sub := e.cache.SubscribeAll(updateBuffer)
+	defer e.cache.Unsubscribe(sub)
+
+	ticker := time.NewTicker(tickInterval)
+	defer ticker.Stop()
+
+	logger.Info("Alert engine started")
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Info("Alert engine stopped")
+			return
+		case <-e.reload:
+			e.loadRules(ctx)
+		case <-ticker.C:
+			now := time.Now().UTC()
+			for _, carID := range e.cache.CarIDs() {
+				e.evaluateCar(ctx, carID, now)
+			}
+			e.pollRecords(ctx, now)
+		case u, ok := <-sub.Updates():
+			if !ok {
+				return
+			}
+			// 丢失的事件由定时评估兜底
+			sub.Lagged()
+			e.evaluateCar(ctx, u.CarID, time.Now().UTC())
+		}
+	}
+}
+
+// loadRules 从数据库加载启用的规则
+func (e *Engine) loadRules(ctx context.Context) {
+	rules, err := e.repo.ListRules(ctx)
+	if err != nil {
+		logger.Errorf("Failed to load alert rules: %v", err)
+		return
+	}
+
+	now := time.Now().UTC()
+	enabled := make([]model.AlertRule, 0, len(rules))
+	active := make(map[int64]bool, len(rules))
+	for _, rule := range rules {
+		if !rule.Enabled {
+			continue
+		}
+		enabled = append(enabled, rule)
+		active[rule.ID] = true
+
+		if v, ok := e.versions[rule.ID]; ok && !v.Equal(rule.UpdatedAt) {
+			e.resetRule(rule.ID)
+		}
+		e.versions[rule.ID] = rule.UpdatedAt
+
+		// 新加载的充电/行程规则只检查之后结束的记录
+		if rule.Source != model.AlertSourceMQTT {
+			if _, ok := e.watermarks[rule.ID]; !ok {
+				e.watermarks[rule.ID] = now.Add(-time.Duration(rule.DebounceSeconds) * time.Second)
+			}
+		}
+	}
+
+	// 清理已删除或已停用规则的状态
+	for id := range e.versions {
+		if !active[id] {
+			delete(e.versions, id)
+			delete(e.watermarks, id)
+		}
+	}
+	for key := range e.states {
+		if !active[key.ruleID] {
+			delete(e.states, key)
+		}
+	}
+
+	e.rules = enabled
+	logger.Debugf("Loaded %d enabled alert rules", len(enabled))
+}
+
+// loadLastTriggered 恢复每条规则最近的触发时间，避免重启后立即重复告警
+func (e *Engine) loadLastTriggered(ctx context.Context) {
+	last, err := e.repo.GetLastTriggered(ctx)
+	if err != nil {
+		return
+	}
+	for ruleID, cars := range last {
+		for carID, t := range cars {
+			e.state(ruleID, carID).lastFired = t
+		}
+	}
+}
+
+// resetRule 规则被修改后重置防抖状态，保留冷却时间
+func (e *Engine) resetRule(ruleID int64) {
+	for key, st := range e.states {
+		if key.ruleID == ruleID {
+			st.since = time.Time{}
+			st.fired = false
+		}
+	}
+}
+
+func (e *Engine) state(ruleID int64, carID int16) *ruleState {
+	key := stateKey{ruleID: ruleID, carID: carID}
+	st, ok := e.states[key]
+	if !ok {
+		st = &ruleState{}
+		e.states[key] = st
+	}
+	return st
+}
+
+// evaluateCar 评估某辆车上所有 MQTT 规则
+func (e *Engine) evaluateCar(ctx context.Context, carID int16, now time.Time) {
+	var entries map[string]mqtt.Entry
+	for i := range e.rules {
+		rule := &e.rules[i]
+		if rule.Source != model.AlertSourceMQTT || (rule.CarID != nil && *rule.CarID != carID) {
+			continue
+		}
+		if entries == nil {
+			entries = e.cache.GetEntriesForCar(carID)
+		}
+
+		matched, values := false, map[string]string(nil)
+		if inWindow(rule, now) {
+			matched, values = match(rule.Conditions, func(metric string) (string, bool) {
+				entry, ok := entries[metric]
+				return entry.Raw, ok
+			})
+		}
+
+		st := e.state(rule.ID, carID)
+		if !matched {
+			st.since = time.Time{}
+			st.fired = false
+			continue
+		}
+		if st.since.IsZero() {
+			st.since = now
+		}
+		if st.fired || now.Sub(st.since) < time.Duration(rule.DebounceSeconds)*time.Second {
+			continue
+		}
+		if e.coolingDown(rule, st, now) {
+			continue
+		}
+		st.fired = true
+		st.lastFired = now
+		e.fire(ctx, rule, carID, describe(rule, values), values, now)
+	}
+}
+
+// pollRecords 检查新结束的充电/行程记录
+func (e *Engine) pollRecords(ctx context.Context, now time.Time) {
+	for i := range e.rules {
+		rule := &e.rules[i]
+		if rule.Source == model.AlertSourceMQTT {
+			continue
+		}
+
+		before := now.Add(-time.Duration(rule.DebounceSeconds) * time.Second)
+		after := e.watermarks[rule.ID]
+		if !before.After(after) {
+			continue
+		}
+
+		var records []model.AlertRecord
+		var err error
+		if rule.Source == model.AlertSourceCharge {
+			records, err = e.repo.GetFinishedCharges(ctx, rule.CarID, after, before)
+		} else {
+			records, err = e.repo.GetFinishedDrives(ctx, rule.CarID, after, before)
+		}
+		if err != nil {
+			// 下次定时评估时重试同一区间
+			continue
+		}
+		e.watermarks[rule.ID] = before
+
+		for _, record := range records {
+			if !inWindow(rule, record.EndDate) {
+				continue
+			}
+			matched, values := match(rule.Conditions, func(metric string) (string, bool) {
+				v, ok := record.Fields[metric]
+				return v, ok
+			})
+			if !matched {
+				continue
+			}
+			st := e.state(rule.ID, record.CarID)
+			if e.coolingDown(rule, st, now) {
+				continue
+			}
+			st.lastFired = now
+			values[rule.Source+"_id"] = strconv.FormatInt(record.ID, 10)
+			e.fire(ctx, rule, record.CarID, describe(rule, values), values, now)
+		}
+	}
+}
+
+// coolingDown 判断是否仍处于冷却期
+func (e *Engine) coolingDown(rule *model.AlertRule, st *ruleState, now time.Time) bool {
+	if rule.CooldownSeconds <= 0 || st.lastFired.IsZero() {
+		return false
+	}
+	return now.Sub(st.lastFired) < time.Duration(rule.CooldownSeconds)*time.Second
+}
+
+// fire 记录一次告警，并在后台推送到规则关联的通知渠道
+func (e *Engine) fire(ctx context.Context, rule *model.AlertRule, carID int16, message string, details map[string]string, now time.Time) {
+	ruleID := rule.ID
+	event := &model.AlertEvent{
+		RuleID:      &ruleID,
+		RuleName:    rule.Name,
+		CarID:       carID,
+		Source:      rule.Source,
+		Message:     message,
+		Details:     details,
+		TriggeredAt: now,
+	}
+	logger.Infof("Alert triggered for car %d: %s", carID, message)
+
+	writeCtx, cancel := context.WithTimeout(ctx, writeTimeout)
+	defer cancel()
+	if err := e.repo.InsertEvent(writeCtx, event); err != nil {
+		logger.Errorf("Failed to record alert event for rule %d: %v", rule.ID, err)
+	}
+
+	if e.notifier == nil || len(rule.ChannelIDs) == 0 {
+		return
+	}
+	data := make(map[string]interface{}, len(details)+3)
+	for k, v := range details {
+		data[k] = v
+	}
+	data["ruleId"] = rule.ID
+	data["ruleName"] = rule.Name
+	data["source"] = rule.Source
+	msg := notify.Message{
+		Kind:  notify.KindAlert,
+		Title: rule.Name,
+		Body:  message,
+		CarID: carID,
+		Time:  now,
+		Data:  data,
+	}
+	channelIDs := append([]int64(nil), rule.ChannelIDs...)
+	// 推送可能较慢，不阻塞引擎的事件循环
+	go func() {
+		notifyCtx, cancel := context.WithTimeout(context.Background(), notifyTimeout)
+		defer cancel()
+		e.notifier.Dispatch(notifyCtx, channelIDs, msg)
+	}()
+}
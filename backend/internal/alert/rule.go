@@ -0,0 +1,146 @@
+package alert
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"teslamate-cyberui/internal/model"
+)
+
+// Validate 校验告警规则的合法性
+func Validate(rule *model.AlertRule) error {
+	if strings.TrimSpace(rule.Name) == "" {
+		return fmt.Errorf("name is required")
+	}
+	switch rule.Source {
+	case model.AlertSourceMQTT, model.AlertSourceCharge, model.AlertSourceDrive:
+	default:
+		return fmt.Errorf("invalid source %q (expected mqtt, charge or drive)", rule.Source)
+	}
+	if len(rule.Conditions) == 0 {
+		return fmt.Errorf("at least one condition is required")
+	}
+	for i, cond := range rule.Conditions {
+		if strings.TrimSpace(cond.Metric) == "" {
+			return fmt.Errorf("condition %d: metric is required", i+1)
+		}
+		switch cond.Operator {
+		case model.AlertOpEq, model.AlertOpNe:
+		case model.AlertOpGt, model.AlertOpGte, model.AlertOpLt, model.AlertOpLte:
+			if _, err := strconv.ParseFloat(cond.Value, 64); err != nil {
+				return fmt.Errorf("condition %d: operator %s requires a numeric value", i+1, cond.Operator)
+			}
+		default:
+			return fmt.Errorf("condition %d: invalid operator %q", i+1, cond.Operator)
+		}
+	}
+	if (rule.TimeStart == nil) != (rule.TimeEnd == nil) {
+		return fmt.Errorf("timeStart and timeEnd must be set together")
+	}
+	if rule.TimeStart != nil {
+		if _, err := parseClock(*rule.TimeStart); err != nil {
+			return fmt.Errorf("invalid timeStart: %v", err)
+		}
+		if _, err := parseClock(*rule.TimeEnd); err != nil {
+			return fmt.Errorf("invalid timeEnd: %v", err)
+		}
+	}
+	if rule.DebounceSeconds < 0 || rule.CooldownSeconds < 0 {
+		return fmt.Errorf("debounceSeconds and cooldownSeconds must not be negative")
+	}
+	return nil
+}
+
+// parseClock 解析 HH:MM，返回当天的分钟数
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("expected HH:MM, got %q", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// inWindow 判断时间是否落在规则的生效时间窗口内（服务器本地时区）
+// 未设置窗口时全天生效；start > end 表示跨越午夜，例如 22:00-06:00
+func inWindow(rule *model.AlertRule, t time.Time) bool {
+	if rule.TimeStart == nil || rule.TimeEnd == nil {
+		return true
+	}
+	start, err1 := parseClock(*rule.TimeStart)
+	end, err2 := parseClock(*rule.TimeEnd)
+	if err1 != nil || err2 != nil || start == end {
+		return true
+	}
+	local := t.In(time.Local)
+	m := local.Hour()*60 + local.Minute()
+	if start < end {
+		return m >= start && m < end
+	}
+	return m >= start || m < end
+}
+
+// match 判断所有条件是否满足，lookup 返回指标当前值；同时返回参与判断的指标值
+func match(conditions model.AlertConditions, lookup func(metric string) (string, bool)) (bool, map[string]string) {
+	values := make(map[string]string, len(conditions))
+	for _, cond := range conditions {
+		actual, ok := lookup(cond.Metric)
+		if !ok {
+			return false, values
+		}
+		values[cond.Metric] = actual
+		if !compare(actual, cond.Operator, cond.Value) {
+			return false, values
+		}
+	}
+	return true, values
+}
+
+// compare 比较实际值和阈值：两者都是数字时按数值比较，否则 eq / ne 按字符串（忽略大小写）比较
+func compare(actual, op, expected string) bool {
+	a, errA := strconv.ParseFloat(actual, 64)
+	e, errE := strconv.ParseFloat(expected, 64)
+	numeric := errA == nil && errE == nil
+
+	switch op {
+	case model.AlertOpEq:
+		if numeric {
+			return a == e
+		}
+		return strings.EqualFold(actual, expected)
+	case model.AlertOpNe:
+		if numeric {
+			return a != e
+		}
+		return !strings.EqualFold(actual, expected)
+	}
+
+	if !numeric {
+		return false
+	}
+	switch op {
+	case model.AlertOpGt:
+		return a > e
+	case model.AlertOpGte:
+		return a >= e
+	case model.AlertOpLt:
+		return a < e
+	case model.AlertOpLte:
+		return a <= e
+	}
+	return false
+}
+
+// describe 生成告警消息，例如 “SOC low: battery_level = 15 (lt 20)”
+func describe(rule *model.AlertRule, values map[string]string) string {
+	parts := make([]string, 0, len(rule.Conditions))
+	for _, cond := range rule.Conditions {
+		actual := values[cond.Metric]
+		if actual == "" {
+			actual = `""`
+		}
+		parts = append(parts, fmt.Sprintf("%s = %s (%s %s)", cond.Metric, actual, cond.Operator, cond.Value))
+	}
+	return fmt.Sprintf("%s: %s", rule.Name, strings.Join(parts, ", "))
+}
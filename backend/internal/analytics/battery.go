@@ -0,0 +1,189 @@
+package analytics
+
+import (
+	"math"
+
+	"teslamate-cyberui/internal/model"
+)
+
+const (
+	// outlierThreshold 修正 Z 分数超过该值的样本视为离群
+	outlierThreshold = 3.5
+	// bandPoints 趋势线置信带的取点数
+	bandPoints = 20
+)
+
+// BatteryHealth 根据每次充电估算的容量拟合容量趋势并计算衰减
+// 先用车龄趋势的残差标记离群样本，再分别对里程和车龄拟合；当前容量优先取里程趋势在最新里程处的值。
+// nominal 不为空时以其作为新车容量，否则以趋势线在首个样本处的值为基准，此时衰减的置信区间来自斜率的置信区间。
+func BatteryHealth(samples []model.BatteryCapacitySample, nominal *float64) *model.BatteryHealthStats {
+	stats := &model.BatteryHealthStats{
+		NominalCapacity: nominal,
+		SampleCount:     len(samples),
+		Samples:         samples,
+	}
+	if stats.Samples == nil {
+		stats.Samples = []model.BatteryCapacitySample{}
+	}
+	if len(samples) == 0 {
+		return stats
+	}
+
+	markOutliers(samples)
+
+	var ageX, ageY, odoX, odoY []float64
+	for _, s := range samples {
+		if s.Outlier {
+			stats.OutlierCount++
+			continue
+		}
+		ageX = append(ageX, s.AgeDays)
+		ageY = append(ageY, s.Capacity)
+		if s.Odometer != nil {
+			odoX = append(odoX, *s.Odometer)
+			odoY = append(odoY, s.Capacity)
+		}
+	}
+
+	last := samples[len(samples)-1]
+	stats.AgeDays = last.AgeDays
+	for i := len(samples) - 1; i >= 0; i-- {
+		if samples[i].Odometer != nil {
+			stats.Odometer = *samples[i].Odometer
+			break
+		}
+	}
+
+	odoFit, _ := FitLinear(odoX, odoY)
+	ageFit, _ := FitLinear(ageX, ageY)
+	if odoFit != nil {
+		stats.ByOdometer = trend(odoFit, odoX)
+		for i := range samples {
+			if samples[i].Odometer != nil {
+				fitted := odoFit.Predict(*samples[i].Odometer)
+				samples[i].Fitted = &fitted
+			}
+		}
+	}
+	if ageFit != nil {
+		stats.ByAge = trend(ageFit, ageX)
+	}
+
+	// 选择用于计算当前容量的趋势
+	fit, xs, current := odoFit, odoX, stats.Odometer
+	if fit == nil {
+		fit, xs, current = ageFit, ageX, stats.AgeDays
+	}
+
+	if fit == nil {
+		// 样本不足以拟合时取有效样本的中位数
+		values := ageY
+		if len(values) == 0 {
+			values = []float64{last.Capacity}
+		}
+		stats.CurrentCapacity = Median(values)
+		stats.CurrentCapacityLower = stats.CurrentCapacity
+		stats.CurrentCapacityUpper = stats.CurrentCapacity
+		stats.InitialCapacity = stats.CurrentCapacity
+		if nominal != nil && *nominal > 0 {
+			stats.InitialCapacity = *nominal
+		}
+		setDegradation(stats, stats.CurrentCapacity, stats.CurrentCapacity, stats.CurrentCapacity)
+		return stats
+	}
+
+	stats.CurrentCapacity = fit.Predict(current)
+	stats.CurrentCapacityLower, stats.CurrentCapacityUpper = fit.ConfidenceBand(current)
+
+	if nominal != nil && *nominal > 0 {
+		stats.InitialCapacity = *nominal
+		setDegradation(stats, stats.CurrentCapacity, stats.CurrentCapacityLower, stats.CurrentCapacityUpper)
+		return stats
+	}
+
+	// 没有新车容量时，以趋势线在第一个样本处的值为基准，衰减量 = -斜率 * 区间长度
+	first := minOf(xs)
+	stats.InitialCapacity = fit.Predict(first)
+	span := current - first
+	slopeLower, slopeUpper := fit.SlopeInterval()
+	setDegradation(stats,
+		stats.InitialCapacity+fit.Slope*span,
+		stats.InitialCapacity+slopeLower*span,
+		stats.InitialCapacity+slopeUpper*span)
+	return stats
+}
+
+// markOutliers 按车龄趋势的残差标记离群样本，样本不足以拟合时直接按容量判断
+func markOutliers(samples []model.BatteryCapacitySample) {
+	xs := make([]float64, len(samples))
+	ys := make([]float64, len(samples))
+	for i, s := range samples {
+		xs[i] = s.AgeDays
+		ys[i] = s.Capacity
+	}
+
+	values := ys
+	if fit, err := FitLinear(xs, ys); err == nil {
+		values = make([]float64, len(samples))
+		for i := range samples {
+			values[i] = ys[i] - fit.Predict(xs[i])
+		}
+	}
+	for i, outlier := range Outliers(values, outlierThreshold) {
+		samples[i].Outlier = outlier
+	}
+}
+
+// setDegradation 根据当前容量（及其区间）和基准容量计算衰减与健康度
+// 样本较少时区间可能超出基准容量或小于 0，衰减及其区间限制在 0-100%
+func setDegradation(stats *model.BatteryHealthStats, current, lower, upper float64) {
+	if stats.InitialCapacity <= 0 {
+		return
+	}
+	degradation := func(capacity float64) float64 {
+		return math.Min(100, math.Max(0, (1-capacity/stats.InitialCapacity)*100))
+	}
+	// 容量越高衰减越小，区间上下限互换
+	stats.DegradationPercent = degradation(current)
+	stats.DegradationPercentLower = degradation(math.Max(lower, upper))
+	stats.DegradationPercentUpper = degradation(math.Min(lower, upper))
+	stats.HealthPercent = 100 - stats.DegradationPercent
+}
+
+// trend 将拟合结果转换为响应结构，并在样本范围内等距取点生成置信带
+func trend(fit *LinearFit, xs []float64) *model.CapacityTrend {
+	slopeLower, slopeUpper := fit.SlopeInterval()
+	t := &model.CapacityTrend{
+		Slope:          fit.Slope,
+		Intercept:      fit.Intercept,
+		SlopeLower:     slopeLower,
+		SlopeUpper:     slopeUpper,
+		RSquared:       fit.RSquared,
+		ResidualStdDev: fit.ResidualStdDev,
+		N:              fit.N,
+		Band:           make([]model.TrendBandPoint, 0, bandPoints),
+	}
+	lo, hi := minOf(xs), maxOf(xs)
+	for i := 0; i < bandPoints; i++ {
+		x := lo + (hi-lo)*float64(i)/float64(bandPoints-1)
+		lower, upper := fit.ConfidenceBand(x)
+		t.Band = append(t.Band, model.TrendBandPoint{X: x, Fitted: fit.Predict(x), Lower: lower, Upper: upper})
+	}
+	return t
+}
+
+func minOf(xs []float64) float64 {
+	m := math.Inf(1)
+	for _, x := range xs {
+		m = math.Min(m, x)
+	}
+	return m
+}
+
+func maxOf(xs []float64) float64 {
+	m := math.Inf(-1)
+	for _, x := range xs {
+		m = math.Max(m, x)
+	}
+	return m
+}
@@ -0,0 +1,59 @@
+package analytics
+
+import (
+	"testing"
+
+	"teslamate-cyberui/internal/model"
+)
+
+func TestSetDegradationClampsBounds(t *testing.T) {
+	tests := []struct {
+		name                        string
+		current, lower, upper       float64
+		degradation, dLower, dUpper float64
+	}{
+		{"within range", 67.5, 60, 72, 10, 4, 20},
+		// 置信区间高于基准容量时衰减不为负
+		{"above initial capacity", 80, 70, 90, 0, 0, 6.666667},
+		// 置信区间低于 0 时衰减不超过 100%
+		{"negative capacity", 5, -10, 20, 93.333333, 73.333333, 100},
+	}
+	for _, tt := range tests {
+		stats := &model.BatteryHealthStats{InitialCapacity: 75}
+		setDegradation(stats, tt.current, tt.lower, tt.upper)
+		if !approx(stats.DegradationPercent, tt.degradation, 1e-5) ||
+			!approx(stats.DegradationPercentLower, tt.dLower, 1e-5) ||
+			!approx(stats.DegradationPercentUpper, tt.dUpper, 1e-5) {
+			t.Errorf("%s: degradation = %.6f [%.6f, %.6f], want %.6f [%.6f, %.6f]", tt.name,
+				stats.DegradationPercent, stats.DegradationPercentLower, stats.DegradationPercentUpper,
+				tt.degradation, tt.dLower, tt.dUpper)
+		}
+		if !approx(stats.HealthPercent, 100-tt.degradation, 1e-5) {
+			t.Errorf("%s: health = %.6f, want %.6f", tt.name, stats.HealthPercent, 100-tt.degradation)
+		}
+	}
+}
+
+func TestBatteryHealthFewSamplesStaysInRange(t *testing.T) {
+	// 3 个离散的样本使置信区间很宽
+	odometer := func(v float64) *float64 { return &v }
+	samples := []model.BatteryCapacitySample{
+		{AgeDays: 0, Odometer: odometer(1000), Capacity: 74},
+		{AgeDays: 10, Odometer: odometer(1500), Capacity: 79},
+		{AgeDays: 20, Odometer: odometer(2000), Capacity: 70},
+	}
+	nominal := 75.0
+	for _, n := range []*float64{nil, &nominal} {
+		stats := BatteryHealth(append([]model.BatteryCapacitySample(nil), samples...), n)
+		for name, v := range map[string]float64{
+			"degradation": stats.DegradationPercent,
+			"lower":       stats.DegradationPercentLower,
+			"upper":       stats.DegradationPercentUpper,
+			"health":      stats.HealthPercent,
+		} {
+			if v < 0 || v > 100 {
+				t.Errorf("nominal=%v: %s = %.2f, want within [0, 100]", n, name, v)
+			}
+		}
+	}
+}
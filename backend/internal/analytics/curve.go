@@ -0,0 +1,166 @@
+package analytics
+
+import (
+	"math"
+	"sort"
+
+	"teslamate-cyberui/internal/model"
+)
+
+// chargeSeries 充电曲线中分别做 LTTB 降采样的序列，value 返回 false 表示该点没有数据
+var chargeSeries = []struct {
+	name  string
+	value func(p *model.ChargeDataPoint) (float64, bool)
+}{
+	{"power", func(p *model.ChargeDataPoint) (float64, bool) { return float64(p.ChargerPower), true }},
+	{"voltage", func(p *model.ChargeDataPoint) (float64, bool) { return float64(p.ChargerVoltage), true }},
+	{"current", func(p *model.ChargeDataPoint) (float64, bool) { return float64(p.ChargerCurrent), true }},
+	{"outsideTemp", func(p *model.ChargeDataPoint) (float64, bool) {
+		if p.OutsideTemp == nil {
+			return 0, false
+		}
+		return *p.OutsideTemp, true
+	}},
+}
+
+// LTTB 用 Largest-Triangle-Three-Buckets 算法从序列中选出 threshold 个最能保留形状的点，返回升序下标
+// 首尾两点总会保留；threshold 小于 3 或不小于点数时返回全部下标
+func LTTB(xs, ys []float64, threshold int) []int {
+	n := len(xs)
+	if threshold < 3 || threshold >= n {
+		all := make([]int, n)
+		for i := range all {
+			all[i] = i
+		}
+		return all
+	}
+
+	selected := make([]int, 0, threshold)
+	selected = append(selected, 0)
+
+	// 除首尾外的点平均分到 threshold-2 个桶中，每个桶选出与上一个选中点、下一个桶均值构成三角形面积最大的点
+	every := float64(n-2) / float64(threshold-2)
+	a := 0
+	for i := 0; i < threshold-2; i++ {
+		avgStart := int(math.Floor(float64(i+1)*every)) + 1
+		avgEnd := min(int(math.Floor(float64(i+2)*every))+1, n)
+		var avgX, avgY float64
+		for j := avgStart; j < avgEnd; j++ {
+			avgX += xs[j]
+			avgY += ys[j]
+		}
+		count := float64(avgEnd - avgStart)
+		avgX /= count
+		avgY /= count
+
+		rangeStart := int(math.Floor(float64(i)*every)) + 1
+		rangeEnd := int(math.Floor(float64(i+1)*every)) + 1
+		maxArea := -1.0
+		next := rangeStart
+		for j := rangeStart; j < rangeEnd; j++ {
+			area := math.Abs((xs[a]-avgX)*(ys[j]-ys[a]) - (xs[a]-xs[j])*(avgY-ys[a]))
+			if area > maxArea {
+				maxArea = area
+				next = j
+			}
+		}
+		selected = append(selected, next)
+		a = next
+	}
+
+	return append(selected, n-1)
+}
+
+// ReduceChargeCurve 启发式精简充电曲线：只保留关键数据点（电量变化点、功率显著变化点）
+// 这样可以减少锯齿效果，同时保持曲线的关键特征
+func ReduceChargeCurve(points []model.ChargeDataPoint) []model.ChargeDataPoint {
+	sampled := []model.ChargeDataPoint{}
+	if len(points) == 0 {
+		return sampled
+	}
+
+	// 始终保留第一个点
+	sampled = append(sampled, points[0])
+	lastBatteryLevel := points[0].BatteryLevel
+	lastPower := points[0].ChargerPower
+
+	for i := 1; i < len(points)-1; i++ {
+		point := points[i]
+
+		// 保留条件：
+		// 1. 电量变化了（每变化1%保留一个点）
+		// 2. 功率变化超过3kW（捕捉充电功率的显著变化）
+		batteryChanged := point.BatteryLevel != lastBatteryLevel
+		powerChangedSignificantly := math.Abs(float64(point.ChargerPower-lastPower)) >= 3
+
+		if batteryChanged || powerChangedSignificantly {
+			sampled = append(sampled, point)
+			lastBatteryLevel = point.BatteryLevel
+			lastPower = point.ChargerPower
+		}
+	}
+
+	// 始终保留最后一个点
+	if len(points) > 1 {
+		sampled = append(sampled, points[len(points)-1])
+	}
+	return sampled
+}
+
+// SampleChargeCurve 按指定方式采样充电曲线，points 需按时间排序
+// lttb 时功率、电压、电流和车外温度分别降采样到 threshold 个点并放在 Series 中，
+// DataPoints 为各序列选中点的并集，因此最多有 4*threshold 个点
+func SampleChargeCurve(chargeID int64, points []model.ChargeDataPoint, method string, threshold int) *model.ChargeStats {
+	stats := &model.ChargeStats{
+		ChargingProcessID: chargeID,
+		Sampling:          model.ChargeSampling{Method: method, RawPoints: len(points)},
+	}
+
+	switch method {
+	case model.ChargeSamplingRaw:
+		stats.DataPoints = points
+	case model.ChargeSamplingLTTB:
+		stats.Sampling.Threshold = threshold
+		stats.Sampling.SeriesPoints = make(map[string]int, len(chargeSeries))
+		stats.Series = make(map[string][]model.ChargeSeriesPoint, len(chargeSeries))
+
+		keep := make(map[int]bool)
+		for _, s := range chargeSeries {
+			var indexes []int
+			var xs, ys []float64
+			for i := range points {
+				v, ok := s.value(&points[i])
+				if !ok {
+					continue
+				}
+				indexes = append(indexes, i)
+				xs = append(xs, points[i].Date.Sub(points[0].Date).Seconds())
+				ys = append(ys, v)
+			}
+
+			series := []model.ChargeSeriesPoint{}
+			for _, j := range LTTB(xs, ys, threshold) {
+				idx := indexes[j]
+				keep[idx] = true
+				series = append(series, model.ChargeSeriesPoint{Date: points[idx].Date, Value: ys[j]})
+			}
+			stats.Series[s.name] = series
+			stats.Sampling.SeriesPoints[s.name] = len(series)
+		}
+
+		merged := make([]int, 0, len(keep))
+		for idx := range keep {
+			merged = append(merged, idx)
+		}
+		sort.Ints(merged)
+		stats.DataPoints = make([]model.ChargeDataPoint, 0, len(merged))
+		for _, idx := range merged {
+			stats.DataPoints = append(stats.DataPoints, points[idx])
+		}
+	default:
+		stats.DataPoints = ReduceChargeCurve(points)
+	}
+
+	stats.Sampling.ReturnedPoints = len(stats.DataPoints)
+	return stats
+}
@@ -0,0 +1,170 @@
+package analytics
+
+import (
+	"math"
+	"slices"
+	"testing"
+	"time"
+
+	"teslamate-cyberui/internal/model"
+)
+
+// series 生成 x 为 0..n-1、y 为 f(x) 的序列
+func series(n int, f func(i int) float64) ([]float64, []float64) {
+	xs := make([]float64, n)
+	ys := make([]float64, n)
+	for i := range xs {
+		xs[i] = float64(i)
+		ys[i] = f(i)
+	}
+	return xs, ys
+}
+
+// checkSelection 检查选出的下标严格递增且保留首尾
+func checkSelection(t *testing.T, name string, got []int, n, want int) {
+	t.Helper()
+	if len(got) != want {
+		t.Fatalf("%s: selected %d points, want %d", name, len(got), want)
+	}
+	if want == 0 {
+		return
+	}
+	if got[0] != 0 || got[len(got)-1] != n-1 {
+		t.Errorf("%s: first/last = %d/%d, want 0/%d", name, got[0], got[len(got)-1], n-1)
+	}
+	for i := 1; i < len(got); i++ {
+		if got[i] <= got[i-1] {
+			t.Errorf("%s: indexes not strictly increasing: %v", name, got)
+			break
+		}
+	}
+}
+
+func TestLTTBThresholds(t *testing.T) {
+	xs, ys := series(10, func(i int) float64 { return math.Sin(float64(i)) })
+	tests := []struct {
+		name      string
+		threshold int
+		want      int
+	}{
+		{"threshold below 3 returns all", 2, 10},
+		{"zero threshold returns all", 0, 10},
+		{"threshold equal to n returns all", 10, 10},
+		{"threshold above n returns all", 50, 10},
+		{"n is threshold+1", 9, 9},
+		{"minimum threshold", 3, 3},
+		{"half", 5, 5},
+	}
+	for _, tt := range tests {
+		checkSelection(t, tt.name, LTTB(xs, ys, tt.threshold), len(xs), tt.want)
+	}
+
+	if got := LTTB(nil, nil, 5); len(got) != 0 {
+		t.Errorf("LTTB(empty) = %v, want empty", got)
+	}
+}
+
+func TestLTTBKeepsSpike(t *testing.T) {
+	xs, ys := series(100, func(i int) float64 {
+		if i == 42 {
+			return 100
+		}
+		return 1
+	})
+	got := LTTB(xs, ys, 10)
+	checkSelection(t, "spike", got, len(xs), 10)
+	if !slices.Contains(got, 42) {
+		t.Errorf("spike at 42 not selected: %v", got)
+	}
+}
+
+// chargePoints 生成每分钟一个点的充电曲线，功率在第 20 分钟附近骤降，电压缓慢上升
+func chargePoints(n int, withTemp bool) []model.ChargeDataPoint {
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	points := make([]model.ChargeDataPoint, n)
+	for i := range points {
+		power := 150
+		if i >= 20 {
+			power = 60
+		}
+		points[i] = model.ChargeDataPoint{
+			Date:           start.Add(time.Duration(i) * time.Minute),
+			BatteryLevel:   20 + i/2,
+			ChargerPower:   power,
+			ChargerVoltage: 350 + i,
+			ChargerCurrent: power * 1000 / (350 + i),
+		}
+		if withTemp {
+			temp := 10 + math.Sin(float64(i)/5)
+			points[i].OutsideTemp = &temp
+		}
+	}
+	return points
+}
+
+func TestSampleChargeCurveLTTB(t *testing.T) {
+	points := chargePoints(60, true)
+	stats := SampleChargeCurve(7, points, model.ChargeSamplingLTTB, 8)
+
+	if stats.ChargingProcessID != 7 || stats.Sampling.RawPoints != 60 || stats.Sampling.Threshold != 8 {
+		t.Errorf("sampling = %+v", stats.Sampling)
+	}
+
+	union := make(map[time.Time]bool)
+	for _, s := range chargeSeries {
+		values := stats.Series[s.name]
+		if len(values) != 8 || stats.Sampling.SeriesPoints[s.name] != 8 {
+			t.Errorf("series %s has %d points (reported %d), want 8", s.name, len(values), stats.Sampling.SeriesPoints[s.name])
+			continue
+		}
+		if !values[0].Date.Equal(points[0].Date) || !values[len(values)-1].Date.Equal(points[len(points)-1].Date) {
+			t.Errorf("series %s does not keep first and last points", s.name)
+		}
+		for _, v := range values {
+			union[v.Date] = true
+		}
+	}
+
+	// DataPoints 为各序列选中点按时间排序的并集
+	if len(stats.DataPoints) != len(union) || stats.Sampling.ReturnedPoints != len(union) {
+		t.Fatalf("DataPoints = %d (reported %d), want %d", len(stats.DataPoints), stats.Sampling.ReturnedPoints, len(union))
+	}
+	for i, p := range stats.DataPoints {
+		if !union[p.Date] {
+			t.Errorf("DataPoints[%d] at %s is not selected by any series", i, p.Date)
+		}
+		if i > 0 && !p.Date.After(stats.DataPoints[i-1].Date) {
+			t.Errorf("DataPoints not sorted at %d", i)
+		}
+	}
+}
+
+func TestSampleChargeCurveWithoutOutsideTemp(t *testing.T) {
+	stats := SampleChargeCurve(1, chargePoints(30, false), model.ChargeSamplingLTTB, 5)
+	temps, ok := stats.Series["outsideTemp"]
+	if !ok || temps == nil || len(temps) != 0 {
+		t.Errorf("outsideTemp series = %v, want empty", temps)
+	}
+	if stats.Sampling.SeriesPoints["outsideTemp"] != 0 {
+		t.Errorf("outsideTemp points = %d, want 0", stats.Sampling.SeriesPoints["outsideTemp"])
+	}
+	if len(stats.Series["power"]) != 5 {
+		t.Errorf("power series has %d points, want 5", len(stats.Series["power"]))
+	}
+}
+
+func TestSampleChargeCurveRawAndDefault(t *testing.T) {
+	points := chargePoints(30, false)
+	raw := SampleChargeCurve(1, points, model.ChargeSamplingRaw, 5)
+	if len(raw.DataPoints) != 30 || raw.Sampling.ReturnedPoints != 30 || raw.Series != nil {
+		t.Errorf("raw returned %d points, series %v", len(raw.DataPoints), raw.Series)
+	}
+
+	reduced := SampleChargeCurve(1, points, "", 5)
+	if n := len(reduced.DataPoints); n == 0 || n >= 30 {
+		t.Errorf("default sampling returned %d points", n)
+	}
+	if first, last := reduced.DataPoints[0], reduced.DataPoints[len(reduced.DataPoints)-1]; !first.Date.Equal(points[0].Date) || !last.Date.Equal(points[29].Date) {
+		t.Errorf("default sampling does not keep first and last points")
+	}
+}
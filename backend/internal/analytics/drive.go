@@ -0,0 +1,214 @@
+package analytics
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"teslamate-cyberui/internal/model"
+)
+
+const (
+	// maxSegmentGapSec 相邻轨迹点间隔超过该秒数时视为数据缺失，该段不计入能量和功率区间
+	maxSegmentGapSec = 300
+	// elevationProfilePoints 海拔剖面的最大点数
+	elevationProfilePoints = 500
+	// earthRadiusKm 地球平均半径
+	earthRadiusKm = 6371.0
+)
+
+// powerBandEdges 功率区间边界 (kW)，负值为动能回收
+var powerBandEdges = []float64{-50, -20, 0, 20, 50, 100}
+
+// splitAccumulator 累加一个每公里分段
+type splitAccumulator struct {
+	distance  float64
+	duration  float64
+	energy    float64
+	startElev *int
+	endElev   *int
+}
+
+func (s *splitAccumulator) split(index int) model.DriveDistanceSplit {
+	split := model.DriveDistanceSplit{
+		Index:       index,
+		Distance:    s.distance,
+		DurationSec: s.duration,
+		Energy:      s.energy,
+	}
+	if s.duration > 0 {
+		split.SpeedAvg = s.distance / s.duration * 3600
+	}
+	if s.distance > 0 {
+		split.Efficiency = s.energy / s.distance * 1000
+	}
+	if s.startElev != nil && s.endElev != nil {
+		delta := *s.endElev - *s.startElev
+		split.ElevationDelta = &delta
+	}
+	return split
+}
+
+// AnalyzeDrive 根据行程轨迹点计算海拔剖面、能量收支、功率区间和每公里分段，positions 需按时间排序
+// 能量由相邻两点的平均功率乘以时间间隔得到；累计爬升/下降优先使用 drives 表记录的值
+func AnalyzeDrive(detail *model.DriveDetail, positions []model.DrivePosition) *model.DriveAnalysis {
+	analysis := &model.DriveAnalysis{
+		DriveID:          detail.ID,
+		ElevationProfile: []model.ElevationPoint{},
+		Splits:           []model.DriveDistanceSplit{},
+	}
+
+	bandDurations := make([]float64, len(powerBandEdges)+1)
+	bandEnergy := make([]float64, len(powerBandEdges)+1)
+	cumulative := make([]float64, len(positions))
+	var ascent, descent float64
+	var lastElev *int
+	current := &splitAccumulator{}
+
+	for i := range positions {
+		p := &positions[i]
+		if p.Elevation != nil {
+			if lastElev != nil {
+				if d := float64(*p.Elevation - *lastElev); d > 0 {
+					ascent += d
+				} else {
+					descent -= d
+				}
+			}
+			lastElev = p.Elevation
+		}
+		if i == 0 {
+			current.startElev = p.Elevation
+			current.endElev = p.Elevation
+			continue
+		}
+
+		prev := &positions[i-1]
+		dist := segmentDistance(prev, p)
+		cumulative[i] = cumulative[i-1] + dist
+		dt := p.Date.Sub(prev.Date).Seconds()
+
+		current.distance += dist
+		if dt > 0 {
+			current.duration += dt
+		}
+		if dt > 0 && dt <= maxSegmentGapSec {
+			power := float64(prev.Power+p.Power) / 2
+			energy := power * dt / 3600
+			current.energy += energy
+
+			if energy > 0 {
+				analysis.Energy.Consumed += energy
+			} else {
+				analysis.Energy.Regenerated -= energy
+			}
+			if prev.Elevation != nil && p.Elevation != nil {
+				if *p.Elevation > *prev.Elevation && energy > 0 {
+					analysis.Energy.Climbing += energy
+				} else if *p.Elevation < *prev.Elevation && energy < 0 {
+					analysis.Energy.Descending -= energy
+				}
+			}
+
+			band := sort.Search(len(powerBandEdges), func(k int) bool { return powerBandEdges[k] > power })
+			bandDurations[band] += dt
+			bandEnergy[band] += energy
+		}
+
+		if p.Elevation != nil {
+			if current.startElev == nil {
+				current.startElev = p.Elevation
+			}
+			current.endElev = p.Elevation
+		}
+		if current.distance >= 1 {
+			analysis.Splits = append(analysis.Splits, current.split(len(analysis.Splits)+1))
+			current = &splitAccumulator{startElev: p.Elevation, endElev: p.Elevation}
+		}
+	}
+	// 最后不足 1 km 的一段，忽略只有几米的尾巴
+	if current.distance >= 0.01 {
+		analysis.Splits = append(analysis.Splits, current.split(len(analysis.Splits)+1))
+	}
+
+	if n := len(positions); n > 0 {
+		analysis.Distance = cumulative[n-1]
+		analysis.DurationSec = positions[n-1].Date.Sub(positions[0].Date).Seconds()
+	}
+
+	analysis.Energy.Net = analysis.Energy.Consumed - analysis.Energy.Regenerated
+	if analysis.Energy.Consumed > 0 {
+		analysis.Energy.RegenPercent = analysis.Energy.Regenerated / analysis.Energy.Consumed * 100
+	}
+
+	if detail.Ascent != nil && detail.Descent != nil {
+		analysis.Ascent = float64(*detail.Ascent)
+		analysis.Descent = float64(*detail.Descent)
+		analysis.ElevationSource = model.ElevationSourceDrive
+	} else if lastElev != nil {
+		analysis.Ascent = ascent
+		analysis.Descent = descent
+		analysis.ElevationSource = model.ElevationSourcePositions
+	}
+
+	var xs, ys []float64
+	for i := range positions {
+		if positions[i].Elevation != nil {
+			xs = append(xs, cumulative[i])
+			ys = append(ys, float64(*positions[i].Elevation))
+		}
+	}
+	for _, j := range LTTB(xs, ys, elevationProfilePoints) {
+		analysis.ElevationProfile = append(analysis.ElevationProfile, model.ElevationPoint{Distance: xs[j], Elevation: int(ys[j])})
+	}
+
+	analysis.PowerBands = powerBands(bandDurations, bandEnergy)
+	return analysis
+}
+
+// powerBands 按 powerBandEdges 生成功率区间，首尾区间没有下限 / 上限
+func powerBands(durations, energy []float64) []model.DrivePowerBand {
+	var total float64
+	for _, d := range durations {
+		total += d
+	}
+
+	bands := make([]model.DrivePowerBand, 0, len(durations))
+	for i := range durations {
+		band := model.DrivePowerBand{DurationSec: durations[i], Energy: energy[i]}
+		if i > 0 {
+			lo := powerBandEdges[i-1]
+			band.Min = &lo
+		}
+		if i < len(powerBandEdges) {
+			hi := powerBandEdges[i]
+			band.Max = &hi
+		}
+		switch {
+		case band.Min == nil:
+			band.Label = fmt.Sprintf("<%g", *band.Max)
+		case band.Max == nil:
+			band.Label = fmt.Sprintf(">=%g", *band.Min)
+		default:
+			band.Label = fmt.Sprintf("%g~%g", *band.Min, *band.Max)
+		}
+		if total > 0 {
+			band.Percent = durations[i] / total * 100
+		}
+		bands = append(bands, band)
+	}
+	return bands
+}
+
+// segmentDistance 相邻两个轨迹点之间的距离 (km)，优先使用里程表读数，缺失或回退时按经纬度计算球面距离
+func segmentDistance(a, b *model.DrivePosition) float64 {
+	if a.Odometer != nil && b.Odometer != nil && *b.Odometer >= *a.Odometer {
+		return *b.Odometer - *a.Odometer
+	}
+	lat1 := a.Latitude * math.Pi / 180
+	lat2 := b.Latitude * math.Pi / 180
+	dLat := lat2 - lat1
+	dLon := (b.Longitude - a.Longitude) * math.Pi / 180
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadiusKm * math.Asin(math.Sqrt(h))
+}
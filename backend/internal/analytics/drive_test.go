@@ -0,0 +1,178 @@
+package analytics
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"teslamate-cyberui/internal/model"
+)
+
+// drivePositions 构造一段行程的轨迹点：
+// 0-100s 以 36 kW 爬升 10 m（1 kWh），100-200s 平均功率为 0，200-300s 以 -36 kW 下降 10 m（回收 1 kWh），
+// 之后间隔 700s 的一段视为数据缺失，只计入距离和时长
+func drivePositions() []model.DrivePosition {
+	start := time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC)
+	pos := func(sec int, odo float64, power, elev int) model.DrivePosition {
+		return model.DrivePosition{
+			Date:      start.Add(time.Duration(sec) * time.Second),
+			Power:     power,
+			Elevation: &elev,
+			Odometer:  &odo,
+		}
+	}
+	return []model.DrivePosition{
+		pos(0, 1000.0, 36, 100),
+		pos(100, 1000.5, 36, 110),
+		pos(200, 1001.0, -36, 100),
+		pos(300, 1001.5, -36, 90),
+		pos(1000, 1001.8, 10, 90),
+	}
+}
+
+func TestAnalyzeDriveEnergy(t *testing.T) {
+	a := AnalyzeDrive(&model.DriveDetail{ID: 3}, drivePositions())
+
+	checks := []struct {
+		name      string
+		got, want float64
+	}{
+		{"distance", a.Distance, 1.8},
+		{"duration", a.DurationSec, 1000},
+		{"consumed", a.Energy.Consumed, 1},
+		{"regenerated", a.Energy.Regenerated, 1},
+		{"net", a.Energy.Net, 0},
+		{"climbing", a.Energy.Climbing, 1},
+		{"descending", a.Energy.Descending, 1},
+		{"regen percent", a.Energy.RegenPercent, 100},
+		{"ascent", a.Ascent, 10},
+		{"descent", a.Descent, 20},
+	}
+	for _, c := range checks {
+		if !approx(c.got, c.want, 1e-6) {
+			t.Errorf("%s = %v, want %v", c.name, c.got, c.want)
+		}
+	}
+	if a.DriveID != 3 || a.ElevationSource != model.ElevationSourcePositions {
+		t.Errorf("drive id = %d, elevation source = %q", a.DriveID, a.ElevationSource)
+	}
+	if len(a.ElevationProfile) != 5 || a.ElevationProfile[1].Elevation != 110 || !approx(a.ElevationProfile[4].Distance, 1.8, 1e-6) {
+		t.Errorf("elevation profile = %+v", a.ElevationProfile)
+	}
+}
+
+func TestAnalyzeDriveUsesRecordedAscent(t *testing.T) {
+	ascent, descent := 42, 17
+	a := AnalyzeDrive(&model.DriveDetail{Ascent: &ascent, Descent: &descent}, drivePositions())
+	if a.Ascent != 42 || a.Descent != 17 || a.ElevationSource != model.ElevationSourceDrive {
+		t.Errorf("ascent/descent = %v/%v (%s), want 42/17 from drive", a.Ascent, a.Descent, a.ElevationSource)
+	}
+}
+
+func TestAnalyzeDrivePowerBands(t *testing.T) {
+	a := AnalyzeDrive(&model.DriveDetail{}, drivePositions())
+
+	want := []struct {
+		label    string
+		duration float64
+		energy   float64
+	}{
+		{"<-50", 0, 0},
+		{"-50~-20", 100, -1},
+		{"-20~0", 0, 0},
+		{"0~20", 100, 0},
+		{"20~50", 100, 1},
+		{"50~100", 0, 0},
+		{">=100", 0, 0},
+	}
+	if len(a.PowerBands) != len(want) {
+		t.Fatalf("got %d power bands, want %d", len(a.PowerBands), len(want))
+	}
+	for i, w := range want {
+		b := a.PowerBands[i]
+		if b.Label != w.label || b.DurationSec != w.duration || !approx(b.Energy, w.energy, 1e-9) {
+			t.Errorf("band %d = %s %vs %v kWh, want %s %vs %v kWh", i, b.Label, b.DurationSec, b.Energy, w.label, w.duration, w.energy)
+		}
+		if wantPct := w.duration / 300 * 100; !approx(b.Percent, wantPct, 1e-9) {
+			t.Errorf("band %s percent = %v, want %v", b.Label, b.Percent, wantPct)
+		}
+	}
+	if first, last := a.PowerBands[0], a.PowerBands[len(a.PowerBands)-1]; first.Min != nil || last.Max != nil {
+		t.Errorf("open-ended bands have bounds: first.Min = %v, last.Max = %v", first.Min, last.Max)
+	}
+
+	// 区间为 [Min, Max)，恰好 20 kW 属于 20~50
+	start := time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC)
+	edge := AnalyzeDrive(&model.DriveDetail{}, []model.DrivePosition{
+		{Date: start, Power: 20},
+		{Date: start.Add(time.Minute), Power: 20},
+	})
+	if b := edge.PowerBands[4]; b.Label != "20~50" || b.DurationSec != 60 {
+		t.Errorf("20 kW landed in %+v, want 20~50", edge.PowerBands)
+	}
+}
+
+func TestAnalyzeDriveSplits(t *testing.T) {
+	a := AnalyzeDrive(&model.DriveDetail{}, drivePositions())
+	if len(a.Splits) != 2 {
+		t.Fatalf("got %d splits, want 2: %+v", len(a.Splits), a.Splits)
+	}
+
+	first := a.Splits[0]
+	if first.Index != 1 || !approx(first.Distance, 1, 1e-9) || first.DurationSec != 200 ||
+		!approx(first.Energy, 1, 1e-9) || !approx(first.SpeedAvg, 18, 1e-9) || !approx(first.Efficiency, 1000, 1e-9) {
+		t.Errorf("split 1 = %+v", first)
+	}
+	if first.ElevationDelta == nil || *first.ElevationDelta != 0 {
+		t.Errorf("split 1 elevation delta = %v, want 0", first.ElevationDelta)
+	}
+
+	// 最后不足 1 km 的一段，包含数据缺失的间隔
+	last := a.Splits[1]
+	if last.Index != 2 || !approx(last.Distance, 0.8, 1e-9) || last.DurationSec != 800 || !approx(last.Energy, -1, 1e-9) {
+		t.Errorf("split 2 = %+v", last)
+	}
+	if last.ElevationDelta == nil || *last.ElevationDelta != -10 {
+		t.Errorf("split 2 elevation delta = %v, want -10", last.ElevationDelta)
+	}
+}
+
+func TestAnalyzeDriveDropsTinyTail(t *testing.T) {
+	positions := drivePositions()[:3]
+	odo := 1001.005
+	positions = append(positions, model.DrivePosition{Date: positions[2].Date.Add(time.Second), Odometer: &odo})
+	a := AnalyzeDrive(&model.DriveDetail{}, positions)
+	if len(a.Splits) != 1 {
+		t.Errorf("got %d splits, want the 5 m tail dropped: %+v", len(a.Splits), a.Splits)
+	}
+}
+
+func TestAnalyzeDriveEmpty(t *testing.T) {
+	a := AnalyzeDrive(&model.DriveDetail{ID: 1}, nil)
+	if a.Distance != 0 || len(a.Splits) != 0 || len(a.ElevationProfile) != 0 || a.ElevationSource != "" {
+		t.Errorf("analysis = %+v", a)
+	}
+	if len(a.PowerBands) != len(powerBandEdges)+1 {
+		t.Errorf("got %d power bands, want %d", len(a.PowerBands), len(powerBandEdges)+1)
+	}
+}
+
+func TestSegmentDistance(t *testing.T) {
+	odo := func(v float64) *float64 { return &v }
+	a := model.DrivePosition{Latitude: 0, Longitude: 0, Odometer: odo(100)}
+	b := model.DrivePosition{Latitude: 1, Longitude: 0, Odometer: odo(100.7)}
+	if got := segmentDistance(&a, &b); !approx(got, 0.7, 1e-9) {
+		t.Errorf("odometer distance = %v, want 0.7", got)
+	}
+
+	// 里程表回退或缺失时按经纬度计算，纬度 1° 约 111.19 km
+	want := earthRadiusKm * math.Pi / 180
+	b.Odometer = odo(99)
+	if got := segmentDistance(&a, &b); !approx(got, want, 1e-6) {
+		t.Errorf("distance with odometer going back = %v, want %v", got, want)
+	}
+	b.Odometer = nil
+	if got := segmentDistance(&a, &b); !approx(got, want, 1e-6) {
+		t.Errorf("distance without odometer = %v, want %v", got, want)
+	}
+}
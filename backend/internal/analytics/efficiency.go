@@ -0,0 +1,129 @@
+package analytics
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"teslamate-cyberui/internal/model"
+)
+
+// climateOnThreshold 空调开启的轨迹点占比不低于该值的行程计入空调开启
+const climateOnThreshold = 0.5
+
+// efficiencyAccumulator 累加一个分组的距离和能耗
+type efficiencyAccumulator struct {
+	samples  int
+	distance float64
+	energy   float64
+}
+
+func (a *efficiencyAccumulator) add(s *model.EfficiencySample) {
+	a.samples++
+	a.distance += s.Distance
+	a.energy += s.EnergyUsed
+}
+
+func (a *efficiencyAccumulator) bucket(label string, lo, hi *float64) model.EfficiencyBucket {
+	b := model.EfficiencyBucket{
+		Label:      label,
+		Min:        lo,
+		Max:        hi,
+		Samples:    a.samples,
+		Distance:   a.distance,
+		EnergyUsed: a.energy,
+	}
+	if a.distance > 0 {
+		b.Efficiency = a.energy / a.distance * 1000
+	}
+	return b
+}
+
+// EfficiencyFactors 将行程样本按车外温度、平均速度、每公里爬升和空调状态分组，计算各组按距离加权的能耗 (Wh/km)
+// 某个维度没有数据的样本不参与该维度的分组；空调开启占比不低于一半的行程计入 on，否则计入 off
+func EfficiencyFactors(set *model.EfficiencySamples, steps model.EfficiencyFactorSteps) *model.EfficiencyFactors {
+	samples := set.Samples
+	result := &model.EfficiencyFactors{
+		PreferredRange:   set.PreferredRange,
+		EfficiencySource: set.EfficiencySource,
+		Steps:            steps,
+	}
+
+	var overall efficiencyAccumulator
+	for i := range samples {
+		overall.add(&samples[i])
+	}
+	result.Overall = overall.bucket("all", nil, nil)
+
+	result.Temperature = rangeBuckets(samples, steps.Temperature, func(s *model.EfficiencySample) (float64, bool) {
+		if s.OutsideTemp == nil {
+			return 0, false
+		}
+		return *s.OutsideTemp, true
+	})
+	result.Speed = rangeBuckets(samples, steps.Speed, func(s *model.EfficiencySample) (float64, bool) {
+		if s.SpeedAvg == nil {
+			return 0, false
+		}
+		return *s.SpeedAvg, true
+	})
+	result.Elevation = rangeBuckets(samples, steps.Elevation, func(s *model.EfficiencySample) (float64, bool) {
+		if s.ElevationGain == nil || s.Distance <= 0 {
+			return 0, false
+		}
+		return *s.ElevationGain / s.Distance, true
+	})
+
+	var on, off efficiencyAccumulator
+	for i := range samples {
+		s := &samples[i]
+		if s.ClimateOnRatio == nil {
+			continue
+		}
+		if *s.ClimateOnRatio >= climateOnThreshold {
+			on.add(s)
+		} else {
+			off.add(s)
+		}
+	}
+	result.Climate = []model.EfficiencyBucket{}
+	if off.samples > 0 {
+		result.Climate = append(result.Climate, off.bucket("off", nil, nil))
+	}
+	if on.samples > 0 {
+		result.Climate = append(result.Climate, on.bucket("on", nil, nil))
+	}
+
+	return result
+}
+
+// rangeBuckets 按 step 宽度把样本分到 [k*step, (k+1)*step) 区间，value 返回 false 的样本跳过，结果按区间升序
+func rangeBuckets(samples []model.EfficiencySample, step float64, value func(s *model.EfficiencySample) (float64, bool)) []model.EfficiencyBucket {
+	groups := make(map[int64]*efficiencyAccumulator)
+	for i := range samples {
+		v, ok := value(&samples[i])
+		if !ok || math.IsNaN(v) || math.IsInf(v, 0) {
+			continue
+		}
+		k := int64(math.Floor(v / step))
+		if groups[k] == nil {
+			groups[k] = &efficiencyAccumulator{}
+		}
+		groups[k].add(&samples[i])
+	}
+
+	keys := make([]int64, 0, len(groups))
+	for k := range groups {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+
+	buckets := make([]model.EfficiencyBucket, 0, len(keys))
+	for _, k := range keys {
+		lo := float64(k) * step
+		hi := float64(k+1) * step
+		label := fmt.Sprintf("%g~%g", lo, hi)
+		buckets = append(buckets, groups[k].bucket(label, &lo, &hi))
+	}
+	return buckets
+}
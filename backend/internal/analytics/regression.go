@@ -0,0 +1,150 @@
+// Package analytics 提供统计接口使用的数值计算：线性回归、置信区间、离群点检测和曲线降采样
+package analytics
+
+import (
+	"errors"
+	"math"
+	"sort"
+)
+
+// ErrNotEnoughData 样本数不足以拟合
+var ErrNotEnoughData = errors.New("not enough data points")
+
+// LinearFit 最小二乘线性拟合 y = Intercept + Slope * x
+type LinearFit struct {
+	Slope     float64
+	Intercept float64
+	N         int
+	// SlopeStdErr 斜率的标准误
+	SlopeStdErr float64
+	// ResidualStdDev 残差标准差
+	ResidualStdDev float64
+	RSquared       float64
+
+	meanX float64
+	sxx   float64
+}
+
+// FitLinear 对样本做最小二乘拟合，至少需要 3 个点且 x 不全相同
+func FitLinear(xs, ys []float64) (*LinearFit, error) {
+	n := len(xs)
+	if n != len(ys) || n < 3 {
+		return nil, ErrNotEnoughData
+	}
+
+	var sumX, sumY float64
+	for i := range xs {
+		sumX += xs[i]
+		sumY += ys[i]
+	}
+	meanX, meanY := sumX/float64(n), sumY/float64(n)
+
+	var sxx, sxy, syy float64
+	for i := range xs {
+		dx, dy := xs[i]-meanX, ys[i]-meanY
+		sxx += dx * dx
+		sxy += dx * dy
+		syy += dy * dy
+	}
+	if sxx == 0 {
+		return nil, ErrNotEnoughData
+	}
+
+	fit := &LinearFit{
+		Slope: sxy / sxx,
+		N:     n,
+		meanX: meanX,
+		sxx:   sxx,
+	}
+	fit.Intercept = meanY - fit.Slope*meanX
+
+	var sse float64
+	for i := range xs {
+		r := ys[i] - fit.Predict(xs[i])
+		sse += r * r
+	}
+	fit.ResidualStdDev = math.Sqrt(sse / float64(n-2))
+	fit.SlopeStdErr = fit.ResidualStdDev / math.Sqrt(sxx)
+	if syy > 0 {
+		fit.RSquared = 1 - sse/syy
+	}
+	return fit, nil
+}
+
+// Predict 返回 x 处的拟合值
+func (f *LinearFit) Predict(x float64) float64 {
+	return f.Intercept + f.Slope*x
+}
+
+// ConfidenceBand 返回 x 处拟合均值的 95% 置信区间
+func (f *LinearFit) ConfidenceBand(x float64) (lower, upper float64) {
+	y := f.Predict(x)
+	dx := x - f.meanX
+	half := TQuantile95(f.N-2) * f.ResidualStdDev * math.Sqrt(1/float64(f.N)+dx*dx/f.sxx)
+	return y - half, y + half
+}
+
+// SlopeInterval 返回斜率的 95% 置信区间
+func (f *LinearFit) SlopeInterval() (lower, upper float64) {
+	half := TQuantile95(f.N-2) * f.SlopeStdErr
+	return f.Slope - half, f.Slope + half
+}
+
+// tTable 双侧 95% 的 t 分布分位数，下标为自由度
+var tTable = []float64{
+	0, 12.706, 4.303, 3.182, 2.776, 2.571, 2.447, 2.365, 2.306, 2.262, 2.228,
+	2.201, 2.179, 2.160, 2.145, 2.131, 2.120, 2.110, 2.101, 2.093, 2.086,
+	2.080, 2.074, 2.069, 2.064, 2.060, 2.056, 2.052, 2.048, 2.045, 2.042,
+}
+
+// TQuantile95 返回自由度为 df 的双侧 95% t 分位数，df 较大时近似为正态分布
+func TQuantile95(df int) float64 {
+	switch {
+	case df < 1:
+		return math.Inf(1)
+	case df < len(tTable):
+		return tTable[df]
+	case df <= 60:
+		return 2.000
+	case df <= 120:
+		return 1.980
+	default:
+		return 1.960
+	}
+}
+
+// Median 返回中位数，不修改输入
+func Median(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 1 {
+		return sorted[mid]
+	}
+	return (sorted[mid-1] + sorted[mid]) / 2
+}
+
+// Outliers 使用修正 Z 分数（基于中位数绝对偏差）标记离群值，
+// |0.6745 * (v - median) / MAD| > threshold 视为离群，常用阈值为 3.5
+func Outliers(values []float64, threshold float64) []bool {
+	flags := make([]bool, len(values))
+	if len(values) < 3 {
+		return flags
+	}
+	median := Median(values)
+	deviations := make([]float64, len(values))
+	for i, v := range values {
+		deviations[i] = math.Abs(v - median)
+	}
+	mad := Median(deviations)
+	if mad == 0 {
+		return flags
+	}
+	for i, d := range deviations {
+		flags[i] = 0.6745*d/mad > threshold
+	}
+	return flags
+}
@@ -0,0 +1,148 @@
+package analytics
+
+import (
+	"math"
+	"testing"
+)
+
+func approx(a, b, tol float64) bool {
+	return math.Abs(a-b) <= tol
+}
+
+// 参考值来自 R：fit <- lm(y ~ x, data.frame(x = 1:5, y = c(2, 4, 5, 4, 5)))
+// summary(fit)、confint(fit) 和 predict(fit, data.frame(x = c(3, 5)), interval = "confidence")。
+// TQuantile95 使用三位小数的 t 分位数，区间端点允许 1e-3 的误差
+func TestFitLinearKnownAnswer(t *testing.T) {
+	fit, err := FitLinear([]float64{1, 2, 3, 4, 5}, []float64{2, 4, 5, 4, 5})
+	if err != nil {
+		t.Fatalf("FitLinear: %v", err)
+	}
+	checks := []struct {
+		name      string
+		got, want float64
+	}{
+		{"slope", fit.Slope, 0.6},
+		{"intercept", fit.Intercept, 2.2},
+		{"residual std dev", fit.ResidualStdDev, 0.894427},
+		{"slope std err", fit.SlopeStdErr, 0.282843},
+		{"r squared", fit.RSquared, 0.6},
+		{"predict(5)", fit.Predict(5), 5.2},
+	}
+	for _, c := range checks {
+		if !approx(c.got, c.want, 1e-6) {
+			t.Errorf("%s = %.6f, want %.6f", c.name, c.got, c.want)
+		}
+	}
+	if fit.N != 5 {
+		t.Errorf("N = %d, want 5", fit.N)
+	}
+
+	lower, upper := fit.SlopeInterval()
+	if !approx(lower, -0.300138, 1e-3) || !approx(upper, 1.500138, 1e-3) {
+		t.Errorf("SlopeInterval = [%.6f, %.6f], want [-0.300138, 1.500138]", lower, upper)
+	}
+	lower, upper = fit.ConfidenceBand(3)
+	if !approx(lower, 2.727022, 1e-3) || !approx(upper, 5.272978, 1e-3) {
+		t.Errorf("ConfidenceBand(3) = [%.6f, %.6f], want [2.727022, 5.272978]", lower, upper)
+	}
+	lower, upper = fit.ConfidenceBand(5)
+	if !approx(lower, 2.995137, 1e-3) || !approx(upper, 7.404863, 1e-3) {
+		t.Errorf("ConfidenceBand(5) = [%.6f, %.6f], want [2.995137, 7.404863]", lower, upper)
+	}
+}
+
+func TestFitLinearExact(t *testing.T) {
+	xs := []float64{0, 10, 20, 30}
+	ys := []float64{3, 23, 43, 63}
+	fit, err := FitLinear(xs, ys)
+	if err != nil {
+		t.Fatalf("FitLinear: %v", err)
+	}
+	if !approx(fit.Slope, 2, 1e-12) || !approx(fit.Intercept, 3, 1e-12) || !approx(fit.RSquared, 1, 1e-12) {
+		t.Errorf("fit = %+v, want y = 3 + 2x with R² = 1", fit)
+	}
+	if fit.ResidualStdDev > 1e-9 || fit.SlopeStdErr > 1e-9 {
+		t.Errorf("residual std dev = %g, slope std err = %g, want 0", fit.ResidualStdDev, fit.SlopeStdErr)
+	}
+	lower, upper := fit.ConfidenceBand(15)
+	if !approx(lower, 33, 1e-9) || !approx(upper, 33, 1e-9) {
+		t.Errorf("ConfidenceBand(15) = [%g, %g], want [33, 33]", lower, upper)
+	}
+}
+
+func TestFitLinearNotEnoughData(t *testing.T) {
+	tests := []struct {
+		name   string
+		xs, ys []float64
+	}{
+		{"empty", nil, nil},
+		{"two points", []float64{1, 2}, []float64{1, 2}},
+		{"length mismatch", []float64{1, 2, 3}, []float64{1, 2}},
+		{"constant x", []float64{4, 4, 4}, []float64{1, 2, 3}},
+	}
+	for _, tt := range tests {
+		if _, err := FitLinear(tt.xs, tt.ys); err != ErrNotEnoughData {
+			t.Errorf("%s: err = %v, want ErrNotEnoughData", tt.name, err)
+		}
+	}
+}
+
+func TestTQuantile95(t *testing.T) {
+	tests := []struct {
+		df   int
+		want float64
+	}{
+		{1, 12.706},
+		{3, 3.182},
+		{10, 2.228},
+		{30, 2.042},
+		{45, 2.000},
+		{100, 1.980},
+		{1000, 1.960},
+	}
+	for _, tt := range tests {
+		if got := TQuantile95(tt.df); got != tt.want {
+			t.Errorf("TQuantile95(%d) = %v, want %v", tt.df, got, tt.want)
+		}
+	}
+	if got := TQuantile95(0); !math.IsInf(got, 1) {
+		t.Errorf("TQuantile95(0) = %v, want +Inf", got)
+	}
+}
+
+func TestMedian(t *testing.T) {
+	values := []float64{5, 1, 3}
+	if got := Median(values); got != 3 {
+		t.Errorf("Median(odd) = %v, want 3", got)
+	}
+	if values[0] != 5 || values[1] != 1 {
+		t.Errorf("Median modified its input: %v", values)
+	}
+	if got := Median([]float64{4, 1, 3, 2}); got != 2.5 {
+		t.Errorf("Median(even) = %v, want 2.5", got)
+	}
+	if got := Median(nil); got != 0 {
+		t.Errorf("Median(nil) = %v, want 0", got)
+	}
+}
+
+func TestOutliers(t *testing.T) {
+	// 中位数 11，MAD 1：50 的修正 Z 分数为 0.6745 * 39 ≈ 26.3
+	got := Outliers([]float64{10, 11, 10, 12, 11, 50}, 3.5)
+	want := []bool{false, false, false, false, false, true}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Outliers = %v, want %v", got, want)
+			break
+		}
+	}
+
+	// MAD 为 0 或样本少于 3 个时不标记
+	for _, values := range [][]float64{{5, 5, 5, 9}, {1, 100}} {
+		for i, flagged := range Outliers(values, 3.5) {
+			if flagged {
+				t.Errorf("Outliers(%v) flagged index %d", values, i)
+			}
+		}
+	}
+}
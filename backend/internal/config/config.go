@@ -3,6 +3,7 @@ package config
 import (
 	"os"
 	"strings"
+	"time"
 )
 
 // MQTTConfig MQTT配置
@@ -12,6 +13,21 @@ type MQTTConfig struct {
 	Username string
 	Password string
 	ClientID string
+	// SyncInterval 与数据库车辆列表对齐订阅的间隔
+	SyncInterval time.Duration
+	// Namespace 对应 TeslaMate 的 MQTT_NAMESPACE，topic 为 teslamate/<namespace>/cars/...
+	Namespace string
+	// Scheme 连接协议：tcp / ssl / ws / wss
+	Scheme string
+	// WSPath websocket 连接的路径，仅 ws / wss 时生效
+	WSPath string
+	// CACert 私有 CA 证书路径（PEM）
+	CACert string
+	// ClientCert / ClientKey 客户端证书和私钥路径（PEM），用于双向 TLS
+	ClientCert string
+	ClientKey  string
+	// TLSVerify 是否校验服务端证书
+	TLSVerify bool
 }
 
 // Config 应用配置
@@ -29,7 +45,9 @@ type ServerConfig struct {
 	Mode        string
 	CORSOrigins []string
 	APIKey      string
-	EnableMock  bool
+	// AdminKey 写入 TeslaMate 数据（如地理围栏）所需的管理密钥，为空时禁用写入接口
+	AdminKey   string
+	EnableMock bool
 }
 
 // DatabaseConfig 数据库配置
@@ -74,6 +92,16 @@ func getEnvSlice(key string, defaultValue []string) []string {
 	return defaultValue
 }
 
+// getEnvDuration 获取时长类型的环境变量（如 5m、30s），为空或格式错误时返回默认值
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	if value := os.Getenv(key); value != "" {
+		if d, err := time.ParseDuration(value); err == nil && d > 0 {
+			return d
+		}
+	}
+	return defaultValue
+}
+
 // Load 加载配置
 func Load() (*Config, error) {
 	cfg := &Config{
@@ -83,6 +111,7 @@ func Load() (*Config, error) {
 			Mode:        getEnv("CYBERUI_SERVER_MODE", "debug"),
 			CORSOrigins: getEnvSlice("CYBERUI_CORS_ORIGINS", []string{"*"}),
 			APIKey:      getEnv("CYBERUI_API_KEY", ""),
+			AdminKey:    getEnv("CYBERUI_ADMIN_KEY", ""),
 			EnableMock:  getEnv("CYBERUI_MOCK_DATA", "false") == "true",
 		},
 		Database: DatabaseConfig{
@@ -97,11 +126,19 @@ func Load() (*Config, error) {
 			Level: getEnv("LOG_LEVEL", "info"),
 		},
 		MQTT: MQTTConfig{
-			Host:     getEnv("TESLAMATE_MQTT_HOST", getEnv("TESLAMATE_DB_HOST", "localhost")),
-			Port:     getEnv("TESLAMATE_MQTT_PORT", "1883"),
-			Username: getEnv("TESLAMATE_MQTT_USERNAME", ""),
-			Password: getEnv("TESLAMATE_MQTT_PASSWORD", ""),
-			ClientID: getEnv("TESLAMATE_MQTT_CLIENT_ID", "teslamate-cyberui-backend"),
+			Host:         getEnv("TESLAMATE_MQTT_HOST", getEnv("TESLAMATE_DB_HOST", "localhost")),
+			Port:         getEnv("TESLAMATE_MQTT_PORT", "1883"),
+			Username:     getEnv("TESLAMATE_MQTT_USERNAME", ""),
+			Password:     getEnv("TESLAMATE_MQTT_PASSWORD", ""),
+			ClientID:     getEnv("TESLAMATE_MQTT_CLIENT_ID", "teslamate-cyberui-backend"),
+			SyncInterval: getEnvDuration("TESLAMATE_MQTT_SYNC_INTERVAL", 5*time.Minute),
+			Namespace:    getEnv("TESLAMATE_MQTT_NAMESPACE", ""),
+			Scheme:       getEnv("TESLAMATE_MQTT_SCHEME", "tcp"),
+			WSPath:       getEnv("TESLAMATE_MQTT_WS_PATH", "/"),
+			CACert:       getEnv("TESLAMATE_MQTT_CA_CERT", ""),
+			ClientCert:   getEnv("TESLAMATE_MQTT_CLIENT_CERT", ""),
+			ClientKey:    getEnv("TESLAMATE_MQTT_CLIENT_KEY", ""),
+			TLSVerify:    getEnv("TESLAMATE_MQTT_TLS_VERIFY", "true") != "false",
 		},
 	}
 
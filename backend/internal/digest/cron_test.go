@@ -0,0 +1,164 @@
+package digest
+
+import (
+	"testing"
+	"time"
+	_ "time/tzdata"
+)
+
+func TestScheduleMatches(t *testing.T) {
+	tests := []struct {
+		spec string
+		at   string
+		want bool
+	}{
+		// 步长
+		{"*/15 * * * *", "2024-01-10 08:30", true},
+		{"*/15 * * * *", "2024-01-10 08:10", false},
+		{"5/20 * * * *", "2024-01-10 08:45", true},
+		{"5/20 * * * *", "2024-01-10 08:40", false},
+		// 范围和带步长的范围
+		{"0 9-17 * * *", "2024-01-10 17:00", true},
+		{"0 9-17 * * *", "2024-01-10 18:00", false},
+		{"0 9-17/4 * * *", "2024-01-10 13:00", true},
+		{"0 9-17/4 * * *", "2024-01-10 11:00", false},
+		// 列表
+		{"0 0 1,15 * *", "2024-01-15 00:00", true},
+		{"0 0 1,15 * *", "2024-01-14 00:00", false},
+		{"0,30 6,18 * * *", "2024-01-10 18:30", true},
+		// 英文缩写，7 也表示周日
+		{"0 0 * JAN-MAR MON-FRI", "2024-02-05 00:00", true},
+		{"0 0 * JAN-MAR MON-FRI", "2024-04-01 00:00", false},
+		{"0 0 * * 7", "2024-01-07 00:00", true},
+		{"0 0 * * sun", "2024-01-07 00:00", true},
+		// 简写
+		{"@daily", "2024-01-10 00:00", true},
+		{"@daily", "2024-01-10 00:01", false},
+		{"@weekly", "2024-01-07 00:00", true},
+		{"@weekly", "2024-01-08 00:00", false},
+		{"@monthly", "2024-02-01 00:00", true},
+		// 日和周都被限定时满足其一即可
+		{"0 0 13 * FRI", "2024-09-13 00:00", true},
+		{"0 0 13 * FRI", "2024-01-13 00:00", true},
+		{"0 0 13 * FRI", "2024-01-12 00:00", true},
+		{"0 0 13 * FRI", "2024-01-11 00:00", false},
+		// 以 * 开头的日或周（含 */n）视为不限定，两者需同时满足
+		{"0 0 * * MON", "2024-01-08 00:00", true},
+		{"0 0 * * MON", "2024-01-09 00:00", false},
+		{"0 0 */2 * MON", "2024-01-01 00:00", true},
+		{"0 0 */2 * MON", "2024-01-08 00:00", false},
+		{"0 0 */2 * MON", "2024-01-03 00:00", false},
+		{"0 0 1 * */2", "2024-02-01 00:00", true},
+		{"0 0 1 * */2", "2024-03-01 00:00", false},
+	}
+	for _, tt := range tests {
+		s, err := ParseSchedule(tt.spec)
+		if err != nil {
+			t.Errorf("ParseSchedule(%q): %v", tt.spec, err)
+			continue
+		}
+		at, _ := time.ParseInLocation("2006-01-02 15:04", tt.at, time.UTC)
+		if got := s.Matches(at); got != tt.want {
+			t.Errorf("%q Matches(%s) = %v, want %v", tt.spec, tt.at, got, tt.want)
+		}
+	}
+}
+
+func TestParseScheduleErrors(t *testing.T) {
+	for _, spec := range []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"abc * * * *",
+		"@every5m",
+	} {
+		if _, err := ParseSchedule(spec); err == nil {
+			t.Errorf("ParseSchedule(%q) succeeded, want error", spec)
+		}
+	}
+}
+
+func TestScheduleNext(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatalf("load location: %v", err)
+	}
+	tests := []struct {
+		name  string
+		spec  string
+		loc   *time.Location
+		after string
+		want  string
+	}{
+		{"next minute", "* * * * *", time.UTC, "2024-01-10 08:30", "2024-01-10 08:31"},
+		{"month end rollover", "0 0 * * *", time.UTC, "2024-01-31 23:59", "2024-02-01 00:00"},
+		{"year end rollover", "0 0 1 * *", time.UTC, "2024-12-15 12:00", "2025-01-01 00:00"},
+		{"skips short months", "0 0 31 * *", time.UTC, "2024-04-15 00:00", "2024-05-31 00:00"},
+		{"leap day", "0 0 29 2 *", time.UTC, "2023-03-01 00:00", "2024-02-29 00:00"},
+		// 2024-03-10 02:00 EST 跳到 03:00 EDT
+		{"dst start hourly", "0 * * * *", ny, "2024-03-10 01:30", "2024-03-10 03:00"},
+		{"dst start after gap", "0 3 * * *", ny, "2024-03-10 00:00", "2024-03-10 03:00"},
+		{"dst start skipped time", "30 2 * * *", ny, "2024-03-10 00:00", "2024-03-11 02:30"},
+	}
+	for _, tt := range tests {
+		s, err := ParseSchedule(tt.spec)
+		if err != nil {
+			t.Fatalf("ParseSchedule(%q): %v", tt.spec, err)
+		}
+		after, _ := time.ParseInLocation("2006-01-02 15:04", tt.after, tt.loc)
+		want, _ := time.ParseInLocation("2006-01-02 15:04", tt.want, tt.loc)
+		if got := s.Next(after); !got.Equal(want) {
+			t.Errorf("%s: Next(%s) = %s, want %s", tt.name, after, got, want)
+		}
+	}
+}
+
+func TestScheduleNextDSTEnd(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatalf("load location: %v", err)
+	}
+	s, err := ParseSchedule("30 1 * * *")
+	if err != nil {
+		t.Fatalf("ParseSchedule: %v", err)
+	}
+
+	// 2024-11-03 02:00 EDT 回拨到 01:00 EST，01:30 出现两次，只在第一次触发
+	first := time.Date(2024, 11, 3, 5, 30, 0, 0, time.UTC).In(ny)
+	second := first.Add(time.Hour)
+	if got := s.Next(time.Date(2024, 11, 3, 0, 0, 0, 0, ny)); !got.Equal(first) {
+		t.Errorf("Next = %s, want %s", got, first)
+	}
+	if !s.Matches(first) {
+		t.Errorf("Matches(%s) = false, want true", first)
+	}
+	if s.Matches(second) {
+		t.Errorf("Matches(%s) = true, want false", second)
+	}
+	if got, want := s.Next(first), time.Date(2024, 11, 4, 1, 30, 0, 0, ny); !got.Equal(want) {
+		t.Errorf("Next(%s) = %s, want %s", first, got, want)
+	}
+
+	// 每小时的计划在回拨后的一小时内不重复触发
+	hourly, _ := ParseSchedule("15 * * * *")
+	if got, want := hourly.Next(first), time.Date(2024, 11, 3, 2, 15, 0, 0, ny); !got.Equal(want) {
+		t.Errorf("hourly Next(%s) = %s, want %s", first, got, want)
+	}
+}
+
+func TestScheduleNextImpossible(t *testing.T) {
+	s, err := ParseSchedule("0 0 30 2 *")
+	if err != nil {
+		t.Fatalf("ParseSchedule: %v", err)
+	}
+	if got := s.Next(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)); !got.IsZero() {
+		t.Errorf("Next = %s, want zero time", got)
+	}
+}
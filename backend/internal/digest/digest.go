@@ -0,0 +1,128 @@
+// Package digest 生成每辆车的周期行程/充电摘要，并按 cron 计划推送到通知渠道
+package digest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"teslamate-cyberui/internal/model"
+	"teslamate-cyberui/internal/repository"
+)
+
+// topLocations 摘要中列出的充电地点数量
+const topLocations = 5
+
+// ErrCarNotFound 车辆不存在
+var ErrCarNotFound = errors.New("car not found")
+
+// ValidPeriod 判断周期是否合法
+func ValidPeriod(period string) bool {
+	switch period {
+	case model.DigestPeriodDay, model.DigestPeriodWeek, model.DigestPeriodMonth:
+		return true
+	}
+	return false
+}
+
+// PeriodRange 返回截止到 now 的周期区间：最近 24 小时、最近 7 天或最近一个月
+func PeriodRange(period string, now time.Time) (time.Time, time.Time, error) {
+	switch period {
+	case model.DigestPeriodDay:
+		return now.AddDate(0, 0, -1), now, nil
+	case model.DigestPeriodWeek:
+		return now.AddDate(0, 0, -7), now, nil
+	case model.DigestPeriodMonth:
+		return now.AddDate(0, -1, 0), now, nil
+	}
+	return time.Time{}, time.Time{}, fmt.Errorf("period must be day, week or month")
+}
+
+// Builder 基于现有的统计查询组装摘要
+type Builder struct {
+	repo *repository.Repository
+}
+
+// NewBuilder 创建摘要生成器
+func NewBuilder(repo *repository.Repository) *Builder {
+	return &Builder{repo: repo}
+}
+
+// Build 生成车辆截止到 now 的周期摘要，车辆不存在时返回 ErrCarNotFound
+func (b *Builder) Build(ctx context.Context, carID int16, period string, now time.Time) (*model.Digest, error) {
+	start, end, err := PeriodRange(period, now)
+	if err != nil {
+		return nil, err
+	}
+
+	car, err := b.repo.Car.GetByID(ctx, carID)
+	if err != nil {
+		return nil, err
+	}
+	if car == nil {
+		return nil, ErrCarNotFound
+	}
+
+	d := &model.Digest{
+		CarID:                carID,
+		CarName:              car.DisplayName(),
+		Period:               period,
+		StartDate:            start.UTC(),
+		EndDate:              end.UTC(),
+		GeneratedAt:          now.UTC(),
+		TopChargingLocations: []model.ChargeLocationStat{},
+	}
+
+	drives, err := b.repo.Drive.GetStatsSummary(ctx, carID, &start, &end)
+	if err != nil {
+		return nil, err
+	}
+	d.Distance = drives.TotalDistance
+	d.DriveCount = drives.DriveCount
+	d.MaxSpeed = drives.MaxSpeed
+
+	charges, err := b.repo.Charge.GetStatsSummary(ctx, carID, &start, &end)
+	if err != nil {
+		return nil, err
+	}
+	d.EnergyAdded = charges.TotalEnergy
+	d.ChargeCount = charges.TotalCount
+	d.ChargeDurationMin = charges.TotalDuration
+	d.Cost = charges.TotalCost
+
+	locations := append([]model.ChargeLocationStat(nil), charges.LocationStats...)
+	sort.SliceStable(locations, func(i, j int) bool {
+		if locations[i].TotalEnergy != locations[j].TotalEnergy {
+			return locations[i].TotalEnergy > locations[j].TotalEnergy
+		}
+		return locations[i].Count > locations[j].Count
+	})
+	if len(locations) > topLocations {
+		locations = locations[:topLocations]
+	}
+	d.TopChargingLocations = append(d.TopChargingLocations, locations...)
+
+	if err := b.fillEfficiency(ctx, d, start, end); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// fillEfficiency 统计周期内行程的能耗和平均能效，使用与能效统计接口相同的查询，行程范围与里程、次数等字段一致
+func (b *Builder) fillEfficiency(ctx context.Context, d *model.Digest, start, end time.Time) error {
+	usage, err := b.repo.Stats.GetEnergyUsage(ctx, d.CarID, &start, &end)
+	if err != nil {
+		return err
+	}
+	if usage.Distance <= 0 {
+		return nil
+	}
+	energy := usage.EnergyUsed
+	efficiency := energy * 1000 / usage.Distance
+	d.EnergyUsed = &energy
+	d.Efficiency = &efficiency
+	d.EfficiencySource = usage.EfficiencySource
+	return nil
+}
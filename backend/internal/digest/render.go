@@ -0,0 +1,121 @@
+package digest
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"strings"
+	"time"
+
+	"teslamate-cyberui/internal/model"
+)
+
+// 输出格式
+const (
+	FormatJSON     = "json"
+	FormatMarkdown = "markdown"
+	FormatHTML     = "html"
+)
+
+var periodTitles = map[string]string{
+	model.DigestPeriodDay:   "Daily",
+	model.DigestPeriodWeek:  "Weekly",
+	model.DigestPeriodMonth: "Monthly",
+}
+
+// Title 摘要标题，如 "Weekly digest · My Model 3"
+func Title(d *model.Digest) string {
+	return fmt.Sprintf("%s digest · %s", periodTitles[d.Period], d.CarName)
+}
+
+// dateRange 以服务器时区格式化摘要区间
+func dateRange(d *model.Digest) string {
+	layout := "2006-01-02 15:04"
+	return d.StartDate.In(time.Local).Format(layout) + " – " + d.EndDate.In(time.Local).Format(layout)
+}
+
+// digestLines 摘要的各项指标，Markdown 和 HTML 共用
+func digestLines(d *model.Digest) [][2]string {
+	lines := [][2]string{
+		{"Distance", fmt.Sprintf("%.1f km", d.Distance)},
+		{"Drives", fmt.Sprintf("%d", d.DriveCount)},
+	}
+	if d.DriveCount > 0 {
+		lines = append(lines, [2]string{"Max speed", fmt.Sprintf("%d km/h", d.MaxSpeed)})
+	}
+	if d.Efficiency != nil {
+		lines = append(lines, [2]string{"Efficiency", fmt.Sprintf("%.0f Wh/km (%.1f kWh used)", *d.Efficiency, *d.EnergyUsed)})
+	}
+	lines = append(lines,
+		[2]string{"Energy added", fmt.Sprintf("%.1f kWh", d.EnergyAdded)},
+		[2]string{"Charges", fmt.Sprintf("%d (%s)", d.ChargeCount, formatMinutes(d.ChargeDurationMin))},
+	)
+	if d.Cost > 0 {
+		lines = append(lines, [2]string{"Cost", fmt.Sprintf("%.2f", d.Cost)})
+	}
+	return lines
+}
+
+func formatMinutes(min float64) string {
+	m := int(min + 0.5)
+	if m < 60 {
+		return fmt.Sprintf("%d min", m)
+	}
+	return fmt.Sprintf("%dh %02dmin", m/60, m%60)
+}
+
+// Markdown 渲染为 Markdown，同时作为纯文本通知的正文
+func Markdown(d *model.Digest) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n\n", Title(d))
+	fmt.Fprintf(&b, "%s\n\n", dateRange(d))
+	for _, line := range digestLines(d) {
+		fmt.Fprintf(&b, "- **%s:** %s\n", line[0], line[1])
+	}
+	if len(d.TopChargingLocations) > 0 {
+		b.WriteString("\n## Top charging locations\n\n")
+		for i, loc := range d.TopChargingLocations {
+			fmt.Fprintf(&b, "%d. %s — %.1f kWh, %d sessions\n", i+1, loc.Location, loc.TotalEnergy, loc.Count)
+		}
+	}
+	return b.String()
+}
+
+var htmlTemplate = template.Must(template.New("digest").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>{{.Title}}</title></head>
+<body style="font-family: -apple-system, 'Segoe UI', Helvetica, Arial, sans-serif; color: #1f2933; max-width: 560px; margin: 0 auto; padding: 16px;">
+<h2 style="margin-bottom: 4px;">{{.Title}}</h2>
+<p style="color: #7b8794; margin-top: 0;">{{.Range}}</p>
+<table style="border-collapse: collapse; width: 100%;">
+{{- range .Lines}}
+<tr><td style="padding: 6px 0; border-bottom: 1px solid #e4e7eb;">{{index . 0}}</td><td style="padding: 6px 0; border-bottom: 1px solid #e4e7eb; text-align: right; font-weight: 600;">{{index . 1}}</td></tr>
+{{- end}}
+</table>
+{{- if .Locations}}
+<h3>Top charging locations</h3>
+<table style="border-collapse: collapse; width: 100%;">
+<tr><th style="text-align: left; padding: 4px 0;">Location</th><th style="text-align: right; padding: 4px 0;">Energy</th><th style="text-align: right; padding: 4px 0;">Sessions</th></tr>
+{{- range .Locations}}
+<tr><td style="padding: 4px 0;">{{.Location}}</td><td style="text-align: right; padding: 4px 0;">{{printf "%.1f" .TotalEnergy}} kWh</td><td style="text-align: right; padding: 4px 0;">{{.Count}}</td></tr>
+{{- end}}
+</table>
+{{- end}}
+</body>
+</html>
+`))
+
+// HTML 渲染为独立的 HTML 页面，用于浏览器预览和邮件正文
+func HTML(d *model.Digest) (string, error) {
+	var buf bytes.Buffer
+	err := htmlTemplate.Execute(&buf, map[string]interface{}{
+		"Title":     Title(d),
+		"Range":     dateRange(d),
+		"Lines":     digestLines(d),
+		"Locations": d.TopChargingLocations,
+	})
+	if err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
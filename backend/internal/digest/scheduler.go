@@ -0,0 +1,212 @@
+package digest
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"teslamate-cyberui/internal/logger"
+	"teslamate-cyberui/internal/model"
+	"teslamate-cyberui/internal/notify"
+	"teslamate-cyberui/internal/repository"
+)
+
+const (
+	// maxCatchUp 一次唤醒最多补检查的分钟数（推送耗时较长或系统休眠后）
+	maxCatchUp = 60
+	// sendTimeout 生成并推送一个计划的全部摘要的超时时间
+	sendTimeout = 2 * time.Minute
+)
+
+// LoadSchedules 从 UI 设置中读取摘要计划，未配置时返回空列表
+func LoadSchedules(settings repository.UISettingRepository) ([]model.DigestSchedule, error) {
+	setting, err := settings.Get(model.DigestSchedulesKey)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return []model.DigestSchedule{}, nil
+		}
+		return nil, err
+	}
+	if strings.TrimSpace(setting.Value) == "" {
+		return []model.DigestSchedule{}, nil
+	}
+
+	var schedules []model.DigestSchedule
+	if err := json.Unmarshal([]byte(setting.Value), &schedules); err != nil {
+		return nil, fmt.Errorf("invalid %s setting: %v", model.DigestSchedulesKey, err)
+	}
+	return schedules, nil
+}
+
+// SaveSchedules 校验后保存摘要计划
+func SaveSchedules(settings repository.UISettingRepository, schedules []model.DigestSchedule) error {
+	if err := ValidateSchedules(schedules); err != nil {
+		return err
+	}
+	if schedules == nil {
+		schedules = []model.DigestSchedule{}
+	}
+	b, err := json.Marshal(schedules)
+	if err != nil {
+		return err
+	}
+	return settings.Set(model.DigestSchedulesKey, string(b))
+}
+
+// ValidateSchedules 校验计划的周期、cron 表达式和渠道
+func ValidateSchedules(schedules []model.DigestSchedule) error {
+	for i, s := range schedules {
+		name := s.Name
+		if name == "" {
+			name = fmt.Sprintf("#%d", i+1)
+		}
+		if !ValidPeriod(s.Period) {
+			return fmt.Errorf("schedule %s: period must be day, week or month", name)
+		}
+		if _, err := ParseSchedule(s.Cron); err != nil {
+			return fmt.Errorf("schedule %s: %v", name, err)
+		}
+		if s.Enabled && len(s.ChannelIDs) == 0 {
+			return fmt.Errorf("schedule %s: at least one notification channel is required", name)
+		}
+	}
+	return nil
+}
+
+// Scheduler 每分钟检查摘要计划，到期时生成摘要并推送
+// 计划保存在 UI 设置中，每次检查时重新读取，修改后无需重启
+type Scheduler struct {
+	repo     *repository.Repository
+	builder  *Builder
+	notifier *notify.Dispatcher
+}
+
+// NewScheduler 创建摘要调度器
+func NewScheduler(repo *repository.Repository, notifier *notify.Dispatcher) *Scheduler {
+	return &Scheduler{
+		repo:     repo,
+		builder:  NewBuilder(repo),
+		notifier: notifier,
+	}
+}
+
+// Run 运行调度器直到 ctx 结束
+func (s *Scheduler) Run(ctx context.Context) {
+	logger.Info("Digest scheduler started")
+	last := time.Now().In(time.Local).Truncate(time.Minute)
+	for {
+		next := last.Add(time.Minute)
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			logger.Info("Digest scheduler stopped")
+			return
+		case <-timer.C:
+		}
+
+		// 依次检查从上次到现在的每一分钟，避免推送耗时过长时漏掉计划
+		now := time.Now().In(time.Local).Truncate(time.Minute)
+		if now.Sub(last) > maxCatchUp*time.Minute {
+			last = now.Add(-maxCatchUp * time.Minute)
+		}
+		for t := last.Add(time.Minute); !t.After(now); t = t.Add(time.Minute) {
+			s.check(ctx, t)
+		}
+		if now.After(last) {
+			last = now
+		}
+	}
+}
+
+// check 推送在 t 这一分钟到期的计划
+func (s *Scheduler) check(ctx context.Context, t time.Time) {
+	schedules, err := LoadSchedules(s.repo.UISetting)
+	if err != nil {
+		logger.Errorf("Failed to load digest schedules: %v", err)
+		return
+	}
+
+	for _, schedule := range schedules {
+		if !schedule.Enabled || len(schedule.ChannelIDs) == 0 {
+			continue
+		}
+		cron, err := ParseSchedule(schedule.Cron)
+		if err != nil {
+			logger.Warnf("Skipping digest schedule %q: %v", schedule.Name, err)
+			continue
+		}
+		if !cron.Matches(t) {
+			continue
+		}
+		s.send(ctx, schedule)
+	}
+}
+
+// send 为计划涉及的每辆车生成摘要并推送
+func (s *Scheduler) send(ctx context.Context, schedule model.DigestSchedule) {
+	ctx, cancel := context.WithTimeout(ctx, sendTimeout)
+	defer cancel()
+
+	var carIDs []int16
+	if schedule.CarID != nil {
+		carIDs = []int16{*schedule.CarID}
+	} else {
+		cars, err := s.repo.Car.GetAll(ctx)
+		if err != nil {
+			logger.Errorf("Failed to list cars for digest schedule %q: %v", schedule.Name, err)
+			return
+		}
+		for _, car := range cars {
+			carIDs = append(carIDs, car.ID)
+		}
+	}
+
+	now := time.Now()
+	for _, carID := range carIDs {
+		d, err := s.builder.Build(ctx, carID, schedule.Period, now)
+		if err != nil {
+			logger.Errorf("Failed to build %s digest for car %d: %v", schedule.Period, carID, err)
+			continue
+		}
+		msg, err := Message(d)
+		if err != nil {
+			logger.Errorf("Failed to render %s digest for car %d: %v", schedule.Period, carID, err)
+			continue
+		}
+		logger.Infof("Sending %s digest for car %d (schedule %q)", schedule.Period, carID, schedule.Name)
+		s.notifier.Dispatch(ctx, schedule.ChannelIDs, msg)
+	}
+}
+
+// Message 将摘要转换为通知消息：正文为 Markdown，邮件渠道附带 HTML，
+// 模板可以通过 {{.Data.distance}} 等引用摘要的 JSON 字段
+func Message(d *model.Digest) (notify.Message, error) {
+	html, err := HTML(d)
+	if err != nil {
+		return notify.Message{}, err
+	}
+
+	b, err := json.Marshal(d)
+	if err != nil {
+		return notify.Message{}, err
+	}
+	data := map[string]interface{}{}
+	if err := json.Unmarshal(b, &data); err != nil {
+		return notify.Message{}, err
+	}
+
+	return notify.Message{
+		Kind:  notify.KindDigest,
+		Title: Title(d),
+		Body:  Markdown(d),
+		CarID: d.CarID,
+		Time:  d.GeneratedAt,
+		Data:  data,
+		HTML:  html,
+	}, nil
+}
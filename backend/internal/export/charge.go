@@ -0,0 +1,101 @@
+package export
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"time"
+
+	"teslamate-cyberui/internal/model"
+)
+
+// ChargeHeader 充电记录导出的表头
+var ChargeHeader = []string{
+	"ID", "Start", "End", "Duration (min)", "Location", "Latitude", "Longitude", "Type",
+	"Energy added (kWh)", "Energy used (kWh)", "Efficiency (%)", "Cost",
+	"SOC start (%)", "SOC end (%)", "Ideal range start (km)", "Ideal range end (km)",
+	"Rated range start (km)", "Rated range end (km)", "Outside temp avg (°C)", "Odometer (km)",
+}
+
+// ChargeRow 充电记录导出行，顺序与 ChargeHeader 一致
+func ChargeRow(r *model.ChargeExportRow) []interface{} {
+	var end interface{}
+	if r.EndDate != nil {
+		end = *r.EndDate
+	}
+	var efficiency interface{}
+	if r.Efficiency != nil {
+		efficiency = math.Round(*r.Efficiency*10) / 10
+	}
+	return []interface{}{
+		r.ID, r.StartDate, end, r.DurationMin, r.Location, r.Latitude, r.Longitude, r.ChargeType,
+		r.ChargeEnergyAdded, r.ChargeEnergyUsed, efficiency, r.Cost,
+		r.StartBatteryLevel, r.EndBatteryLevel, r.StartIdealRangeKm, r.EndIdealRangeKm,
+		r.StartRatedRangeKm, r.EndRatedRangeKm, r.OutsideTempAvg, r.Odometer,
+	}
+}
+
+// ChargeSampleHeader 原始充电曲线导出的表头，对应 TeslaMate charges 表的字段
+var ChargeSampleHeader = []string{
+	"Time", "Battery level (%)", "Usable battery level (%)", "Energy added (kWh)",
+	"Charger power (kW)", "Charger voltage (V)", "Charger actual current (A)", "Charger pilot current (A)",
+	"Charger phases", "Ideal range (km)", "Rated range (km)", "Outside temp (°C)",
+	"Battery heater", "Battery heater on", "Battery heater no power", "Not enough power to heat",
+	"Fast charger present", "Fast charger type", "Fast charger brand", "Charge cable",
+}
+
+// ChargeSampleRow 原始充电采样导出行，顺序与 ChargeSampleHeader 一致
+func ChargeSampleRow(s *model.Charge) []interface{} {
+	return []interface{}{
+		s.Date, nullInt16(s.BatteryLevel), nullInt16(s.UsableBatteryLevel), s.ChargeEnergyAdded,
+		int(s.ChargerPower), nullInt16(s.ChargerVoltage), nullInt16(s.ChargerActualCurrent), nullInt16(s.ChargerPilotCurrent),
+		nullInt16(s.ChargerPhases), s.IdealBatteryRangeKm, nullFloat(s.RatedBatteryRangeKm), nullFloat(s.OutsideTemp),
+		nullBool(s.BatteryHeater), nullBool(s.BatteryHeaterOn), nullBool(s.BatteryHeaterNoPower), nullBool(s.NotEnoughPowerToHeat),
+		nullBool(s.FastChargerPresent), nullString(s.FastChargerType), nullString(s.FastChargerBrand), nullString(s.ConnChargeCable),
+	}
+}
+
+// ChargesFileName 充电记录导出文件名，如 charges-1-20250101-20251231.xlsx
+func ChargesFileName(carID int16, startDate, endDate *time.Time, format string) string {
+	name := fmt.Sprintf("charges-%d", carID)
+	if startDate != nil {
+		name += "-" + startDate.In(time.Local).Format("20060102")
+	}
+	if endDate != nil {
+		name += "-" + endDate.In(time.Local).Format("20060102")
+	}
+	return name + "." + format
+}
+
+// ChargeCurveFileName 充电曲线导出文件名，如 charge-42-20250602-2100.csv
+func ChargeCurveFileName(charge *model.ChargeDetail, format string) string {
+	return fmt.Sprintf("charge-%d-%s.%s", charge.ID, charge.StartDate.In(time.Local).Format("20060102-1504"), format)
+}
+
+func nullInt16(v sql.NullInt16) interface{} {
+	if !v.Valid {
+		return nil
+	}
+	return int(v.Int16)
+}
+
+func nullFloat(v sql.NullFloat64) interface{} {
+	if !v.Valid {
+		return nil
+	}
+	return v.Float64
+}
+
+func nullBool(v sql.NullBool) interface{} {
+	if !v.Valid {
+		return nil
+	}
+	return v.Bool
+}
+
+func nullString(v sql.NullString) interface{} {
+	if !v.Valid {
+		return nil
+	}
+	return v.String
+}
@@ -0,0 +1,46 @@
+package export
+
+import (
+	"bufio"
+	"encoding/csv"
+	"strconv"
+)
+
+var csvHeader = []string{
+	"drive_id", "time", "latitude", "longitude", "elevation_m",
+	"speed_kmh", "power_kw", "battery_level", "outside_temp_c", "inside_temp_c",
+}
+
+// writeCSV 每个轨迹点一行，缺失的海拔和温度留空
+func writeCSV(w *bufio.Writer, t *Track) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader); err != nil {
+		return err
+	}
+
+	driveID := strconv.FormatInt(t.Drive.ID, 10)
+	for i := range t.Positions {
+		p := &t.Positions[i]
+		elevation := ""
+		if p.Elevation != nil {
+			elevation = strconv.Itoa(*p.Elevation)
+		}
+		record := []string{
+			driveID,
+			formatTime(p.Date),
+			formatFloat(p.Latitude, -1),
+			formatFloat(p.Longitude, -1),
+			elevation,
+			strconv.Itoa(p.Speed),
+			strconv.Itoa(p.Power),
+			strconv.Itoa(p.BatteryLevel),
+			optionalFloat(p.OutsideTemp),
+			optionalFloat(p.InsideTemp),
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,115 @@
+// Package export 将行程轨迹导出为 GPX / KML / GeoJSON / CSV 文件
+// 除坐标和时间外，还导出速度、功率、海拔、电量和温度，便于导入运动记录平台或 GIS 软件
+package export
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"mime"
+	"strconv"
+	"strings"
+	"time"
+
+	"teslamate-cyberui/internal/model"
+)
+
+// 导出格式
+const (
+	FormatGPX     = "gpx"
+	FormatKML     = "kml"
+	FormatGeoJSON = "geojson"
+	FormatCSV     = "csv"
+)
+
+// creator 写入导出文件的生成者名称
+const creator = "TeslaMate CyberUI"
+
+var contentTypes = map[string]string{
+	FormatGPX:     "application/gpx+xml",
+	FormatKML:     "application/vnd.google-earth.kml+xml",
+	FormatGeoJSON: "application/geo+json",
+	FormatCSV:     "text/csv; charset=utf-8",
+}
+
+// Track 一次行程的轨迹
+type Track struct {
+	Drive     *model.DriveDetail
+	Positions []model.DrivePosition
+}
+
+// Name 轨迹名称，如 "2025-06-02 08:40 Home → Office"
+func (t *Track) Name() string {
+	return fmt.Sprintf("%s %s → %s", t.Drive.StartDate.In(time.Local).Format("2006-01-02 15:04"),
+		t.Drive.StartLocation, t.Drive.EndLocation)
+}
+
+// ValidFormat 判断是否为支持的导出格式
+func ValidFormat(format string) bool {
+	_, ok := contentTypes[format]
+	return ok
+}
+
+// ContentType 导出格式对应的 Content-Type
+func ContentType(format string) string {
+	return contentTypes[format]
+}
+
+// FormatFromAccept 根据 Accept 请求头选择导出格式，没有匹配的格式时返回空字符串
+func FormatFromAccept(accept string) string {
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		switch mediaType {
+		case "application/gpx+xml":
+			return FormatGPX
+		case "application/vnd.google-earth.kml+xml":
+			return FormatKML
+		case "application/geo+json":
+			return FormatGeoJSON
+		case "text/csv":
+			return FormatCSV
+		}
+	}
+	return ""
+}
+
+// FileName 单次行程的导出文件名，如 drive-123-20250602-0840.gpx
+func FileName(drive *model.DriveDetail, format string) string {
+	return fmt.Sprintf("drive-%d-%s.%s", drive.ID, drive.StartDate.In(time.Local).Format("20060102-1504"), format)
+}
+
+// WriteTrack 按指定格式写出轨迹
+func WriteTrack(w io.Writer, format string, t *Track) error {
+	// bufio.Writer 会记住第一次写入错误，只需在 Flush 时检查
+	bw := bufio.NewWriter(w)
+	switch format {
+	case FormatGPX:
+		writeGPX(bw, t)
+	case FormatKML:
+		writeKML(bw, t)
+	case FormatGeoJSON:
+		if err := writeGeoJSON(bw, t); err != nil {
+			return err
+		}
+	case FormatCSV:
+		if err := writeCSV(bw, t); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unsupported export format %q", format)
+	}
+	return bw.Flush()
+}
+
+// formatFloat 格式化数值，prec 为 -1 时使用最短表示
+func formatFloat(v float64, prec int) string {
+	return strconv.FormatFloat(v, 'f', prec, 64)
+}
+
+// formatTime 导出文件中的时间统一使用 UTC 的 RFC 3339 格式
+func formatTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
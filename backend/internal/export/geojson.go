@@ -0,0 +1,102 @@
+package export
+
+import (
+	"bufio"
+	"encoding/json"
+)
+
+// geoJSONFeature 行程轨迹的 LineString 要素
+// 逐点数据放在 coordinateProperties 中，与 togeojson 等工具的约定一致
+type geoJSONFeature struct {
+	Type       string            `json:"type"`
+	Geometry   geoJSONLineString `json:"geometry"`
+	Properties geoJSONProperties `json:"properties"`
+}
+
+type geoJSONLineString struct {
+	Type        string      `json:"type"`
+	Coordinates [][]float64 `json:"coordinates"`
+}
+
+type geoJSONProperties struct {
+	DriveID              int64                       `json:"driveId"`
+	Name                 string                      `json:"name"`
+	StartDate            string                      `json:"startDate"`
+	EndDate              string                      `json:"endDate,omitempty"`
+	StartLocation        string                      `json:"startLocation"`
+	EndLocation          string                      `json:"endLocation"`
+	Distance             float64                     `json:"distance"`
+	DurationMin          int                         `json:"durationMin"`
+	CoordinateProperties geoJSONCoordinateProperties `json:"coordinateProperties"`
+}
+
+type geoJSONCoordinateProperties struct {
+	Times        []string   `json:"times"`
+	Speed        []int      `json:"speed"`
+	Power        []int      `json:"power"`
+	BatteryLevel []int      `json:"batteryLevel"`
+	OutsideTemp  []*float64 `json:"outsideTemp"`
+	InsideTemp   []*float64 `json:"insideTemp"`
+}
+
+// writeGeoJSON 写出包含一条轨迹的 FeatureCollection，坐标为 [经度, 纬度, 海拔]
+// 同一条线的坐标维度需要一致，只要有点缺少海拔就全部省略海拔
+func writeGeoJSON(w *bufio.Writer, t *Track) error {
+	n := len(t.Positions)
+	withElevation := n > 0
+	for _, p := range t.Positions {
+		if p.Elevation == nil {
+			withElevation = false
+			break
+		}
+	}
+	props := geoJSONProperties{
+		DriveID:       t.Drive.ID,
+		Name:          t.Name(),
+		StartDate:     formatTime(t.Drive.StartDate),
+		StartLocation: t.Drive.StartLocation,
+		EndLocation:   t.Drive.EndLocation,
+		Distance:      t.Drive.Distance,
+		DurationMin:   t.Drive.DurationMin,
+		CoordinateProperties: geoJSONCoordinateProperties{
+			Times:        make([]string, 0, n),
+			Speed:        make([]int, 0, n),
+			Power:        make([]int, 0, n),
+			BatteryLevel: make([]int, 0, n),
+			OutsideTemp:  make([]*float64, 0, n),
+			InsideTemp:   make([]*float64, 0, n),
+		},
+	}
+	if t.Drive.EndDate != nil {
+		props.EndDate = formatTime(*t.Drive.EndDate)
+	}
+
+	coords := make([][]float64, 0, n)
+	cp := &props.CoordinateProperties
+	for _, p := range t.Positions {
+		coord := []float64{p.Longitude, p.Latitude}
+		if withElevation {
+			coord = append(coord, float64(*p.Elevation))
+		}
+		coords = append(coords, coord)
+		cp.Times = append(cp.Times, formatTime(p.Date))
+		cp.Speed = append(cp.Speed, p.Speed)
+		cp.Power = append(cp.Power, p.Power)
+		cp.BatteryLevel = append(cp.BatteryLevel, p.BatteryLevel)
+		cp.OutsideTemp = append(cp.OutsideTemp, p.OutsideTemp)
+		cp.InsideTemp = append(cp.InsideTemp, p.InsideTemp)
+	}
+
+	collection := struct {
+		Type     string           `json:"type"`
+		Features []geoJSONFeature `json:"features"`
+	}{
+		Type: "FeatureCollection",
+		Features: []geoJSONFeature{{
+			Type:       "Feature",
+			Geometry:   geoJSONLineString{Type: "LineString", Coordinates: coords},
+			Properties: props,
+		}},
+	}
+	return json.NewEncoder(w).Encode(collection)
+}
@@ -0,0 +1,52 @@
+package export
+
+import (
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"strings"
+)
+
+// gpxExtensionNS CyberUI 自定义扩展的命名空间，存放 TrackPointExtension 中没有的字段
+const gpxExtensionNS = "https://github.com/DeaglePC/TeslamateCyberUI/gpx/1"
+
+// escapeXML 转义 XML 文本
+func escapeXML(s string) string {
+	var b strings.Builder
+	_ = xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+// writeGPX 写出 GPX 1.1 轨迹
+// 车外温度和速度 (m/s) 写入 Garmin TrackPointExtension v2，Strava 等平台可以识别；
+// 功率、电量和车内温度写入自定义扩展
+func writeGPX(w *bufio.Writer, t *Track) {
+	name := escapeXML(t.Name())
+
+	w.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n")
+	fmt.Fprintf(w, `<gpx version="1.1" creator="%s" xmlns="http://www.topografix.com/GPX/1/1"`+
+		` xmlns:gpxtpx="http://www.garmin.com/xmlschemas/TrackPointExtension/v2"`+
+		` xmlns:cyberui="%s">`+"\n", creator, gpxExtensionNS)
+	fmt.Fprintf(w, "<metadata><name>%s</name><time>%s</time></metadata>\n", name, formatTime(t.Drive.StartDate))
+	fmt.Fprintf(w, "<trk><name>%s</name><type>driving</type><trkseg>\n", name)
+
+	for _, p := range t.Positions {
+		fmt.Fprintf(w, `<trkpt lat="%s" lon="%s">`, formatFloat(p.Latitude, -1), formatFloat(p.Longitude, -1))
+		if p.Elevation != nil {
+			fmt.Fprintf(w, "<ele>%d</ele>", *p.Elevation)
+		}
+		fmt.Fprintf(w, "<time>%s</time><extensions><gpxtpx:TrackPointExtension>", formatTime(p.Date))
+		if p.OutsideTemp != nil {
+			fmt.Fprintf(w, "<gpxtpx:atemp>%s</gpxtpx:atemp>", formatFloat(*p.OutsideTemp, 1))
+		}
+		fmt.Fprintf(w, "<gpxtpx:speed>%s</gpxtpx:speed></gpxtpx:TrackPointExtension>", formatFloat(float64(p.Speed)/3.6, 2))
+		fmt.Fprintf(w, "<cyberui:speed_kmh>%d</cyberui:speed_kmh><cyberui:power_kw>%d</cyberui:power_kw><cyberui:battery_level>%d</cyberui:battery_level>",
+			p.Speed, p.Power, p.BatteryLevel)
+		if p.InsideTemp != nil {
+			fmt.Fprintf(w, "<cyberui:inside_temp>%s</cyberui:inside_temp>", formatFloat(*p.InsideTemp, 1))
+		}
+		w.WriteString("</extensions></trkpt>\n")
+	}
+
+	w.WriteString("</trkseg></trk>\n</gpx>\n")
+}
@@ -0,0 +1,72 @@
+package export
+
+import (
+	"bufio"
+	"fmt"
+	"strconv"
+
+	"teslamate-cyberui/internal/model"
+)
+
+// kmlArrayFields gx:Track 的逐点扩展数据
+var kmlArrayFields = []struct {
+	name        string
+	typ         string
+	displayName string
+	value       func(p *model.DrivePosition) string
+}{
+	{"speed", "int", "Speed (km/h)", func(p *model.DrivePosition) string { return strconv.Itoa(p.Speed) }},
+	{"power", "int", "Power (kW)", func(p *model.DrivePosition) string { return strconv.Itoa(p.Power) }},
+	{"battery_level", "int", "Battery level (%)", func(p *model.DrivePosition) string { return strconv.Itoa(p.BatteryLevel) }},
+	{"outside_temp", "float", "Outside temperature (°C)", func(p *model.DrivePosition) string { return optionalFloat(p.OutsideTemp) }},
+	{"inside_temp", "float", "Inside temperature (°C)", func(p *model.DrivePosition) string { return optionalFloat(p.InsideTemp) }},
+}
+
+func optionalFloat(v *float64) string {
+	if v == nil {
+		return ""
+	}
+	return formatFloat(*v, 1)
+}
+
+// writeKML 写出带时间戳的 gx:Track，逐点数据写入 ExtendedData 的 gx:SimpleArrayData
+// 没有海拔数据的点海拔记为 0，轨迹贴地显示
+func writeKML(w *bufio.Writer, t *Track) {
+	name := escapeXML(t.Name())
+
+	w.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n")
+	w.WriteString(`<kml xmlns="http://www.opengis.net/kml/2.2" xmlns:gx="http://www.google.com/kml/ext/2.2">` + "\n")
+	fmt.Fprintf(w, "<Document><name>%s</name>\n", name)
+	w.WriteString(`<Schema id="drive">`)
+	for _, f := range kmlArrayFields {
+		fmt.Fprintf(w, `<gx:SimpleArrayField name="%s" type="%s"><displayName>%s</displayName></gx:SimpleArrayField>`,
+			f.name, f.typ, escapeXML(f.displayName))
+	}
+	w.WriteString("</Schema>\n")
+	w.WriteString(`<Style id="track"><LineStyle><color>ff3c14dc</color><width>4</width></LineStyle></Style>` + "\n")
+
+	fmt.Fprintf(w, "<Placemark><name>%s</name><styleUrl>#track</styleUrl>\n", name)
+	fmt.Fprintf(w, "<ExtendedData><Data name=\"distance\"><value>%s</value></Data><Data name=\"duration_min\"><value>%d</value></Data></ExtendedData>\n",
+		formatFloat(t.Drive.Distance, 2), t.Drive.DurationMin)
+	w.WriteString("<gx:Track>\n")
+	for i := range t.Positions {
+		fmt.Fprintf(w, "<when>%s</when>\n", formatTime(t.Positions[i].Date))
+	}
+	for i := range t.Positions {
+		p := &t.Positions[i]
+		elevation := 0
+		if p.Elevation != nil {
+			elevation = *p.Elevation
+		}
+		fmt.Fprintf(w, "<gx:coord>%s %s %d</gx:coord>\n", formatFloat(p.Longitude, -1), formatFloat(p.Latitude, -1), elevation)
+	}
+	w.WriteString(`<ExtendedData><SchemaData schemaUrl="#drive">` + "\n")
+	for _, f := range kmlArrayFields {
+		fmt.Fprintf(w, `<gx:SimpleArrayData name="%s">`, f.name)
+		for i := range t.Positions {
+			fmt.Fprintf(w, "<gx:value>%s</gx:value>", f.value(&t.Positions[i]))
+		}
+		w.WriteString("</gx:SimpleArrayData>\n")
+	}
+	w.WriteString("</SchemaData></ExtendedData>\n</gx:Track>\n</Placemark>\n</Document>\n</kml>\n")
+}
@@ -0,0 +1,112 @@
+package export
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FormatXLSX Excel 表格格式，仅用于表格导出
+const FormatXLSX = "xlsx"
+
+var tableContentTypes = map[string]string{
+	FormatCSV:  "text/csv; charset=utf-8",
+	FormatXLSX: "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+}
+
+// ValidTableFormat 判断是否为支持的表格导出格式
+func ValidTableFormat(format string) bool {
+	_, ok := tableContentTypes[format]
+	return ok
+}
+
+// TableContentType 表格导出格式对应的 Content-Type
+func TableContentType(format string) string {
+	return tableContentTypes[format]
+}
+
+// TableWriter 逐行写出表格，写完后需要调用 Close
+// 单元格支持 nil（空）、string、int、int64、float64、*float64、bool 和 time.Time
+type TableWriter interface {
+	WriteRow(cells []interface{}) error
+	Close() error
+}
+
+// NewTableWriter 创建表格写入器并写出表头
+func NewTableWriter(w io.Writer, format, sheetName string, header []string) (TableWriter, error) {
+	switch format {
+	case FormatCSV:
+		return newCSVTable(w, header)
+	case FormatXLSX:
+		return newXLSXTable(w, sheetName, header)
+	}
+	return nil, fmt.Errorf("unsupported table format %q", format)
+}
+
+// csvTable CSV 表格，时间为服务器本地时区
+type csvTable struct {
+	w      *csv.Writer
+	record []string
+}
+
+func newCSVTable(w io.Writer, header []string) (*csvTable, error) {
+	// 写入 UTF-8 BOM，便于 Excel 正确识别非 ASCII 地址
+	if _, err := w.Write([]byte("\xEF\xBB\xBF")); err != nil {
+		return nil, err
+	}
+	t := &csvTable{w: csv.NewWriter(w)}
+	if err := t.w.Write(header); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+func (t *csvTable) WriteRow(cells []interface{}) error {
+	t.record = t.record[:0]
+	for _, cell := range cells {
+		t.record = append(t.record, csvCell(cell))
+	}
+	return t.w.Write(t.record)
+}
+
+func (t *csvTable) Close() error {
+	t.w.Flush()
+	return t.w.Error()
+}
+
+// EscapeCSVFormula 以 =、+、-、@、制表符或回车开头的文本会被 Excel 当作公式执行，前面加上 ' 按文本显示
+func EscapeCSVFormula(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+// csvCell 格式化单元格，文本经过 EscapeCSVFormula 处理，数值不受影响
+func csvCell(cell interface{}) string {
+	switch v := cell.(type) {
+	case nil:
+		return ""
+	case string:
+		return EscapeCSVFormula(v)
+	case int:
+		return strconv.Itoa(v)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return formatFloat(v, -1)
+	case *float64:
+		if v == nil {
+			return ""
+		}
+		return formatFloat(*v, -1)
+	case bool:
+		return strconv.FormatBool(v)
+	case time.Time:
+		return v.In(time.Local).Format("2006-01-02 15:04:05")
+	}
+	return fmt.Sprint(cell)
+}
@@ -0,0 +1,53 @@
+package export
+
+import (
+	"bytes"
+	"encoding/csv"
+	"strings"
+	"testing"
+)
+
+func TestEscapeCSVFormula(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"", ""},
+		{"Home", "Home"},
+		{"=HYPERLINK(\"http://x\")", "'=HYPERLINK(\"http://x\")"},
+		{"+1", "'+1"},
+		{"-2+3", "'-2+3"},
+		{"@SUM(A1)", "'@SUM(A1)"},
+		{"\tcmd", "'\tcmd"},
+		{"\rcmd", "'\rcmd"},
+		{"a=b", "a=b"},
+	}
+	for _, tt := range tests {
+		if got := EscapeCSVFormula(tt.in); got != tt.want {
+			t.Errorf("EscapeCSVFormula(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestCSVTableEscapesTextOnly(t *testing.T) {
+	var buf bytes.Buffer
+	tw, err := NewTableWriter(&buf, FormatCSV, "charges", []string{"location", "cost"})
+	if err != nil {
+		t.Fatalf("NewTableWriter: %v", err)
+	}
+	if err := tw.WriteRow([]interface{}{"=cmd|' /C calc'!A0", -12.5}); err != nil {
+		t.Fatalf("WriteRow: %v", err)
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	out := buf.String()
+	if !strings.HasPrefix(out, "\xEF\xBB\xBF") {
+		t.Error("missing UTF-8 BOM")
+	}
+	records, err := csv.NewReader(strings.NewReader(strings.TrimPrefix(out, "\xEF\xBB\xBF"))).ReadAll()
+	if err != nil {
+		t.Fatalf("parse csv: %v", err)
+	}
+	if got := records[1]; got[0] != "'=cmd|' /C calc'!A0" || got[1] != "-12.5" {
+		t.Errorf("row = %q", got)
+	}
+}
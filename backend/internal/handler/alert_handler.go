@@ -0,0 +1,228 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"teslamate-cyberui/internal/alert"
+	"teslamate-cyberui/internal/logger"
+	"teslamate-cyberui/internal/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AlertRuleRequest 创建/更新告警规则请求
+type AlertRuleRequest struct {
+	Name            string                 `json:"name"`
+	CarID           *int16                 `json:"carId"`
+	Source          string                 `json:"source"`
+	Conditions      []model.AlertCondition `json:"conditions"`
+	TimeStart       *string                `json:"timeStart"`
+	TimeEnd         *string                `json:"timeEnd"`
+	DebounceSeconds int                    `json:"debounceSeconds"`
+	CooldownSeconds int                    `json:"cooldownSeconds"`
+	ChannelIDs      []int64                `json:"channelIds"`
+	Enabled         *bool                  `json:"enabled"`
+}
+
+// toRule 转换为规则模型，enabled 未指定时默认启用
+func (req *AlertRuleRequest) toRule() *model.AlertRule {
+	rule := &model.AlertRule{
+		Name:            req.Name,
+		CarID:           req.CarID,
+		Source:          req.Source,
+		Conditions:      req.Conditions,
+		TimeStart:       req.TimeStart,
+		TimeEnd:         req.TimeEnd,
+		DebounceSeconds: req.DebounceSeconds,
+		CooldownSeconds: req.CooldownSeconds,
+		ChannelIDs:      req.ChannelIDs,
+		Enabled:         true,
+	}
+	if req.Enabled != nil {
+		rule.Enabled = *req.Enabled
+	}
+	return rule
+}
+
+// reloadAlerts 规则变更后通知告警引擎
+func (h *Handler) reloadAlerts() {
+	if h.alerts != nil {
+		h.alerts.Reload()
+	}
+}
+
+// GetAlertRules 获取所有告警规则
+func (h *Handler) GetAlertRules(c *gin.Context) {
+	rules, err := h.repo.Alert.ListRules(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse(500, "Failed to get alert rules"))
+		return
+	}
+
+	c.JSON(http.StatusOK, SuccessResponse(rules))
+}
+
+// GetAlertRule 获取单条告警规则
+func (h *Handler) GetAlertRule(c *gin.Context) {
+	ruleID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse(400, "Invalid rule ID"))
+		return
+	}
+
+	rule, err := h.repo.Alert.GetRule(c.Request.Context(), ruleID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse(500, "Failed to get alert rule"))
+		return
+	}
+	if rule == nil {
+		c.JSON(http.StatusNotFound, ErrorResponse(404, "Alert rule not found"))
+		return
+	}
+
+	c.JSON(http.StatusOK, SuccessResponse(rule))
+}
+
+// CreateAlertRule 创建告警规则
+func (h *Handler) CreateAlertRule(c *gin.Context) {
+	var req AlertRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse(400, err.Error()))
+		return
+	}
+
+	rule := req.toRule()
+	if err := alert.Validate(rule); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse(400, err.Error()))
+		return
+	}
+
+	if err := h.repo.Alert.CreateRule(c.Request.Context(), rule); err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse(500, "Failed to create alert rule"))
+		return
+	}
+	h.reloadAlerts()
+
+	c.JSON(http.StatusOK, SuccessResponse(rule))
+}
+
+// UpdateAlertRule 更新告警规则
+func (h *Handler) UpdateAlertRule(c *gin.Context) {
+	ruleID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse(400, "Invalid rule ID"))
+		return
+	}
+
+	var req AlertRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse(400, err.Error()))
+		return
+	}
+
+	rule := req.toRule()
+	rule.ID = ruleID
+	if err := alert.Validate(rule); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse(400, err.Error()))
+		return
+	}
+
+	found, err := h.repo.Alert.UpdateRule(c.Request.Context(), rule)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse(500, "Failed to update alert rule"))
+		return
+	}
+	if !found {
+		c.JSON(http.StatusNotFound, ErrorResponse(404, "Alert rule not found"))
+		return
+	}
+	h.reloadAlerts()
+
+	c.JSON(http.StatusOK, SuccessResponse(rule))
+}
+
+// DeleteAlertRule 删除告警规则，已产生的告警历史保留
+func (h *Handler) DeleteAlertRule(c *gin.Context) {
+	ruleID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse(400, "Invalid rule ID"))
+		return
+	}
+
+	found, err := h.repo.Alert.DeleteRule(c.Request.Context(), ruleID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse(500, "Failed to delete alert rule"))
+		return
+	}
+	if !found {
+		c.JSON(http.StatusNotFound, ErrorResponse(404, "Alert rule not found"))
+		return
+	}
+	h.reloadAlerts()
+
+	c.JSON(http.StatusOK, SuccessResponse(nil))
+}
+
+// GetAlertEvents 分页获取告警历史，可按 carId / ruleId 筛选
+func (h *Handler) GetAlertEvents(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("pageSize", "20"))
+
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	var carID *int16
+	if s := c.Query("carId"); s != "" {
+		id, err := strconv.ParseInt(s, 10, 16)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse(400, "Invalid car ID"))
+			return
+		}
+		v := int16(id)
+		carID = &v
+	}
+	var ruleID *int64
+	if s := c.Query("ruleId"); s != "" {
+		id, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse(400, "Invalid rule ID"))
+			return
+		}
+		ruleID = &id
+	}
+
+	result, err := h.repo.Alert.ListEvents(c.Request.Context(), carID, ruleID, page, pageSize)
+	if err != nil {
+		logger.Errorf("Failed to get alert events: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse(500, "Failed to get alert events"))
+		return
+	}
+
+	c.JSON(http.StatusOK, SuccessResponse(result))
+}
+
+// DeleteAlertEvent 删除一条告警历史
+func (h *Handler) DeleteAlertEvent(c *gin.Context) {
+	eventID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse(400, "Invalid event ID"))
+		return
+	}
+
+	found, err := h.repo.Alert.DeleteEvent(c.Request.Context(), eventID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse(500, "Failed to delete alert event"))
+		return
+	}
+	if !found {
+		c.JSON(http.StatusNotFound, ErrorResponse(404, "Alert event not found"))
+		return
+	}
+
+	c.JSON(http.StatusOK, SuccessResponse(nil))
+}
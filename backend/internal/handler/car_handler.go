@@ -80,5 +80,8 @@ func (h *Handler) GetCarStatus(c *gin.Context) {
 		return
 	}
 
+	// 使用 MQTT 实时数据补全和更新数据库快照
+	mergeLiveStatus(status)
+
 	c.JSON(http.StatusOK, SuccessResponse(status))
 }
@@ -0,0 +1,126 @@
+package handler
+
+import (
+	"time"
+
+	"teslamate-cyberui/internal/model"
+	"teslamate-cyberui/internal/mqtt"
+)
+
+// mergeLiveStatus 将 MQTT 缓存中的实时数据覆盖到数据库快照之上
+// 对于数据库中也有的字段，只有 MQTT 数据比数据库记录更新时才会覆盖（否则视为过期）；
+// 仅来自 MQTT 的字段（锁车、哨兵、插枪等）只要存在就会填充。
+// IdealRange 是按用户偏好和长度单位换算后的值，不做覆盖。
+func mergeLiveStatus(status *model.CarStatus) {
+	if status.Sources == nil {
+		status.Sources = make(map[string]model.FieldSource)
+	}
+	o := liveOverlay{status: status}
+
+	o.setString("state", "state", func(v string) { status.State = v })
+	o.setTime("since", "since", func(t time.Time) { status.Since = &t })
+	o.setBool("healthy", "healthy", func(b bool) { status.Healthy = b })
+	o.setInt("battery_level", "batteryLevel", func(i int) { status.BatteryLevel = i })
+	o.setInt("usable_battery_level", "usableBatteryLevel", func(i int) { status.UsableBatteryLevel = i })
+	o.setFloat("est_battery_range_km", "estRange", func(f float64) { status.EstRange = f })
+	o.setFloat("rated_battery_range_km", "ratedRange", func(f float64) { status.RatedRange = f })
+	o.setFloat("odometer", "odometer", func(f float64) { status.Odometer = f })
+	o.setFloat("inside_temp", "insideTemp", func(f float64) { status.InsideTemp = &f })
+	o.setFloat("outside_temp", "outsideTemp", func(f float64) { status.OutsideTemp = &f })
+	o.setBool("is_climate_on", "isClimateOn", func(b bool) { status.IsClimateOn = b })
+	o.setBool("is_preconditioning", "isPreconditioning", func(b bool) { status.IsPreconditioning = b })
+	o.setBool("locked", "locked", func(b bool) { status.Locked = &b })
+	o.setBool("sentry_mode", "sentryMode", func(b bool) { status.SentryMode = &b })
+	o.setBool("plugged_in", "pluggedIn", func(b bool) { status.PluggedIn = &b })
+	o.setInt("heading", "heading", func(i int) { status.Heading = &i })
+	o.setFloat("latitude", "latitude", func(f float64) { status.Latitude = &f })
+	o.setFloat("longitude", "longitude", func(f float64) { status.Longitude = &f })
+	o.setOptionalString("geofence", "geofence", func(v *string) { status.Geofence = v })
+	o.setString("version", "softwareVersion", func(v string) { status.SoftwareVersion = v })
+	o.setTime("scheduled_charging_start_time", "scheduledChargingStartTime", func(t time.Time) {
+		status.ScheduledChargingStartTime = &t
+	})
+}
+
+// liveOverlay 按字段将 MQTT 值写入 CarStatus 并记录来源
+type liveOverlay struct {
+	status *model.CarStatus
+}
+
+// entry 返回可用于覆盖的 MQTT 值；不存在时返回 false。
+// 数据库中也有该字段时只比较两者的数据时间，MQTT 值较旧时以数据库为准；
+// 保留消息的数据时间未知，只在数据库中没有该字段时使用
+func (o liveOverlay) entry(topic, field string) (mqtt.Entry, bool) {
+	e, ok := mqtt.GlobalCache.GetEntry(o.status.CarID, topic)
+	if !ok {
+		return e, false
+	}
+	if src, ok := o.status.Sources[field]; ok && src.Source == model.FieldSourceDB {
+		if e.Retained || e.UpdatedAt.Before(src.UpdatedAt) {
+			return e, false
+		}
+	}
+	return e, true
+}
+
+// apply 记录字段来自 MQTT
+func (o liveOverlay) apply(field string, e mqtt.Entry) {
+	o.status.Sources[field] = model.FieldSource{Source: model.FieldSourceMQTT, UpdatedAt: e.UpdatedAt, Retained: e.Retained}
+}
+
+func (o liveOverlay) setString(topic, field string, set func(string)) {
+	if e, ok := o.entry(topic, field); ok && e.Raw != "" {
+		set(e.Raw)
+		o.apply(field, e)
+	}
+}
+
+// setOptionalString 空字符串表示该值已被清除（例如离开地理围栏）
+func (o liveOverlay) setOptionalString(topic, field string, set func(*string)) {
+	if e, ok := o.entry(topic, field); ok {
+		if e.Raw == "" {
+			set(nil)
+		} else {
+			v := e.Raw
+			set(&v)
+		}
+		o.apply(field, e)
+	}
+}
+
+func (o liveOverlay) setBool(topic, field string, set func(bool)) {
+	if e, ok := o.entry(topic, field); ok {
+		if b, ok := e.Bool(); ok {
+			set(b)
+			o.apply(field, e)
+		}
+	}
+}
+
+func (o liveOverlay) setInt(topic, field string, set func(int)) {
+	if e, ok := o.entry(topic, field); ok {
+		// TeslaMate 部分整数指标可能以小数形式发布
+		if f, ok := e.Float(); ok {
+			set(int(f))
+			o.apply(field, e)
+		}
+	}
+}
+
+func (o liveOverlay) setFloat(topic, field string, set func(float64)) {
+	if e, ok := o.entry(topic, field); ok {
+		if f, ok := e.Float(); ok {
+			set(f)
+			o.apply(field, e)
+		}
+	}
+}
+
+func (o liveOverlay) setTime(topic, field string, set func(time.Time)) {
+	if e, ok := o.entry(topic, field); ok {
+		if t, ok := e.Time(); ok {
+			set(t)
+			o.apply(field, e)
+		}
+	}
+}
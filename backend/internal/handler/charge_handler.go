@@ -4,7 +4,9 @@ import (
 	"net/http"
 	"strconv"
 
+	"teslamate-cyberui/internal/analytics"
 	"teslamate-cyberui/internal/logger"
+	"teslamate-cyberui/internal/model"
 
 	"github.com/gin-gonic/gin"
 )
@@ -23,21 +25,13 @@ func (h *Handler) GetCharges(c *gin.Context) {
 	}
 	carID := int16(carID64)
 
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	pageSize, _ := strconv.Atoi(c.DefaultQuery("pageSize", "20"))
-
-	if page < 1 {
-		page = 1
-	}
-	if pageSize < 1 || pageSize > 100 {
-		pageSize = 20
+	q, err := listQuery(c, model.ChargeSortFields)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse(400, err.Error()))
+		return
 	}
 
-	// 解析时间筛选参数
-	startDate := parseDateTime(c.Query("startDate"), false)
-	endDate := parseDateTime(c.Query("endDate"), true)
-
-	result, err := h.repo.Charge.GetList(c.Request.Context(), carID, page, pageSize, startDate, endDate)
+	result, err := h.repo.Charge.GetList(c.Request.Context(), carID, q)
 	if err != nil {
 		logger.Errorf("Failed to get charges: %v", err)
 		c.JSON(http.StatusInternalServerError, ErrorResponse(500, "Failed to get charges"))
@@ -71,6 +65,8 @@ func (h *Handler) GetChargeDetail(c *gin.Context) {
 }
 
 // GetChargeStats 获取充电统计数据
+// sampling 选择采样方式：raw（全部数据点）、heuristic（默认，电量每 1% 或功率变化 3 kW 保留一个点）
+// 或 lttb（功率、电压、电流、温度分别降采样到 points 个点，默认 500）
 func (h *Handler) GetChargeStats(c *gin.Context) {
 	chargeID, err := strconv.ParseInt(c.Param("id"), 10, 64)
 	if err != nil {
@@ -78,14 +74,25 @@ func (h *Handler) GetChargeStats(c *gin.Context) {
 		return
 	}
 
-	stats, err := h.repo.Charge.GetStats(c.Request.Context(), chargeID)
+	method := c.DefaultQuery("sampling", model.ChargeSamplingHeuristic)
+	if method != model.ChargeSamplingRaw && method != model.ChargeSamplingHeuristic && method != model.ChargeSamplingLTTB {
+		c.JSON(http.StatusBadRequest, ErrorResponse(400, "sampling must be raw, heuristic or lttb"))
+		return
+	}
+	threshold, err := strconv.Atoi(c.DefaultQuery("points", "500"))
+	if err != nil || threshold < 10 || threshold > 10000 {
+		c.JSON(http.StatusBadRequest, ErrorResponse(400, "points must be between 10 and 10000"))
+		return
+	}
+
+	points, err := h.repo.Charge.GetCurve(c.Request.Context(), chargeID)
 	if err != nil {
 		logger.Errorf("Failed to get charge stats: %v", err)
 		c.JSON(http.StatusInternalServerError, ErrorResponse(500, "Failed to get charge stats"))
 		return
 	}
 
-	c.JSON(http.StatusOK, SuccessResponse(stats))
+	c.JSON(http.StatusOK, SuccessResponse(analytics.SampleChargeCurve(chargeID, points, method, threshold)))
 }
 
 // GetChargeStatsSummary 获取充电统计概览
@@ -0,0 +1,119 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"teslamate-cyberui/internal/digest"
+	"teslamate-cyberui/internal/logger"
+	"teslamate-cyberui/internal/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetCarDigest 预览车辆的周期摘要
+// period 为 day / week / month，format 为 json（默认）/ markdown / html
+func (h *Handler) GetCarDigest(c *gin.Context) {
+	idStr := c.Param("id")
+	carID64, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse(400, "Invalid car ID"))
+		return
+	}
+	if carID64 < -32768 || carID64 > 32767 {
+		c.JSON(http.StatusBadRequest, ErrorResponse(400, "Car ID out of valid range"))
+		return
+	}
+	carID := int16(carID64)
+
+	period := c.DefaultQuery("period", model.DigestPeriodWeek)
+	if !digest.ValidPeriod(period) {
+		c.JSON(http.StatusBadRequest, ErrorResponse(400, "period must be day, week or month"))
+		return
+	}
+	format := c.DefaultQuery("format", digest.FormatJSON)
+	if format != digest.FormatJSON && format != digest.FormatMarkdown && format != digest.FormatHTML {
+		c.JSON(http.StatusBadRequest, ErrorResponse(400, "format must be json, markdown or html"))
+		return
+	}
+
+	d, err := digest.NewBuilder(h.repo).Build(c.Request.Context(), carID, period, time.Now())
+	if err != nil {
+		if errors.Is(err, digest.ErrCarNotFound) {
+			c.JSON(http.StatusNotFound, ErrorResponse(404, "Car not found"))
+			return
+		}
+		logger.Errorf("Failed to build digest for car %d: %v", carID, err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse(500, "Failed to build digest"))
+		return
+	}
+
+	switch format {
+	case digest.FormatMarkdown:
+		c.Data(http.StatusOK, "text/markdown; charset=utf-8", []byte(digest.Markdown(d)))
+	case digest.FormatHTML:
+		html, err := digest.HTML(d)
+		if err != nil {
+			logger.Errorf("Failed to render digest for car %d: %v", carID, err)
+			c.JSON(http.StatusInternalServerError, ErrorResponse(500, "Failed to render digest"))
+			return
+		}
+		c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(html))
+	default:
+		c.JSON(http.StatusOK, SuccessResponse(d))
+	}
+}
+
+// DigestScheduleStatus 摘要计划及其下次执行时间
+type DigestScheduleStatus struct {
+	model.DigestSchedule
+	NextRun *time.Time `json:"nextRun,omitempty"`
+}
+
+// GetDigestSchedules 获取摘要推送计划
+func (h *Handler) GetDigestSchedules(c *gin.Context) {
+	schedules, err := digest.LoadSchedules(h.repo.UISetting)
+	if err != nil {
+		logger.Errorf("Failed to load digest schedules: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse(500, "Failed to get digest schedules"))
+		return
+	}
+
+	now := time.Now().In(time.Local)
+	result := make([]DigestScheduleStatus, 0, len(schedules))
+	for _, s := range schedules {
+		status := DigestScheduleStatus{DigestSchedule: s}
+		if cron, err := digest.ParseSchedule(s.Cron); err == nil && s.Enabled {
+			if next := cron.Next(now); !next.IsZero() {
+				status.NextRun = &next
+			}
+		}
+		result = append(result, status)
+	}
+	c.JSON(http.StatusOK, SuccessResponse(result))
+}
+
+// UpdateDigestSchedules 替换全部摘要推送计划
+func (h *Handler) UpdateDigestSchedules(c *gin.Context) {
+	var schedules []model.DigestSchedule
+	if err := c.ShouldBindJSON(&schedules); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse(400, err.Error()))
+		return
+	}
+	if schedules == nil {
+		schedules = []model.DigestSchedule{}
+	}
+
+	if err := digest.ValidateSchedules(schedules); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse(400, err.Error()))
+		return
+	}
+	if err := digest.SaveSchedules(h.repo.UISetting, schedules); err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse(500, "Failed to save digest schedules"))
+		return
+	}
+
+	c.JSON(http.StatusOK, SuccessResponse(schedules))
+}
@@ -4,7 +4,9 @@ import (
 	"net/http"
 	"strconv"
 
+	"teslamate-cyberui/internal/analytics"
 	"teslamate-cyberui/internal/logger"
+	"teslamate-cyberui/internal/model"
 
 	"github.com/gin-gonic/gin"
 )
@@ -23,21 +25,13 @@ func (h *Handler) GetDrives(c *gin.Context) {
 	}
 	carID := int16(carID64)
 
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	pageSize, _ := strconv.Atoi(c.DefaultQuery("pageSize", "20"))
-
-	if page < 1 {
-		page = 1
-	}
-	if pageSize < 1 || pageSize > 100 {
-		pageSize = 20
+	q, err := listQuery(c, model.DriveSortFields)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse(400, err.Error()))
+		return
 	}
 
-	// 解析时间筛选参数
-	startDate := parseDateTime(c.Query("startDate"), false)
-	endDate := parseDateTime(c.Query("endDate"), true)
-
-	result, err := h.repo.Drive.GetList(c.Request.Context(), carID, page, pageSize, startDate, endDate)
+	result, err := h.repo.Drive.GetList(c.Request.Context(), carID, q)
 	if err != nil {
 		logger.Errorf("Failed to get drives: %v", err)
 		c.JSON(http.StatusInternalServerError, ErrorResponse(500, "Failed to get drives"))
@@ -70,6 +64,36 @@ func (h *Handler) GetDriveDetail(c *gin.Context) {
 	c.JSON(http.StatusOK, SuccessResponse(detail))
 }
 
+// GetDriveAnalysis 获取单次行程分析：累计爬升/下降、海拔剖面、能量收支、功率区间和每公里分段
+func (h *Handler) GetDriveAnalysis(c *gin.Context) {
+	driveID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse(400, "Invalid drive ID"))
+		return
+	}
+
+	ctx := c.Request.Context()
+	detail, err := h.repo.Drive.GetDetail(ctx, driveID)
+	if err != nil {
+		logger.Errorf("Failed to get drive detail: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse(500, "Failed to get drive detail"))
+		return
+	}
+	if detail == nil {
+		c.JSON(http.StatusNotFound, ErrorResponse(404, "Drive not found"))
+		return
+	}
+
+	positions, err := h.repo.Drive.GetPositions(ctx, driveID)
+	if err != nil {
+		logger.Errorf("Failed to get drive positions: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse(500, "Failed to get drive positions"))
+		return
+	}
+
+	c.JSON(http.StatusOK, SuccessResponse(analytics.AnalyzeDrive(detail, positions)))
+}
+
 // GetDrivePositions 获取驾驶轨迹
 func (h *Handler) GetDrivePositions(c *gin.Context) {
 	driveID, err := strconv.ParseInt(c.Param("id"), 10, 64)
@@ -0,0 +1,260 @@
+package handler
+
+import (
+	"archive/zip"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"teslamate-cyberui/internal/export"
+	"teslamate-cyberui/internal/logger"
+	"teslamate-cyberui/internal/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+// exportFormat 解析导出格式：优先使用 format 参数，其次根据 Accept 请求头协商，默认 GPX
+func exportFormat(c *gin.Context) (string, bool) {
+	if format := c.Query("format"); format != "" {
+		return format, export.ValidFormat(format)
+	}
+	if format := export.FormatFromAccept(c.GetHeader("Accept")); format != "" {
+		return format, true
+	}
+	return export.FormatGPX, true
+}
+
+// ExportDrive 导出单次行程轨迹（gpx / kml / geojson / csv）
+func (h *Handler) ExportDrive(c *gin.Context) {
+	driveID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse(400, "Invalid drive ID"))
+		return
+	}
+	format, ok := exportFormat(c)
+	if !ok {
+		c.JSON(http.StatusBadRequest, ErrorResponse(400, "format must be gpx, kml, geojson or csv"))
+		return
+	}
+
+	ctx := c.Request.Context()
+	detail, err := h.repo.Drive.GetDetail(ctx, driveID)
+	if err != nil {
+		logger.Errorf("Failed to get drive detail: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse(500, "Failed to get drive detail"))
+		return
+	}
+	if detail == nil {
+		c.JSON(http.StatusNotFound, ErrorResponse(404, "Drive not found"))
+		return
+	}
+
+	positions, err := h.repo.Drive.GetPositions(ctx, driveID)
+	if err != nil {
+		logger.Errorf("Failed to get drive positions: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse(500, "Failed to get drive positions"))
+		return
+	}
+
+	c.Header("Content-Type", export.ContentType(format))
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, export.FileName(detail, format)))
+	c.Status(http.StatusOK)
+	if err := export.WriteTrack(c.Writer, format, &export.Track{Drive: detail, Positions: positions}); err != nil {
+		logger.Errorf("Failed to export drive %d: %v", driveID, err)
+	}
+}
+
+// ExportDrives 将时间范围内的所有行程分别导出后打包为 zip
+// 逐个行程查询并写入响应，不会把整个时间范围的轨迹放在内存中
+func (h *Handler) ExportDrives(c *gin.Context) {
+	idStr := c.Param("id")
+	carID64, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse(400, "Invalid car ID"))
+		return
+	}
+	if carID64 < -32768 || carID64 > 32767 {
+		c.JSON(http.StatusBadRequest, ErrorResponse(400, "Car ID out of valid range"))
+		return
+	}
+	carID := int16(carID64)
+
+	format, ok := exportFormat(c)
+	if !ok {
+		c.JSON(http.StatusBadRequest, ErrorResponse(400, "format must be gpx, kml, geojson or csv"))
+		return
+	}
+
+	startDate := parseDateTime(c.Query("startDate"), false)
+	endDate := parseDateTime(c.Query("endDate"), true)
+
+	ctx := c.Request.Context()
+	driveIDs, err := h.repo.Drive.GetIDs(ctx, carID, startDate, endDate)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse(500, "Failed to get drives"))
+		return
+	}
+	if len(driveIDs) == 0 {
+		c.JSON(http.StatusNotFound, ErrorResponse(404, "No drives in the selected period"))
+		return
+	}
+
+	fileName := fmt.Sprintf("drives-%d", carID)
+	if startDate != nil {
+		fileName += "-" + startDate.In(time.Local).Format("20060102")
+	}
+	if endDate != nil {
+		fileName += "-" + endDate.In(time.Local).Format("20060102")
+	}
+	c.Header("Content-Type", "application/zip")
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s-%s.zip"`, fileName, format))
+	c.Status(http.StatusOK)
+
+	// 响应头已经发出，之后的错误只能记录日志并中断输出
+	zw := zip.NewWriter(c.Writer)
+	for _, driveID := range driveIDs {
+		if ctx.Err() != nil {
+			logger.Warnf("Drive export for car %d cancelled: %v", carID, ctx.Err())
+			return
+		}
+
+		detail, err := h.repo.Drive.GetDetail(ctx, driveID)
+		if err != nil {
+			logger.Errorf("Failed to export drives for car %d: %v", carID, err)
+			return
+		}
+		if detail == nil {
+			continue
+		}
+		positions, err := h.repo.Drive.GetPositions(ctx, driveID)
+		if err != nil {
+			logger.Errorf("Failed to export drives for car %d: %v", carID, err)
+			return
+		}
+
+		w, err := zw.CreateHeader(&zip.FileHeader{
+			Name:     export.FileName(detail, format),
+			Method:   zip.Deflate,
+			Modified: detail.StartDate,
+		})
+		if err != nil {
+			logger.Errorf("Failed to export drives for car %d: %v", carID, err)
+			return
+		}
+		if err := export.WriteTrack(w, format, &export.Track{Drive: detail, Positions: positions}); err != nil {
+			logger.Errorf("Failed to export drive %d: %v", driveID, err)
+			return
+		}
+		c.Writer.Flush()
+	}
+
+	if err := zw.Close(); err != nil {
+		logger.Errorf("Failed to export drives for car %d: %v", carID, err)
+	}
+}
+
+// streamTable 流式写出表格。表格写入器在第一行数据到达时才创建，
+// 因此查询在输出前失败时仍可以返回 JSON 错误；开始输出后的错误只能记录日志并中断输出
+func streamTable(c *gin.Context, format, fileName, sheetName string, header []string,
+	each func(emit func([]interface{}) error) error) error {
+	var tw export.TableWriter
+	begin := func() error {
+		c.Header("Content-Type", export.TableContentType(format))
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, fileName))
+		c.Status(http.StatusOK)
+		var err error
+		tw, err = export.NewTableWriter(c.Writer, format, sheetName, header)
+		return err
+	}
+
+	err := each(func(cells []interface{}) error {
+		if tw == nil {
+			if err := begin(); err != nil {
+				return err
+			}
+		}
+		return tw.WriteRow(cells)
+	})
+	if err != nil {
+		if tw == nil && !c.Writer.Written() {
+			c.JSON(http.StatusInternalServerError, ErrorResponse(500, "Failed to export data"))
+		}
+		return err
+	}
+	if tw == nil {
+		if err := begin(); err != nil {
+			return err
+		}
+	}
+	return tw.Close()
+}
+
+// ExportCharges 导出充电记录为 CSV 或 XLSX，按开始时间升序，逐行从数据库读取并写出
+func (h *Handler) ExportCharges(c *gin.Context) {
+	idStr := c.Param("id")
+	carID64, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse(400, "Invalid car ID"))
+		return
+	}
+	if carID64 < -32768 || carID64 > 32767 {
+		c.JSON(http.StatusBadRequest, ErrorResponse(400, "Car ID out of valid range"))
+		return
+	}
+	carID := int16(carID64)
+
+	format := c.DefaultQuery("format", export.FormatCSV)
+	if !export.ValidTableFormat(format) {
+		c.JSON(http.StatusBadRequest, ErrorResponse(400, "format must be csv or xlsx"))
+		return
+	}
+
+	startDate := parseDateTime(c.Query("startDate"), false)
+	endDate := parseDateTime(c.Query("endDate"), true)
+
+	fileName := export.ChargesFileName(carID, startDate, endDate, format)
+	err = streamTable(c, format, fileName, "Charges", export.ChargeHeader, func(emit func([]interface{}) error) error {
+		return h.repo.Charge.EachExportRow(c.Request.Context(), carID, startDate, endDate, func(row *model.ChargeExportRow) error {
+			return emit(export.ChargeRow(row))
+		})
+	})
+	if err != nil {
+		logger.Errorf("Failed to export charges for car %d: %v", carID, err)
+	}
+}
+
+// ExportChargeCurve 导出单次充电的原始采样曲线（charges 表全部数据点，不做采样精简）
+func (h *Handler) ExportChargeCurve(c *gin.Context) {
+	chargeID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse(400, "Invalid charge ID"))
+		return
+	}
+
+	format := c.DefaultQuery("format", export.FormatCSV)
+	if !export.ValidTableFormat(format) {
+		c.JSON(http.StatusBadRequest, ErrorResponse(400, "format must be csv or xlsx"))
+		return
+	}
+
+	detail, err := h.repo.Charge.GetDetail(c.Request.Context(), chargeID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse(500, "Failed to get charge detail"))
+		return
+	}
+	if detail == nil {
+		c.JSON(http.StatusNotFound, ErrorResponse(404, "Charge not found"))
+		return
+	}
+
+	fileName := export.ChargeCurveFileName(detail, format)
+	err = streamTable(c, format, fileName, "Charge curve", export.ChargeSampleHeader, func(emit func([]interface{}) error) error {
+		return h.repo.Charge.EachSample(c.Request.Context(), chargeID, func(sample *model.Charge) error {
+			return emit(export.ChargeSampleRow(sample))
+		})
+	})
+	if err != nil {
+		logger.Errorf("Failed to export curve of charge %d: %v", chargeID, err)
+	}
+}
@@ -0,0 +1,187 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"teslamate-cyberui/internal/logger"
+	"teslamate-cyberui/internal/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+// parseGeofenceCarID 解析可选的 carId 查询参数，用于只统计某辆车的到访和充电
+func parseGeofenceCarID(c *gin.Context) (*int16, bool) {
+	idStr := c.Query("carId")
+	if idStr == "" {
+		return nil, true
+	}
+	carID64, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil || carID64 < -32768 || carID64 > 32767 {
+		c.JSON(http.StatusBadRequest, ErrorResponse(400, "Invalid car ID"))
+		return nil, false
+	}
+	carID := int16(carID64)
+	return &carID, true
+}
+
+// validateGeofence 校验并规范化围栏参数，计费方式为空时默认按 kWh 计费
+func validateGeofence(input *model.GeofenceInput) error {
+	input.Name = strings.TrimSpace(input.Name)
+	if input.Name == "" {
+		return errors.New("name is required")
+	}
+	if input.Latitude < -90 || input.Latitude > 90 {
+		return errors.New("latitude must be between -90 and 90")
+	}
+	if input.Longitude < -180 || input.Longitude > 180 {
+		return errors.New("longitude must be between -180 and 180")
+	}
+	if input.Radius < 1 || input.Radius > 5000 {
+		return errors.New("radius must be between 1 and 5000 meters")
+	}
+	if input.BillingType == "" {
+		input.BillingType = model.BillingTypePerKWh
+	}
+	if input.BillingType != model.BillingTypePerKWh && input.BillingType != model.BillingTypePerMinute {
+		return errors.New("billingType must be per_kwh or per_minute")
+	}
+	if input.CostPerUnit != nil && *input.CostPerUnit < 0 {
+		return errors.New("costPerUnit must not be negative")
+	}
+	if input.SessionFee != nil && *input.SessionFee < 0 {
+		return errors.New("sessionFee must not be negative")
+	}
+	return nil
+}
+
+// GetGeofences 获取全部地理围栏及到访、充电统计
+func (h *Handler) GetGeofences(c *gin.Context) {
+	carID, ok := parseGeofenceCarID(c)
+	if !ok {
+		return
+	}
+
+	geofences, err := h.repo.Geofence.List(c.Request.Context(), carID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse(500, "Failed to get geofences"))
+		return
+	}
+
+	c.JSON(http.StatusOK, SuccessResponse(geofences))
+}
+
+// GetGeofence 获取单个地理围栏
+func (h *Handler) GetGeofence(c *gin.Context) {
+	geofenceID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse(400, "Invalid geofence ID"))
+		return
+	}
+	carID, ok := parseGeofenceCarID(c)
+	if !ok {
+		return
+	}
+
+	geofence, err := h.repo.Geofence.Get(c.Request.Context(), geofenceID, carID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse(500, "Failed to get geofence"))
+		return
+	}
+	if geofence == nil {
+		c.JSON(http.StatusNotFound, ErrorResponse(404, "Geofence not found"))
+		return
+	}
+
+	c.JSON(http.StatusOK, SuccessResponse(geofence))
+}
+
+// CreateGeofence 在 TeslaMate 中创建地理围栏（需开启写入模式）
+func (h *Handler) CreateGeofence(c *gin.Context) {
+	var input model.GeofenceInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse(400, err.Error()))
+		return
+	}
+	if err := validateGeofence(&input); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse(400, err.Error()))
+		return
+	}
+
+	id, err := h.repo.Geofence.Create(c.Request.Context(), &input)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse(500, "Failed to create geofence"))
+		return
+	}
+	logger.Infof("Geofence %d (%s) created", id, input.Name)
+
+	geofence, err := h.repo.Geofence.Get(c.Request.Context(), id, nil)
+	if err != nil || geofence == nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse(500, "Failed to get geofence"))
+		return
+	}
+
+	c.JSON(http.StatusOK, SuccessResponse(geofence))
+}
+
+// UpdateGeofence 更新 TeslaMate 中的地理围栏（需开启写入模式）
+func (h *Handler) UpdateGeofence(c *gin.Context) {
+	geofenceID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse(400, "Invalid geofence ID"))
+		return
+	}
+
+	var input model.GeofenceInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse(400, err.Error()))
+		return
+	}
+	if err := validateGeofence(&input); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse(400, err.Error()))
+		return
+	}
+
+	found, err := h.repo.Geofence.Update(c.Request.Context(), geofenceID, &input)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse(500, "Failed to update geofence"))
+		return
+	}
+	if !found {
+		c.JSON(http.StatusNotFound, ErrorResponse(404, "Geofence not found"))
+		return
+	}
+	logger.Infof("Geofence %d (%s) updated", geofenceID, input.Name)
+
+	geofence, err := h.repo.Geofence.Get(c.Request.Context(), geofenceID, nil)
+	if err != nil || geofence == nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse(500, "Failed to get geofence"))
+		return
+	}
+
+	c.JSON(http.StatusOK, SuccessResponse(geofence))
+}
+
+// DeleteGeofence 删除 TeslaMate 中的地理围栏（需开启写入模式），关联的行程和充电不再指向该围栏
+func (h *Handler) DeleteGeofence(c *gin.Context) {
+	geofenceID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse(400, "Invalid geofence ID"))
+		return
+	}
+
+	found, err := h.repo.Geofence.Delete(c.Request.Context(), geofenceID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse(500, "Failed to delete geofence"))
+		return
+	}
+	if !found {
+		c.JSON(http.StatusNotFound, ErrorResponse(404, "Geofence not found"))
+		return
+	}
+	logger.Infof("Geofence %d deleted", geofenceID)
+
+	c.JSON(http.StatusOK, SuccessResponse(nil))
+}
@@ -1,19 +1,29 @@
 package handler
 
 import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 
+	"teslamate-cyberui/internal/alert"
+	"teslamate-cyberui/internal/model"
 	"teslamate-cyberui/internal/repository"
+
+	"github.com/gin-gonic/gin"
 )
 
 // Handler 处理器集合
 type Handler struct {
-	repo *repository.Repository
+	repo   *repository.Repository
+	alerts *alert.Engine
 }
 
-// NewHandler 创建处理器
-func NewHandler(repo *repository.Repository) *Handler {
-	return &Handler{repo: repo}
+// NewHandler 创建处理器，alerts 在 Mock 模式下为 nil
+func NewHandler(repo *repository.Repository, alerts *alert.Engine) *Handler {
+	return &Handler{repo: repo, alerts: alerts}
 }
 
 // Response 通用响应
@@ -90,3 +100,46 @@ func beijingLocation() *time.Location {
 	}
 	return location
 }
+
+// listQuery 解析列表查询参数：page / pageSize 页码分页；cursor 为上一页返回的 nextCursor，传入时忽略 page；
+// sort 排序字段（sortFields 之一，默认 start_date）；order 排序方向 asc / desc（默认 desc），
+// 使用游标时需与生成游标时一致；withTotal=false 时跳过总数统计；startDate / endDate 时间筛选
+func listQuery(c *gin.Context, sortFields []string) (model.ListQuery, error) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("pageSize", "20"))
+
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	q := model.ListQuery{
+		Page:      page,
+		PageSize:  pageSize,
+		SortBy:    c.DefaultQuery("sort", model.SortByStartDate),
+		SortOrder: c.DefaultQuery("order", model.SortDesc),
+		WithTotal: c.Query("withTotal") != "false",
+		StartDate: parseDateTime(c.Query("startDate"), false),
+		EndDate:   parseDateTime(c.Query("endDate"), true),
+	}
+
+	if !slices.Contains(sortFields, q.SortBy) {
+		return q, fmt.Errorf("sort must be one of %s", strings.Join(sortFields, ", "))
+	}
+	if q.SortOrder != model.SortAsc && q.SortOrder != model.SortDesc {
+		return q, errors.New("order must be asc or desc")
+	}
+	if v := c.Query("cursor"); v != "" {
+		cursor, err := model.DecodeListCursor(v)
+		if err != nil {
+			return q, err
+		}
+		if cursor.SortBy != q.SortBy || cursor.SortOrder != q.SortOrder {
+			return q, model.ErrInvalidCursor
+		}
+		q.Cursor = cursor
+	}
+	return q, nil
+}
@@ -0,0 +1,163 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"teslamate-cyberui/internal/logger"
+	"teslamate-cyberui/internal/mqtt"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 每个 SSE 客户端的事件缓冲区大小，超出后丢弃并在下次发送时补发快照
+const liveEventBuffer = 64
+
+// SSE 心跳间隔，防止反向代理因空闲断开连接
+const liveHeartbeatInterval = 15 * time.Second
+
+// LiveSnapshot 实时状态快照事件
+type LiveSnapshot struct {
+	CarID int16                  `json:"carId"`
+	Time  time.Time              `json:"time"`
+	Data  map[string]interface{} `json:"data"`
+}
+
+// LiveUpdate 实时状态变更事件
+type LiveUpdate struct {
+	CarID int16          `json:"carId"`
+	Topic string         `json:"topic"`
+	Type  mqtt.ValueType `json:"type"`
+	Value interface{}    `json:"value"`
+	Time  time.Time      `json:"time"`
+}
+
+// StreamCarLive 通过 Server-Sent Events 推送车辆实时状态
+// 连接建立时先推送一次完整快照（snapshot），之后每次 MQTT 数据变化推送 update 事件
+func (h *Handler) StreamCarLive(c *gin.Context) {
+	idStr := c.Param("id")
+	carID64, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse(400, "Invalid car ID"))
+		return
+	}
+	if carID64 < -32768 || carID64 > 32767 {
+		c.JSON(http.StatusBadRequest, ErrorResponse(400, "Car ID out of valid range"))
+		return
+	}
+	carID := int16(carID64)
+
+	car, err := h.repo.Car.GetByID(c.Request.Context(), carID)
+	if err != nil {
+		logger.Errorf("Failed to get car %d for live stream: %v", carID, err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse(500, "Failed to get car"))
+		return
+	}
+	if car == nil {
+		c.JSON(http.StatusNotFound, ErrorResponse(404, "Car not found"))
+		return
+	}
+
+	// 先订阅再取快照，确保快照之后的变更不会丢失
+	sub := mqtt.GlobalCache.Subscribe(carID, liveEventBuffer)
+	defer mqtt.GlobalCache.Unsubscribe(sub)
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	// 关闭 Nginx 的代理缓冲，否则事件会被攒批发送
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	c.SSEvent("snapshot", buildLiveSnapshot(carID))
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(liveHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	ctx := c.Request.Context()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-heartbeat.C:
+			c.SSEvent("ping", gin.H{"time": time.Now().UTC()})
+			c.Writer.Flush()
+		case u, ok := <-sub.Updates():
+			if !ok {
+				return
+			}
+			// 消费过慢导致丢失事件时，补发一次完整快照让客户端重新对齐
+			if sub.Lagged() {
+				c.SSEvent("snapshot", buildLiveSnapshot(carID))
+			}
+			c.SSEvent("update", LiveUpdate{
+				CarID: u.CarID,
+				Topic: u.Topic,
+				Type:  u.Type,
+				Value: u.Value,
+				Time:  u.UpdatedAt,
+			})
+			c.Writer.Flush()
+		}
+	}
+}
+
+// buildLiveSnapshot 从 MQTT 缓存构建车辆完整快照
+func buildLiveSnapshot(carID int16) LiveSnapshot {
+	entries := mqtt.GlobalCache.GetEntriesForCar(carID)
+	data := make(map[string]interface{}, len(entries))
+	for topic, e := range entries {
+		data[topic] = e.Value
+	}
+	return LiveSnapshot{
+		CarID: carID,
+		Time:  time.Now().UTC(),
+		Data:  data,
+	}
+}
+
+// LiveHistory 单个 MQTT 指标的近期历史
+type LiveHistory struct {
+	CarID    int16          `json:"carId"`
+	Metric   string         `json:"metric"`
+	Type     mqtt.ValueType `json:"type,omitempty"`
+	Capacity int            `json:"capacity"`
+	Points   []mqtt.Sample  `json:"points"`
+}
+
+// GetCarLiveHistory 获取车辆某个 MQTT 指标在内存中保留的近期历史，用于绘制迷你趋势图
+// 历史只保存在进程内存中，服务重启后清空
+func (h *Handler) GetCarLiveHistory(c *gin.Context) {
+	idStr := c.Param("id")
+	carID64, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse(400, "Invalid car ID"))
+		return
+	}
+	if carID64 < -32768 || carID64 > 32767 {
+		c.JSON(http.StatusBadRequest, ErrorResponse(400, "Car ID out of valid range"))
+		return
+	}
+	carID := int16(carID64)
+
+	metric := c.Query("metric")
+	if metric == "" {
+		c.JSON(http.StatusBadRequest, ErrorResponse(400, "metric is required"))
+		return
+	}
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "0"))
+
+	result := LiveHistory{
+		CarID:    carID,
+		Metric:   metric,
+		Capacity: mqtt.GlobalCache.HistorySize(),
+		Points:   mqtt.GlobalCache.History(carID, metric, limit),
+	}
+	if e, ok := mqtt.GlobalCache.GetEntry(carID, metric); ok {
+		result.Type = e.Type
+	}
+
+	c.JSON(http.StatusOK, SuccessResponse(result))
+}
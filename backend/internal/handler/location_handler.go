@@ -0,0 +1,123 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"teslamate-cyberui/internal/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// heatmapDefaultDays 未指定 startDate 时热力图默认覆盖的天数
+const heatmapDefaultDays = 30
+
+// GetVisitedPlaces 获取到访地点排行
+// 行程终点和充电按地理围栏（无围栏时按地址）聚合，limit 默认 50，最大 500
+func (h *Handler) GetVisitedPlaces(c *gin.Context) {
+	idStr := c.Param("id")
+	carID64, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse(400, "Invalid car ID"))
+		return
+	}
+	if carID64 < -32768 || carID64 > 32767 {
+		c.JSON(http.StatusBadRequest, ErrorResponse(400, "Car ID out of valid range"))
+		return
+	}
+	carID := int16(carID64)
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit < 1 || limit > 500 {
+		c.JSON(http.StatusBadRequest, ErrorResponse(400, "limit must be between 1 and 500"))
+		return
+	}
+
+	startDate := parseDateTime(c.Query("startDate"), false)
+	endDate := parseDateTime(c.Query("endDate"), true)
+
+	places, err := h.repo.Location.GetPlaces(c.Request.Context(), carID, startDate, endDate, limit)
+	if err != nil {
+		logger.Errorf("Failed to get visited places: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse(500, "Failed to get visited places"))
+		return
+	}
+
+	c.JSON(http.StatusOK, SuccessResponse(places))
+}
+
+// GetLocationHeatmap 获取到访位置热力图
+// 未指定 startDate 时默认取 endDate（或当前时间）之前 30 天；precision 为坐标保留的小数位数（2-5，默认 4，约 10 米）
+func (h *Handler) GetLocationHeatmap(c *gin.Context) {
+	idStr := c.Param("id")
+	carID64, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse(400, "Invalid car ID"))
+		return
+	}
+	if carID64 < -32768 || carID64 > 32767 {
+		c.JSON(http.StatusBadRequest, ErrorResponse(400, "Car ID out of valid range"))
+		return
+	}
+	carID := int16(carID64)
+
+	precision, err := strconv.Atoi(c.DefaultQuery("precision", "4"))
+	if err != nil || precision < 2 || precision > 5 {
+		c.JSON(http.StatusBadRequest, ErrorResponse(400, "precision must be between 2 and 5"))
+		return
+	}
+
+	startDate := parseDateTime(c.Query("startDate"), false)
+	endDate := parseDateTime(c.Query("endDate"), true)
+	if startDate == nil {
+		end := time.Now().UTC()
+		if endDate != nil {
+			end = *endDate
+		}
+		start := end.AddDate(0, 0, -heatmapDefaultDays)
+		startDate = &start
+	}
+
+	heatmap, err := h.repo.Location.GetHeatmap(c.Request.Context(), carID, startDate, endDate, precision)
+	if err != nil {
+		logger.Errorf("Failed to get location heatmap: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse(500, "Failed to get location heatmap"))
+		return
+	}
+
+	c.JSON(http.StatusOK, SuccessResponse(heatmap))
+}
+
+// GetVisitedRegions 获取到访的城市、省州和国家排行，limit 默认 10
+func (h *Handler) GetVisitedRegions(c *gin.Context) {
+	idStr := c.Param("id")
+	carID64, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse(400, "Invalid car ID"))
+		return
+	}
+	if carID64 < -32768 || carID64 > 32767 {
+		c.JSON(http.StatusBadRequest, ErrorResponse(400, "Car ID out of valid range"))
+		return
+	}
+	carID := int16(carID64)
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	if err != nil || limit < 1 || limit > 100 {
+		c.JSON(http.StatusBadRequest, ErrorResponse(400, "limit must be between 1 and 100"))
+		return
+	}
+
+	startDate := parseDateTime(c.Query("startDate"), false)
+	endDate := parseDateTime(c.Query("endDate"), true)
+
+	regions, err := h.repo.Location.GetRegions(c.Request.Context(), carID, startDate, endDate, limit)
+	if err != nil {
+		logger.Errorf("Failed to get visited regions: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse(500, "Failed to get visited regions"))
+		return
+	}
+
+	c.JSON(http.StatusOK, SuccessResponse(regions))
+}
@@ -0,0 +1,191 @@
+package handler
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"teslamate-cyberui/internal/logger"
+	"teslamate-cyberui/internal/model"
+	"teslamate-cyberui/internal/report"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DriveTagRequest 标记行程用途请求
+type DriveTagRequest struct {
+	Purpose string `json:"purpose"`
+	Note    string `json:"note"`
+}
+
+// DriveTagBatchRequest 批量标记行程用途请求
+type DriveTagBatchRequest struct {
+	DriveIDs []int64 `json:"driveIds"`
+	Purpose  string  `json:"purpose"`
+	Note     string  `json:"note"`
+}
+
+// GetDriveTag 获取行程的用途标记
+func (h *Handler) GetDriveTag(c *gin.Context) {
+	driveID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse(400, "Invalid drive ID"))
+		return
+	}
+
+	tag, err := h.repo.DriveTag.Get(c.Request.Context(), driveID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse(500, "Failed to get drive tag"))
+		return
+	}
+	if tag == nil {
+		c.JSON(http.StatusNotFound, ErrorResponse(404, "Drive is not tagged"))
+		return
+	}
+
+	c.JSON(http.StatusOK, SuccessResponse(tag))
+}
+
+// UpdateDriveTag 标记行程用途（business / private / commute）
+func (h *Handler) UpdateDriveTag(c *gin.Context) {
+	driveID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse(400, "Invalid drive ID"))
+		return
+	}
+
+	var req DriveTagRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse(400, err.Error()))
+		return
+	}
+	if !report.ValidPurpose(req.Purpose) {
+		c.JSON(http.StatusBadRequest, ErrorResponse(400, "purpose must be business, private or commute"))
+		return
+	}
+
+	tag := &model.DriveTag{DriveID: driveID, Purpose: req.Purpose, Note: strings.TrimSpace(req.Note)}
+	found, err := h.repo.DriveTag.Set(c.Request.Context(), tag)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse(500, "Failed to tag drive"))
+		return
+	}
+	if !found {
+		c.JSON(http.StatusNotFound, ErrorResponse(404, "Drive not found"))
+		return
+	}
+
+	c.JSON(http.StatusOK, SuccessResponse(tag))
+}
+
+// DeleteDriveTag 清除行程的用途标记
+func (h *Handler) DeleteDriveTag(c *gin.Context) {
+	driveID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse(400, "Invalid drive ID"))
+		return
+	}
+
+	found, err := h.repo.DriveTag.Delete(c.Request.Context(), driveID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse(500, "Failed to delete drive tag"))
+		return
+	}
+	if !found {
+		c.JSON(http.StatusNotFound, ErrorResponse(404, "Drive is not tagged"))
+		return
+	}
+
+	c.JSON(http.StatusOK, SuccessResponse(nil))
+}
+
+// BatchUpdateDriveTags 批量标记行程用途，返回实际标记的行程数
+func (h *Handler) BatchUpdateDriveTags(c *gin.Context) {
+	var req DriveTagBatchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse(400, err.Error()))
+		return
+	}
+	if len(req.DriveIDs) == 0 || len(req.DriveIDs) > 1000 {
+		c.JSON(http.StatusBadRequest, ErrorResponse(400, "driveIds must contain between 1 and 1000 drives"))
+		return
+	}
+	if !report.ValidPurpose(req.Purpose) {
+		c.JSON(http.StatusBadRequest, ErrorResponse(400, "purpose must be business, private or commute"))
+		return
+	}
+
+	count, err := h.repo.DriveTag.SetMany(c.Request.Context(), req.DriveIDs, req.Purpose, strings.TrimSpace(req.Note))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse(500, "Failed to tag drives"))
+		return
+	}
+
+	c.JSON(http.StatusOK, SuccessResponse(gin.H{"updated": count}))
+}
+
+// GetMileageLog 生成里程日志
+// purpose 可筛选 business / private / commute / untagged，format 为 json（默认）/ csv / html
+func (h *Handler) GetMileageLog(c *gin.Context) {
+	idStr := c.Param("id")
+	carID64, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse(400, "Invalid car ID"))
+		return
+	}
+	if carID64 < -32768 || carID64 > 32767 {
+		c.JSON(http.StatusBadRequest, ErrorResponse(400, "Car ID out of valid range"))
+		return
+	}
+	carID := int16(carID64)
+
+	purpose := c.Query("purpose")
+	if purpose != "" && purpose != model.DrivePurposeUntagged && !report.ValidPurpose(purpose) {
+		c.JSON(http.StatusBadRequest, ErrorResponse(400, "purpose must be business, private, commute or untagged"))
+		return
+	}
+	format := c.DefaultQuery("format", report.FormatJSON)
+	if format != report.FormatJSON && format != report.FormatCSV && format != report.FormatHTML {
+		c.JSON(http.StatusBadRequest, ErrorResponse(400, "format must be json, csv or html"))
+		return
+	}
+
+	startDate := parseDateTime(c.Query("startDate"), false)
+	endDate := parseDateTime(c.Query("endDate"), true)
+
+	log, err := report.BuildMileageLog(c.Request.Context(), h.repo, carID, startDate, endDate, purpose)
+	if err != nil {
+		if errors.Is(err, report.ErrCarNotFound) {
+			c.JSON(http.StatusNotFound, ErrorResponse(404, "Car not found"))
+			return
+		}
+		logger.Errorf("Failed to build mileage log for car %d: %v", carID, err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse(500, "Failed to build mileage log"))
+		return
+	}
+
+	switch format {
+	case report.FormatCSV:
+		var buf bytes.Buffer
+		if err := report.WriteMileageCSV(&buf, log); err != nil {
+			logger.Errorf("Failed to render mileage log for car %d: %v", carID, err)
+			c.JSON(http.StatusInternalServerError, ErrorResponse(500, "Failed to render mileage log"))
+			return
+		}
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, report.FileName(log, "csv")))
+		c.Data(http.StatusOK, "text/csv; charset=utf-8", buf.Bytes())
+	case report.FormatHTML:
+		html, err := report.MileageHTML(log)
+		if err != nil {
+			logger.Errorf("Failed to render mileage log for car %d: %v", carID, err)
+			c.JSON(http.StatusInternalServerError, ErrorResponse(500, "Failed to render mileage log"))
+			return
+		}
+		c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(html))
+	default:
+		c.JSON(http.StatusOK, SuccessResponse(log))
+	}
+}
@@ -0,0 +1,215 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"teslamate-cyberui/internal/logger"
+	"teslamate-cyberui/internal/model"
+	"teslamate-cyberui/internal/notify"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 发送测试通知的超时时间
+const notificationTestTimeout = 30 * time.Second
+
+// NotificationChannelRequest 创建/更新通知渠道请求
+// 更新时敏感字段（secret、token、password、botToken）保持为 "******" 表示沿用原值
+type NotificationChannelRequest struct {
+	Name          string           `json:"name"`
+	Type          string           `json:"type"`
+	Config        model.JSONObject `json:"config"`
+	TitleTemplate string           `json:"titleTemplate"`
+	BodyTemplate  string           `json:"bodyTemplate"`
+	Enabled       *bool            `json:"enabled"`
+}
+
+// toChannel 转换为渠道模型，enabled 未指定时默认启用
+func (req *NotificationChannelRequest) toChannel() *model.NotificationChannel {
+	ch := &model.NotificationChannel{
+		Name:          req.Name,
+		Type:          req.Type,
+		Config:        req.Config,
+		TitleTemplate: req.TitleTemplate,
+		BodyTemplate:  req.BodyTemplate,
+		Enabled:       true,
+	}
+	if ch.Config == nil {
+		ch.Config = model.JSONObject{}
+	}
+	if req.Enabled != nil {
+		ch.Enabled = *req.Enabled
+	}
+	return ch
+}
+
+// maskChannel 返回隐藏敏感配置后的渠道
+func maskChannel(ch model.NotificationChannel) model.NotificationChannel {
+	ch.Config = notify.MaskConfig(ch.Type, ch.Config)
+	return ch
+}
+
+// GetNotificationChannels 获取所有通知渠道
+func (h *Handler) GetNotificationChannels(c *gin.Context) {
+	channels, err := h.repo.Notify.List(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse(500, "Failed to get notification channels"))
+		return
+	}
+
+	for i := range channels {
+		channels[i] = maskChannel(channels[i])
+	}
+	c.JSON(http.StatusOK, SuccessResponse(channels))
+}
+
+// GetNotificationChannel 获取单个通知渠道
+func (h *Handler) GetNotificationChannel(c *gin.Context) {
+	channelID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse(400, "Invalid channel ID"))
+		return
+	}
+
+	ch, err := h.repo.Notify.Get(c.Request.Context(), channelID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse(500, "Failed to get notification channel"))
+		return
+	}
+	if ch == nil {
+		c.JSON(http.StatusNotFound, ErrorResponse(404, "Notification channel not found"))
+		return
+	}
+
+	c.JSON(http.StatusOK, SuccessResponse(maskChannel(*ch)))
+}
+
+// CreateNotificationChannel 创建通知渠道
+func (h *Handler) CreateNotificationChannel(c *gin.Context) {
+	var req NotificationChannelRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse(400, err.Error()))
+		return
+	}
+
+	ch := req.toChannel()
+	if err := notify.Validate(*ch); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse(400, err.Error()))
+		return
+	}
+
+	if err := h.repo.Notify.Create(c.Request.Context(), ch); err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse(500, "Failed to create notification channel"))
+		return
+	}
+
+	c.JSON(http.StatusOK, SuccessResponse(maskChannel(*ch)))
+}
+
+// UpdateNotificationChannel 更新通知渠道
+func (h *Handler) UpdateNotificationChannel(c *gin.Context) {
+	channelID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse(400, "Invalid channel ID"))
+		return
+	}
+
+	var req NotificationChannelRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse(400, err.Error()))
+		return
+	}
+
+	old, err := h.repo.Notify.Get(c.Request.Context(), channelID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse(500, "Failed to get notification channel"))
+		return
+	}
+	if old == nil {
+		c.JSON(http.StatusNotFound, ErrorResponse(404, "Notification channel not found"))
+		return
+	}
+
+	ch := req.toChannel()
+	ch.ID = channelID
+	if ch.Type == old.Type {
+		notify.MergeSecrets(ch.Type, ch.Config, old.Config)
+	}
+	if err := notify.Validate(*ch); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse(400, err.Error()))
+		return
+	}
+
+	found, err := h.repo.Notify.Update(c.Request.Context(), ch)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse(500, "Failed to update notification channel"))
+		return
+	}
+	if !found {
+		c.JSON(http.StatusNotFound, ErrorResponse(404, "Notification channel not found"))
+		return
+	}
+
+	c.JSON(http.StatusOK, SuccessResponse(maskChannel(*ch)))
+}
+
+// DeleteNotificationChannel 删除通知渠道，引用它的告警规则会跳过该渠道
+func (h *Handler) DeleteNotificationChannel(c *gin.Context) {
+	channelID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse(400, "Invalid channel ID"))
+		return
+	}
+
+	found, err := h.repo.Notify.Delete(c.Request.Context(), channelID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse(500, "Failed to delete notification channel"))
+		return
+	}
+	if !found {
+		c.JSON(http.StatusNotFound, ErrorResponse(404, "Notification channel not found"))
+		return
+	}
+
+	c.JSON(http.StatusOK, SuccessResponse(nil))
+}
+
+// TestNotificationChannel 通过渠道发送一条测试消息，失败时返回具体错误
+func (h *Handler) TestNotificationChannel(c *gin.Context) {
+	channelID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse(400, "Invalid channel ID"))
+		return
+	}
+
+	ch, err := h.repo.Notify.Get(c.Request.Context(), channelID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse(500, "Failed to get notification channel"))
+		return
+	}
+	if ch == nil {
+		c.JSON(http.StatusNotFound, ErrorResponse(404, "Notification channel not found"))
+		return
+	}
+
+	msg := notify.Message{
+		Kind:  notify.KindTest,
+		Title: "TeslaMate CyberUI",
+		Body:  "This is a test notification from TeslaMate CyberUI.",
+		Time:  time.Now().UTC(),
+		Data:  map[string]interface{}{},
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), notificationTestTimeout)
+	defer cancel()
+	if err := notify.Send(ctx, *ch, msg); err != nil {
+		logger.Warnf("Test notification via channel %d failed: %v", channelID, err)
+		c.JSON(http.StatusBadGateway, ErrorResponse(502, err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, SuccessResponse(nil))
+}
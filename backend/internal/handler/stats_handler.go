@@ -5,7 +5,9 @@ import (
 	"strconv"
 	"time"
 
+	"teslamate-cyberui/internal/analytics"
 	"teslamate-cyberui/internal/logger"
+	"teslamate-cyberui/internal/model"
 	"teslamate-cyberui/internal/mqtt"
 
 	"github.com/gin-gonic/gin"
@@ -169,6 +171,184 @@ func (h *Handler) GetStatesTimeline(c *gin.Context) {
 	c.JSON(http.StatusOK, SuccessResponse(data))
 }
 
+// GetVampireDrain 获取停放耗电统计
+// minHours 为计入统计的最短停放时长（小时），默认 6，与 Grafana 面板一致
+func (h *Handler) GetVampireDrain(c *gin.Context) {
+	idStr := c.Param("id")
+	carID64, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse(400, "Invalid car ID"))
+		return
+	}
+	if carID64 < -32768 || carID64 > 32767 {
+		c.JSON(http.StatusBadRequest, ErrorResponse(400, "Car ID out of valid range"))
+		return
+	}
+	carID := int16(carID64)
+
+	// 解析时间筛选参数
+	startDate := parseDateTime(c.Query("startDate"), false)
+	endDate := parseDateTime(c.Query("endDate"), true)
+
+	minHours, err := strconv.ParseFloat(c.DefaultQuery("minHours", "6"), 64)
+	if err != nil || minHours < 0 || minHours > 24*7 {
+		c.JSON(http.StatusBadRequest, ErrorResponse(400, "minHours must be between 0 and 168"))
+		return
+	}
+
+	stats, err := h.repo.Stats.GetVampireDrain(c.Request.Context(), carID, startDate, endDate, time.Duration(minHours*float64(time.Hour)))
+	if err != nil {
+		logger.Errorf("Failed to get vampire drain stats: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse(500, "Failed to get vampire drain stats"))
+		return
+	}
+
+	c.JSON(http.StatusOK, SuccessResponse(stats))
+}
+
+// GetProjectedRange 获取预估满电续航历史
+// xAxis=time（默认）时按 interval（day/week/month，默认 day）分组，
+// xAxis=mileage 时按 bucketKm（默认 1000）公里分组
+func (h *Handler) GetProjectedRange(c *gin.Context) {
+	idStr := c.Param("id")
+	carID64, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse(400, "Invalid car ID"))
+		return
+	}
+	if carID64 < -32768 || carID64 > 32767 {
+		c.JSON(http.StatusBadRequest, ErrorResponse(400, "Car ID out of valid range"))
+		return
+	}
+	carID := int16(carID64)
+
+	// 解析时间筛选参数
+	startDate := parseDateTime(c.Query("startDate"), false)
+	endDate := parseDateTime(c.Query("endDate"), true)
+
+	interval := c.DefaultQuery("interval", "day")
+	if interval != "day" && interval != "week" && interval != "month" {
+		c.JSON(http.StatusBadRequest, ErrorResponse(400, "interval must be day, week or month"))
+		return
+	}
+
+	var bucketKm float64
+	switch c.DefaultQuery("xAxis", "time") {
+	case "time":
+	case "mileage":
+		bucketKm, err = strconv.ParseFloat(c.DefaultQuery("bucketKm", "1000"), 64)
+		if err != nil || bucketKm < 10 {
+			c.JSON(http.StatusBadRequest, ErrorResponse(400, "bucketKm must be at least 10"))
+			return
+		}
+	default:
+		c.JSON(http.StatusBadRequest, ErrorResponse(400, "xAxis must be time or mileage"))
+		return
+	}
+
+	stats, err := h.repo.Stats.GetProjectedRange(c.Request.Context(), carID, startDate, endDate, interval, bucketKm)
+	if err != nil {
+		logger.Errorf("Failed to get projected range: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse(500, "Failed to get projected range"))
+		return
+	}
+
+	c.JSON(http.StatusOK, SuccessResponse(stats))
+}
+
+// GetBatteryHealth 获取电池健康报告
+// minSocDelta 为参与估算的充电最小 SOC 变化（默认 20），nominalCapacity 为可选的新车可用容量 (kWh)
+func (h *Handler) GetBatteryHealth(c *gin.Context) {
+	idStr := c.Param("id")
+	carID64, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse(400, "Invalid car ID"))
+		return
+	}
+	if carID64 < -32768 || carID64 > 32767 {
+		c.JSON(http.StatusBadRequest, ErrorResponse(400, "Car ID out of valid range"))
+		return
+	}
+	carID := int16(carID64)
+
+	minSocDelta, err := strconv.Atoi(c.DefaultQuery("minSocDelta", "20"))
+	if err != nil || minSocDelta < 5 || minSocDelta > 100 {
+		c.JSON(http.StatusBadRequest, ErrorResponse(400, "minSocDelta must be between 5 and 100"))
+		return
+	}
+
+	var nominal *float64
+	if v := c.Query("nominalCapacity"); v != "" {
+		capacity, err := strconv.ParseFloat(v, 64)
+		if err != nil || capacity <= 0 || capacity > 300 {
+			c.JSON(http.StatusBadRequest, ErrorResponse(400, "Invalid nominalCapacity"))
+			return
+		}
+		nominal = &capacity
+	}
+
+	samples, err := h.repo.Stats.GetCapacitySamples(c.Request.Context(), carID, minSocDelta)
+	if err != nil {
+		logger.Errorf("Failed to get battery health: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse(500, "Failed to get battery health"))
+		return
+	}
+
+	c.JSON(http.StatusOK, SuccessResponse(analytics.BatteryHealth(samples, nominal)))
+}
+
+// GetEfficiencyFactors 按车外温度、平均速度、每公里爬升和空调状态分组统计行程能耗
+// tempStep / speedStep / elevationStep 为各维度的分组宽度，minDistance 为参与统计的最短行程 (km)
+func (h *Handler) GetEfficiencyFactors(c *gin.Context) {
+	idStr := c.Param("id")
+	carID64, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse(400, "Invalid car ID"))
+		return
+	}
+	if carID64 < -32768 || carID64 > 32767 {
+		c.JSON(http.StatusBadRequest, ErrorResponse(400, "Car ID out of valid range"))
+		return
+	}
+	carID := int16(carID64)
+
+	// 解析时间筛选参数
+	startDate := parseDateTime(c.Query("startDate"), false)
+	endDate := parseDateTime(c.Query("endDate"), true)
+
+	minDistance, err := strconv.ParseFloat(c.DefaultQuery("minDistance", "1"), 64)
+	if err != nil || minDistance < 0 || minDistance > 1000 {
+		c.JSON(http.StatusBadRequest, ErrorResponse(400, "minDistance must be between 0 and 1000"))
+		return
+	}
+
+	var steps model.EfficiencyFactorSteps
+	steps.Temperature, err = strconv.ParseFloat(c.DefaultQuery("tempStep", "5"), 64)
+	if err != nil || steps.Temperature < 1 || steps.Temperature > 20 {
+		c.JSON(http.StatusBadRequest, ErrorResponse(400, "tempStep must be between 1 and 20"))
+		return
+	}
+	steps.Speed, err = strconv.ParseFloat(c.DefaultQuery("speedStep", "10"), 64)
+	if err != nil || steps.Speed < 1 || steps.Speed > 50 {
+		c.JSON(http.StatusBadRequest, ErrorResponse(400, "speedStep must be between 1 and 50"))
+		return
+	}
+	steps.Elevation, err = strconv.ParseFloat(c.DefaultQuery("elevationStep", "5"), 64)
+	if err != nil || steps.Elevation < 1 || steps.Elevation > 100 {
+		c.JSON(http.StatusBadRequest, ErrorResponse(400, "elevationStep must be between 1 and 100"))
+		return
+	}
+
+	samples, err := h.repo.Stats.GetEfficiencySamples(c.Request.Context(), carID, startDate, endDate, minDistance)
+	if err != nil {
+		logger.Errorf("Failed to get efficiency factors: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse(500, "Failed to get efficiency factors"))
+		return
+	}
+
+	c.JSON(http.StatusOK, SuccessResponse(analytics.EfficiencyFactors(samples, steps)))
+}
+
 // parseTimeRange parses time range from query parameters
 // Supports formats: YYYY-MM-DD, YYYY-MM-DDTHH:mm:ss (local Beijing time), RFC3339
 // Returns UTC time for database queries
@@ -0,0 +1,254 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"teslamate-cyberui/internal/logger"
+	"teslamate-cyberui/internal/model"
+	"teslamate-cyberui/internal/tariff"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TariffRequest 创建/更新电价方案请求
+type TariffRequest struct {
+	Name        string              `json:"name"`
+	GeofenceID  *int64              `json:"geofenceId"`
+	Currency    string              `json:"currency"`
+	PricePerKWh float64             `json:"pricePerKwh"`
+	SessionFee  float64             `json:"sessionFee"`
+	Periods     model.TariffPeriods `json:"periods"`
+	ValidFrom   *time.Time          `json:"validFrom"`
+	ValidUntil  *time.Time          `json:"validUntil"`
+	Enabled     *bool               `json:"enabled"`
+}
+
+// toTariff 转换为电价方案模型，enabled 未指定时默认启用
+func (req *TariffRequest) toTariff() *model.Tariff {
+	t := &model.Tariff{
+		Name:        req.Name,
+		GeofenceID:  req.GeofenceID,
+		Currency:    req.Currency,
+		PricePerKWh: req.PricePerKWh,
+		SessionFee:  req.SessionFee,
+		Periods:     req.Periods,
+		ValidFrom:   req.ValidFrom,
+		ValidUntil:  req.ValidUntil,
+		Enabled:     true,
+	}
+	if t.Periods == nil {
+		t.Periods = model.TariffPeriods{}
+	}
+	if req.Enabled != nil {
+		t.Enabled = *req.Enabled
+	}
+	return t
+}
+
+// ChargeCostRequest 手动修改充电费用请求，cost 为 null 时清除费用
+type ChargeCostRequest struct {
+	Cost *float64 `json:"cost"`
+}
+
+// GetTariffs 获取全部电价方案
+func (h *Handler) GetTariffs(c *gin.Context) {
+	tariffs, err := h.repo.Tariff.List(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse(500, "Failed to get tariffs"))
+		return
+	}
+
+	c.JSON(http.StatusOK, SuccessResponse(tariffs))
+}
+
+// GetTariff 获取单个电价方案
+func (h *Handler) GetTariff(c *gin.Context) {
+	tariffID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse(400, "Invalid tariff ID"))
+		return
+	}
+
+	t, err := h.repo.Tariff.Get(c.Request.Context(), tariffID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse(500, "Failed to get tariff"))
+		return
+	}
+	if t == nil {
+		c.JSON(http.StatusNotFound, ErrorResponse(404, "Tariff not found"))
+		return
+	}
+
+	c.JSON(http.StatusOK, SuccessResponse(t))
+}
+
+// CreateTariff 创建电价方案
+func (h *Handler) CreateTariff(c *gin.Context) {
+	var req TariffRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse(400, err.Error()))
+		return
+	}
+
+	t := req.toTariff()
+	if err := tariff.Validate(t); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse(400, err.Error()))
+		return
+	}
+
+	if err := h.repo.Tariff.Create(c.Request.Context(), t); err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse(500, "Failed to create tariff"))
+		return
+	}
+
+	c.JSON(http.StatusOK, SuccessResponse(t))
+}
+
+// UpdateTariff 更新电价方案
+func (h *Handler) UpdateTariff(c *gin.Context) {
+	tariffID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse(400, "Invalid tariff ID"))
+		return
+	}
+
+	var req TariffRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse(400, err.Error()))
+		return
+	}
+
+	t := req.toTariff()
+	t.ID = tariffID
+	if err := tariff.Validate(t); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse(400, err.Error()))
+		return
+	}
+
+	found, err := h.repo.Tariff.Update(c.Request.Context(), t)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse(500, "Failed to update tariff"))
+		return
+	}
+	if !found {
+		c.JSON(http.StatusNotFound, ErrorResponse(404, "Tariff not found"))
+		return
+	}
+
+	c.JSON(http.StatusOK, SuccessResponse(t))
+}
+
+// DeleteTariff 删除电价方案
+func (h *Handler) DeleteTariff(c *gin.Context) {
+	tariffID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse(400, "Invalid tariff ID"))
+		return
+	}
+
+	found, err := h.repo.Tariff.Delete(c.Request.Context(), tariffID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse(500, "Failed to delete tariff"))
+		return
+	}
+	if !found {
+		c.JSON(http.StatusNotFound, ErrorResponse(404, "Tariff not found"))
+		return
+	}
+
+	c.JSON(http.StatusOK, SuccessResponse(nil))
+}
+
+// GetChargeCostEstimate 按电价方案计算单次充电的费用（不写回）
+func (h *Handler) GetChargeCostEstimate(c *gin.Context) {
+	chargeID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse(400, "Invalid charge ID"))
+		return
+	}
+
+	estimate, err := tariff.NewEstimator(h.repo).EstimateCharge(c.Request.Context(), chargeID)
+	if err != nil {
+		logger.Errorf("Failed to estimate cost of charge %d: %v", chargeID, err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse(500, "Failed to calculate charge cost"))
+		return
+	}
+	if estimate == nil {
+		c.JSON(http.StatusNotFound, ErrorResponse(404, "Charge not found"))
+		return
+	}
+
+	c.JSON(http.StatusOK, SuccessResponse(estimate))
+}
+
+// UpdateChargeCost 手动修改 TeslaMate 中的充电费用（需开启写入模式）
+func (h *Handler) UpdateChargeCost(c *gin.Context) {
+	chargeID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse(400, "Invalid charge ID"))
+		return
+	}
+
+	var req ChargeCostRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse(400, err.Error()))
+		return
+	}
+	if req.Cost != nil && (*req.Cost < 0 || *req.Cost > 9999.99) {
+		c.JSON(http.StatusBadRequest, ErrorResponse(400, "cost must be between 0 and 9999.99"))
+		return
+	}
+
+	found, err := h.repo.Charge.UpdateCost(c.Request.Context(), chargeID, req.Cost)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse(500, "Failed to update charge cost"))
+		return
+	}
+	if !found {
+		c.JSON(http.StatusNotFound, ErrorResponse(404, "Charge not found"))
+		return
+	}
+	logger.Infof("Cost of charge %d updated manually", chargeID)
+
+	c.JSON(http.StatusOK, SuccessResponse(req))
+}
+
+// PreviewChargeCosts 预览时间范围内按电价方案计算的充电费用
+func (h *Handler) PreviewChargeCosts(c *gin.Context) {
+	h.recalculateChargeCosts(c, false)
+}
+
+// ApplyChargeCosts 计算时间范围内的充电费用并写回 TeslaMate（需开启写入模式）
+func (h *Handler) ApplyChargeCosts(c *gin.Context) {
+	h.recalculateChargeCosts(c, true)
+}
+
+// recalculateChargeCosts 批量计算充电费用，overwrite=true 时覆盖已有费用
+func (h *Handler) recalculateChargeCosts(c *gin.Context, apply bool) {
+	idStr := c.Param("id")
+	carID64, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse(400, "Invalid car ID"))
+		return
+	}
+	if carID64 < -32768 || carID64 > 32767 {
+		c.JSON(http.StatusBadRequest, ErrorResponse(400, "Car ID out of valid range"))
+		return
+	}
+	carID := int16(carID64)
+
+	startDate := parseDateTime(c.Query("startDate"), false)
+	endDate := parseDateTime(c.Query("endDate"), true)
+	overwrite := c.Query("overwrite") == "true"
+
+	result, err := tariff.NewEstimator(h.repo).Recalculate(c.Request.Context(), carID, startDate, endDate, overwrite, apply)
+	if err != nil {
+		logger.Errorf("Failed to recalculate charge costs for car %d: %v", carID, err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse(500, "Failed to calculate charge costs"))
+		return
+	}
+
+	c.JSON(http.StatusOK, SuccessResponse(result))
+}
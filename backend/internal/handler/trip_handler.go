@@ -0,0 +1,120 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"teslamate-cyberui/internal/logger"
+	"teslamate-cyberui/internal/trip"
+
+	"github.com/gin-gonic/gin"
+)
+
+// tripOptions 解析旅程分组参数：maxGapHours 相邻活动的最长间隔（小时），minDistance 最短总里程 (km)，
+// boundaryGeofences 以逗号分隔的边界地理围栏 ID
+func tripOptions(c *gin.Context) (trip.Options, error) {
+	opts := trip.Options{
+		MaxGap:      trip.DefaultMaxGap,
+		MinDistance: trip.DefaultMinDistance,
+	}
+
+	if v := c.Query("maxGapHours"); v != "" {
+		hours, err := strconv.ParseFloat(v, 64)
+		if err != nil || hours < 0.25 || hours > 72 {
+			return opts, errors.New("maxGapHours must be between 0.25 and 72")
+		}
+		opts.MaxGap = time.Duration(hours * float64(time.Hour))
+	}
+	if v := c.Query("minDistance"); v != "" {
+		distance, err := strconv.ParseFloat(v, 64)
+		if err != nil || distance < 0 || distance > 10000 {
+			return opts, errors.New("minDistance must be between 0 and 10000")
+		}
+		opts.MinDistance = distance
+	}
+	if v := c.Query("boundaryGeofences"); v != "" {
+		for _, s := range strings.Split(v, ",") {
+			id, err := strconv.ParseInt(strings.TrimSpace(s), 10, 64)
+			if err != nil {
+				return opts, errors.New("boundaryGeofences must be a comma separated list of geofence IDs")
+			}
+			opts.BoundaryGeofences = append(opts.BoundaryGeofences, id)
+		}
+	}
+	return opts, nil
+}
+
+// GetTrips 获取由连续行程和充电合并而成的旅程列表
+func (h *Handler) GetTrips(c *gin.Context) {
+	idStr := c.Param("id")
+	carID64, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse(400, "Invalid car ID"))
+		return
+	}
+	if carID64 < -32768 || carID64 > 32767 {
+		c.JSON(http.StatusBadRequest, ErrorResponse(400, "Car ID out of valid range"))
+		return
+	}
+	carID := int16(carID64)
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("pageSize", "20"))
+
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	opts, err := tripOptions(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse(400, err.Error()))
+		return
+	}
+
+	// 解析时间筛选参数
+	startDate := parseDateTime(c.Query("startDate"), false)
+	endDate := parseDateTime(c.Query("endDate"), true)
+
+	result, err := trip.List(c.Request.Context(), h.repo, carID, startDate, endDate, opts, page, pageSize)
+	if err != nil {
+		logger.Errorf("Failed to get trips for car %d: %v", carID, err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse(500, "Failed to get trips"))
+		return
+	}
+
+	c.JSON(http.StatusOK, SuccessResponse(result))
+}
+
+// GetTrip 获取旅程详情，包括时间线和合并后的轨迹；id 为旅程中任一行程的 ID
+func (h *Handler) GetTrip(c *gin.Context) {
+	driveID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse(400, "Invalid trip ID"))
+		return
+	}
+
+	opts, err := tripOptions(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse(400, err.Error()))
+		return
+	}
+
+	detail, err := trip.Get(c.Request.Context(), h.repo, driveID, opts)
+	if err != nil {
+		if errors.Is(err, trip.ErrTripNotFound) {
+			c.JSON(http.StatusNotFound, ErrorResponse(404, "Trip not found"))
+			return
+		}
+		logger.Errorf("Failed to get trip %d: %v", driveID, err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse(500, "Failed to get trip"))
+		return
+	}
+
+	c.JSON(http.StatusOK, SuccessResponse(detail))
+}
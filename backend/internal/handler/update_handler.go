@@ -0,0 +1,38 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"teslamate-cyberui/internal/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetCarUpdates 获取软件更新历史及各版本生效期间的行驶统计
+func (h *Handler) GetCarUpdates(c *gin.Context) {
+	idStr := c.Param("id")
+	carID64, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse(400, "Invalid car ID"))
+		return
+	}
+	if carID64 < -32768 || carID64 > 32767 {
+		c.JSON(http.StatusBadRequest, ErrorResponse(400, "Car ID out of valid range"))
+		return
+	}
+	carID := int16(carID64)
+
+	// 解析时间筛选参数
+	startDate := parseDateTime(c.Query("startDate"), false)
+	endDate := parseDateTime(c.Query("endDate"), true)
+
+	history, err := h.repo.Update.GetHistory(c.Request.Context(), carID, startDate, endDate)
+	if err != nil {
+		logger.Errorf("Failed to get update history: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse(500, "Failed to get update history"))
+		return
+	}
+
+	c.JSON(http.StatusOK, SuccessResponse(history))
+}
@@ -0,0 +1,303 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"teslamate-cyberui/internal/logger"
+	"teslamate-cyberui/internal/mqtt"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// wsWriteWait 单次写操作的超时时间
+	wsWriteWait = 10 * time.Second
+	// wsPongWait 等待客户端 pong 的最长时间，超时视为连接已断开
+	wsPongWait = 60 * time.Second
+	// wsPingInterval 服务端发送 ping 的间隔，必须小于 wsPongWait
+	wsPingInterval = 25 * time.Second
+	// wsMaxMessageSize 客户端消息的最大字节数
+	wsMaxMessageSize = 4096
+	// wsEventBuffer 每个连接的缓存事件缓冲区大小
+	wsEventBuffer = 256
+)
+
+// 认证由 APIKeyAuth 在握手阶段完成，这里不再限制 Origin，与 CORS 的默认策略保持一致
+var wsUpgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+	CheckOrigin: func(r *http.Request) bool {
+		return true
+	},
+}
+
+// WSClientMessage 客户端发送的订阅控制消息
+// action: subscribe / unsubscribe / ping
+// metrics 为空时表示该车辆的全部指标
+type WSClientMessage struct {
+	Action  string   `json:"action"`
+	CarIDs  []int16  `json:"carIds"`
+	Metrics []string `json:"metrics"`
+}
+
+// WSServerMessage 服务端推送的消息
+// type: snapshot / update / subscribed / pong / error
+type WSServerMessage struct {
+	Type          string                 `json:"type"`
+	CarID         int16                  `json:"carId,omitempty"`
+	Topic         string                 `json:"topic,omitempty"`
+	ValueType     mqtt.ValueType         `json:"valueType,omitempty"`
+	Value         interface{}            `json:"value,omitempty"`
+	Data          map[string]interface{} `json:"data,omitempty"`
+	Subscriptions map[string][]string    `json:"subscriptions,omitempty"`
+	Message       string                 `json:"message,omitempty"`
+	Time          time.Time              `json:"time"`
+}
+
+// wsSubscription 单个连接上某辆车的订阅；metrics 为 nil 表示订阅全部指标
+type wsSubscription struct {
+	metrics map[string]bool
+}
+
+// matches 判断指标是否在订阅范围内
+func (s *wsSubscription) matches(topic string) bool {
+	return s.metrics == nil || s.metrics[topic]
+}
+
+// LiveWebSocket 多车复用的实时遥测 WebSocket
+// 一个连接可以通过 subscribe / unsubscribe 消息订阅多辆车及其部分 MQTT 指标
+func (h *Handler) LiveWebSocket(c *gin.Context) {
+	conn, err := wsUpgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		// Upgrade 失败时已经向客户端写入了错误响应
+		logger.Warnf("WebSocket upgrade failed: %v", err)
+		return
+	}
+	defer conn.Close()
+
+	sub := mqtt.GlobalCache.SubscribeAll(wsEventBuffer)
+	defer mqtt.GlobalCache.Unsubscribe(sub)
+
+	// 读协程只负责解析消息，订阅状态和所有写操作都在当前协程中完成
+	// （gorilla/websocket 不支持并发写）
+	incoming := make(chan WSClientMessage)
+	readDone := make(chan struct{})
+	stop := make(chan struct{})
+	defer close(stop)
+	go wsReadLoop(conn, incoming, readDone, stop)
+
+	subscriptions := make(map[int16]*wsSubscription)
+	ping := time.NewTicker(wsPingInterval)
+	defer ping.Stop()
+
+	ctx := c.Request.Context()
+	for {
+		select {
+		case <-ctx.Done():
+			// 服务关闭时通知客户端后退出
+			conn.WriteControl(websocket.CloseMessage,
+				websocket.FormatCloseMessage(websocket.CloseGoingAway, "server shutting down"),
+				time.Now().Add(wsWriteWait))
+			return
+		case <-readDone:
+			return
+		case <-ping.C:
+			conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+			if err := conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		case msg := <-incoming:
+			if err := h.handleWSMessage(c, conn, subscriptions, msg); err != nil {
+				return
+			}
+		case u, ok := <-sub.Updates():
+			if !ok {
+				return
+			}
+			s, subscribed := subscriptions[u.CarID]
+			// 消费过慢导致丢失事件时，为所有已订阅车辆补发快照
+			if sub.Lagged() {
+				for carID, cs := range subscriptions {
+					if err := wsWrite(conn, wsSnapshot(carID, cs)); err != nil {
+						return
+					}
+				}
+			}
+			if !subscribed || !s.matches(u.Topic) {
+				continue
+			}
+			if err := wsWrite(conn, WSServerMessage{
+				Type:      "update",
+				CarID:     u.CarID,
+				Topic:     u.Topic,
+				ValueType: u.Type,
+				Value:     u.Value,
+				Time:      u.UpdatedAt,
+			}); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// wsReadLoop 持续读取客户端消息，连接关闭或出错时关闭 done；stop 关闭后立即退出
+func wsReadLoop(conn *websocket.Conn, incoming chan<- WSClientMessage, done chan<- struct{}, stop <-chan struct{}) {
+	defer close(done)
+
+	conn.SetReadLimit(wsMaxMessageSize)
+	conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	})
+
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseNormalClosure) {
+				logger.Debugf("WebSocket read error: %v", err)
+			}
+			return
+		}
+		// 无法解析的消息以空 action 交给处理逻辑，回复错误而不是断开连接
+		var msg WSClientMessage
+		if err := json.Unmarshal(data, &msg); err != nil {
+			msg = WSClientMessage{}
+		}
+		// 任何客户端消息都视为存活信号
+		conn.SetReadDeadline(time.Now().Add(wsPongWait))
+		select {
+		case incoming <- msg:
+		case <-stop:
+			return
+		}
+	}
+}
+
+// handleWSMessage 处理一条客户端控制消息，返回写错误时连接应当关闭
+func (h *Handler) handleWSMessage(c *gin.Context, conn *websocket.Conn, subscriptions map[int16]*wsSubscription, msg WSClientMessage) error {
+	now := time.Now().UTC()
+
+	switch msg.Action {
+	case "ping":
+		return wsWrite(conn, WSServerMessage{Type: "pong", Time: now})
+
+	case "subscribe":
+		if len(msg.CarIDs) == 0 {
+			return wsWrite(conn, WSServerMessage{Type: "error", Message: "carIds is required", Time: now})
+		}
+		for _, carID := range msg.CarIDs {
+			car, err := h.repo.Car.GetByID(c.Request.Context(), carID)
+			if err != nil {
+				logger.Errorf("Failed to get car %d for WebSocket subscription: %v", carID, err)
+				return wsWrite(conn, WSServerMessage{Type: "error", CarID: carID, Message: "Failed to get car", Time: now})
+			}
+			if car == nil {
+				if err := wsWrite(conn, WSServerMessage{Type: "error", CarID: carID, Message: "Car not found", Time: now}); err != nil {
+					return err
+				}
+				continue
+			}
+
+			s, ok := subscriptions[carID]
+			switch {
+			case !ok && len(msg.Metrics) == 0:
+				s = &wsSubscription{}
+			case !ok:
+				s = &wsSubscription{metrics: make(map[string]bool)}
+			case len(msg.Metrics) == 0:
+				// 追加订阅全部指标
+				s.metrics = nil
+			}
+			if s.metrics != nil {
+				for _, m := range msg.Metrics {
+					s.metrics[m] = true
+				}
+			}
+			subscriptions[carID] = s
+
+			if err := wsWrite(conn, wsSnapshot(carID, s)); err != nil {
+				return err
+			}
+		}
+		return wsWrite(conn, wsSubscribedMessage(subscriptions))
+
+	case "unsubscribe":
+		for _, carID := range msg.CarIDs {
+			s, ok := subscriptions[carID]
+			if !ok {
+				continue
+			}
+			if len(msg.Metrics) == 0 {
+				delete(subscriptions, carID)
+				continue
+			}
+			if s.metrics == nil {
+				if err := wsWrite(conn, WSServerMessage{
+					Type:    "error",
+					CarID:   carID,
+					Message: "Cannot unsubscribe individual metrics from a subscription to all metrics",
+					Time:    now,
+				}); err != nil {
+					return err
+				}
+				continue
+			}
+			for _, m := range msg.Metrics {
+				delete(s.metrics, m)
+			}
+			if len(s.metrics) == 0 {
+				delete(subscriptions, carID)
+			}
+		}
+		return wsWrite(conn, wsSubscribedMessage(subscriptions))
+
+	case "":
+		return wsWrite(conn, WSServerMessage{Type: "error", Message: "Invalid message", Time: now})
+
+	default:
+		return wsWrite(conn, WSServerMessage{Type: "error", Message: "Unknown action: " + msg.Action, Time: now})
+	}
+}
+
+// wsSnapshot 构建按订阅指标过滤后的车辆快照消息
+func wsSnapshot(carID int16, s *wsSubscription) WSServerMessage {
+	snapshot := buildLiveSnapshot(carID)
+	for topic := range snapshot.Data {
+		if !s.matches(topic) {
+			delete(snapshot.Data, topic)
+		}
+	}
+	return WSServerMessage{
+		Type:  "snapshot",
+		CarID: carID,
+		Data:  snapshot.Data,
+		Time:  snapshot.Time,
+	}
+}
+
+// wsSubscribedMessage 构建当前订阅状态消息，空数组表示订阅全部指标
+func wsSubscribedMessage(subscriptions map[int16]*wsSubscription) WSServerMessage {
+	result := make(map[string][]string, len(subscriptions))
+	for carID, s := range subscriptions {
+		metrics := make([]string, 0, len(s.metrics))
+		for m := range s.metrics {
+			metrics = append(metrics, m)
+		}
+		result[strconv.Itoa(int(carID))] = metrics
+	}
+	return WSServerMessage{
+		Type:          "subscribed",
+		Subscriptions: result,
+		Time:          time.Now().UTC(),
+	}
+}
+
+// wsWrite 带超时地写入一条 JSON 消息
+func wsWrite(conn *websocket.Conn, msg WSServerMessage) error {
+	conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+	return conn.WriteJSON(msg)
+}
@@ -1,6 +1,7 @@
 package logger
 
 import (
+	"net/url"
 	"os"
 	"time"
 
@@ -10,6 +11,9 @@ import (
 
 var log *logrus.Logger
 
+// redactedQueryParams 记录请求日志时需要隐藏取值的查询参数（WebSocket 握手通过 apiKey 传递密钥）
+var redactedQueryParams = []string{"apiKey"}
+
 // Init 初始化日志配置
 func Init(level string) {
 	log = logrus.New()
@@ -50,7 +54,7 @@ func GinLogger() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		startTime := time.Now()
 		path := c.Request.URL.Path
-		query := c.Request.URL.RawQuery
+		query := redactQuery(c.Request.URL.RawQuery)
 
 		c.Next()
 
@@ -83,6 +87,28 @@ func GinLogger() gin.HandlerFunc {
 	}
 }
 
+// redactQuery 将查询字符串中敏感参数的值替换为 ***，无法解析时整体隐藏
+func redactQuery(rawQuery string) string {
+	if rawQuery == "" {
+		return ""
+	}
+	values, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return "***"
+	}
+	redacted := false
+	for _, key := range redactedQueryParams {
+		if _, ok := values[key]; ok {
+			values.Set(key, "***")
+			redacted = true
+		}
+	}
+	if !redacted {
+		return rawQuery
+	}
+	return values.Encode()
+}
+
 // WithField 添加字段
 func WithField(key string, value interface{}) *logrus.Entry {
 	return GetLogger().WithField(key, value)
@@ -1,13 +1,17 @@
 package middleware
 
 import (
+	"crypto/subtle"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
 )
 
 // APIKeyAuth creates a middleware that validates the X-API-Key header
 // If apiKey is empty, authentication is disabled (pass-through)
+// Browsers cannot set custom headers on WebSocket handshakes, so upgrade
+// requests may pass the key in the apiKey query parameter instead
 func APIKeyAuth(apiKey string) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// If no API key is configured, disable authentication
@@ -18,6 +22,9 @@ func APIKeyAuth(apiKey string) gin.HandlerFunc {
 
 		// Get the API key from header
 		providedKey := c.GetHeader("X-API-Key")
+		if providedKey == "" && websocket.IsWebSocketUpgrade(c.Request) {
+			providedKey = c.Query("apiKey")
+		}
 
 		// Check if the key matches
 		if providedKey == "" {
@@ -28,7 +35,7 @@ func APIKeyAuth(apiKey string) gin.HandlerFunc {
 			return
 		}
 
-		if providedKey != apiKey {
+		if subtle.ConstantTimeCompare([]byte(providedKey), []byte(apiKey)) != 1 {
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
 				"code":    401,
 				"message": "Invalid API key",
@@ -39,3 +46,38 @@ func APIKeyAuth(apiKey string) gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// AdminKeyAuth creates a middleware that guards endpoints writing to
+// TeslaMate's own tables with the X-Admin-Key header
+// Unlike APIKeyAuth, an empty adminKey disables these endpoints entirely,
+// so the backend stays read-only against TeslaMate unless explicitly enabled
+func AdminKeyAuth(adminKey string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if adminKey == "" {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"code":    403,
+				"message": "Write mode is disabled, set CYBERUI_ADMIN_KEY to enable it",
+			})
+			return
+		}
+
+		providedKey := c.GetHeader("X-Admin-Key")
+		if providedKey == "" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"code":    401,
+				"message": "Admin key is required",
+			})
+			return
+		}
+
+		if subtle.ConstantTimeCompare([]byte(providedKey), []byte(adminKey)) != 1 {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"code":    401,
+				"message": "Invalid admin key",
+			})
+			return
+		}
+
+		c.Next()
+	}
+}
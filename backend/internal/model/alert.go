@@ -0,0 +1,105 @@
+package model
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// 告警规则的数据来源
+const (
+	// AlertSourceMQTT 基于 MQTT 实时数据，条件中的 metric 为 topic 名（如 battery_level、locked）
+	AlertSourceMQTT = "mqtt"
+	// AlertSourceCharge 基于新结束的充电记录（charging_processes）
+	AlertSourceCharge = "charge"
+	// AlertSourceDrive 基于新结束的行程记录（drives）
+	AlertSourceDrive = "drive"
+)
+
+// 条件比较运算符
+const (
+	AlertOpEq  = "eq"
+	AlertOpNe  = "ne"
+	AlertOpGt  = "gt"
+	AlertOpGte = "gte"
+	AlertOpLt  = "lt"
+	AlertOpLte = "lte"
+)
+
+// AlertCondition 单个告警条件，同一规则内的多个条件为“且”的关系
+type AlertCondition struct {
+	Metric   string `json:"metric"`
+	Operator string `json:"operator"`
+	Value    string `json:"value"`
+}
+
+// AlertConditions 以 JSONB 存储的条件列表
+type AlertConditions []AlertCondition
+
+// Value 实现 driver.Valuer
+func (c AlertConditions) Value() (driver.Value, error) {
+	if c == nil {
+		c = AlertConditions{}
+	}
+	b, err := json.Marshal(c)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+// Scan 实现 sql.Scanner
+func (c *AlertConditions) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*c = AlertConditions{}
+		return nil
+	case []byte:
+		return json.Unmarshal(v, c)
+	case string:
+		return json.Unmarshal([]byte(v), c)
+	default:
+		return fmt.Errorf("cannot scan %T into AlertConditions", src)
+	}
+}
+
+// AlertRule 告警规则
+// TimeStart / TimeEnd 为生效时间窗口（HH:MM，服务器本地时区），允许跨越午夜，例如 22:00-06:00；
+// Debounce 为条件需要持续满足的秒数，Cooldown 为两次告警之间的最小间隔秒数；
+// ChannelIDs 为触发时发送通知的渠道
+type AlertRule struct {
+	ID              int64           `db:"id" json:"id"`
+	Name            string          `db:"name" json:"name"`
+	CarID           *int16          `db:"car_id" json:"carId"`
+	Source          string          `db:"source" json:"source"`
+	Conditions      AlertConditions `db:"conditions" json:"conditions"`
+	TimeStart       *string         `db:"time_start" json:"timeStart"`
+	TimeEnd         *string         `db:"time_end" json:"timeEnd"`
+	DebounceSeconds int             `db:"debounce_seconds" json:"debounceSeconds"`
+	CooldownSeconds int             `db:"cooldown_seconds" json:"cooldownSeconds"`
+	ChannelIDs      IDList          `db:"channel_ids" json:"channelIds"`
+	Enabled         bool            `db:"enabled" json:"enabled"`
+	CreatedAt       time.Time       `db:"created_at" json:"createdAt"`
+	UpdatedAt       time.Time       `db:"updated_at" json:"updatedAt"`
+}
+
+// AlertEvent 告警历史记录
+type AlertEvent struct {
+	ID          int64             `json:"id"`
+	RuleID      *int64            `json:"ruleId"`
+	RuleName    string            `json:"ruleName"`
+	CarID       int16             `json:"carId"`
+	Source      string            `json:"source"`
+	Message     string            `json:"message"`
+	Details     map[string]string `json:"details"`
+	TriggeredAt time.Time         `json:"triggeredAt"`
+}
+
+// AlertRecord 新结束的充电或行程记录，Fields 为可在条件中引用的字段
+type AlertRecord struct {
+	ID      int64
+	CarID   int16
+	EndDate time.Time
+	Fields  map[string]string
+}
@@ -2,6 +2,7 @@ package model
 
 import (
 	"database/sql"
+	"fmt"
 	"time"
 )
 
@@ -25,6 +26,17 @@ type Car struct {
 	MarketingName   sql.NullString `db:"marketing_name" json:"marketingName,omitempty"`
 }
 
+// DisplayName 车辆显示名称：优先使用车辆名，其次车型名
+func (c *Car) DisplayName() string {
+	if c.Name.Valid && c.Name.String != "" {
+		return c.Name.String
+	}
+	if c.MarketingName.Valid && c.MarketingName.String != "" {
+		return c.MarketingName.String
+	}
+	return fmt.Sprintf("Car %d", c.ID)
+}
+
 // CarStatus 车辆实时状态
 type CarStatus struct {
 	CarID               int16           `json:"carId"`
@@ -52,8 +64,24 @@ type CarStatus struct {
 	Heading             *int            `json:"heading,omitempty"`
 	Geofence            *string         `json:"geofence,omitempty"`
 	SoftwareVersion     string          `json:"softwareVersion"`
+	// Sources 记录各字段（JSON 字段名）的数据来源和时间，用于判断数据新鲜度
+	Sources map[string]FieldSource `json:"sources,omitempty"`
+}
+
+// FieldSource 状态字段的数据来源
+type FieldSource struct {
+	Source    string    `json:"source"` // "db" 或 "mqtt"
+	UpdatedAt time.Time `json:"updatedAt"`
+	// Retained 值来自 MQTT 保留消息，数据时间未知，UpdatedAt 为接收时间
+	Retained bool `json:"retained,omitempty"`
 }
 
+// 状态字段数据来源
+const (
+	FieldSourceDB   = "db"
+	FieldSourceMQTT = "mqtt"
+)
+
 // Position 位置信息
 type Position struct {
 	ID               int64          `db:"id" json:"id"`
@@ -38,6 +38,7 @@ type ChargeListItem struct {
 	EndBatteryLevel   int        `json:"endBatteryLevel"`
 	Location          string     `json:"location"`
 	Cost              *float64   `json:"cost,omitempty"`
+	Efficiency        *float64   `json:"efficiency,omitempty"` // 充电效率 (%)
 	Latitude          *float64   `json:"latitude,omitempty"`
 	Longitude         *float64   `json:"longitude,omitempty"`
 	ChargeType        string     `json:"chargeType"` // "AC" 或 "DC"
@@ -92,10 +93,37 @@ type Charge struct {
 	FastChargerType      sql.NullString  `db:"fast_charger_type" json:"fastChargerType,omitempty"`
 }
 
+// 充电曲线采样方式
+const (
+	ChargeSamplingRaw       = "raw"       // 全部数据点
+	ChargeSamplingHeuristic = "heuristic" // 电量每变化 1% 或功率变化 3 kW 保留一个点
+	ChargeSamplingLTTB      = "lttb"      // 对每个序列分别做 Largest-Triangle-Three-Buckets 降采样
+)
+
 // ChargeStats 充电统计
 type ChargeStats struct {
 	ChargingProcessID int64             `json:"chargingProcessId"`
+	Sampling          ChargeSampling    `json:"sampling"`
 	DataPoints        []ChargeDataPoint `json:"dataPoints"`
+	// Series 各序列独立降采样的结果，仅 lttb 时返回，键为 power / voltage / current / outsideTemp
+	Series map[string][]ChargeSeriesPoint `json:"series,omitempty"`
+}
+
+// ChargeSampling 充电曲线的采样信息
+type ChargeSampling struct {
+	Method string `json:"method"`
+	// Threshold lttb 时每个序列的目标点数
+	Threshold      int `json:"threshold,omitempty"`
+	RawPoints      int `json:"rawPoints"`
+	ReturnedPoints int `json:"returnedPoints"`
+	// SeriesPoints lttb 时各序列保留的点数
+	SeriesPoints map[string]int `json:"seriesPoints,omitempty"`
+}
+
+// ChargeSeriesPoint 单个序列的数据点
+type ChargeSeriesPoint struct {
+	Date  time.Time `json:"date"`
+	Value float64   `json:"value"`
 }
 
 // ChargeDataPoint 充电数据点
@@ -137,3 +165,27 @@ type ChargeStatsSummary struct {
 	DailyStats    []DailyChargeStat    `json:"dailyStats"`
 	LocationStats []ChargeLocationStat `json:"locationStats"`
 }
+
+// ChargeExportRow 充电记录导出行，包含列表和详情中的全部字段
+type ChargeExportRow struct {
+	ID                int64
+	StartDate         time.Time
+	EndDate           *time.Time
+	DurationMin       int
+	Location          string
+	Latitude          *float64
+	Longitude         *float64
+	ChargeType        string // "AC" 或 "DC"
+	ChargeEnergyAdded float64
+	ChargeEnergyUsed  *float64
+	Efficiency        *float64 // 充电效率 (%)
+	Cost              *float64
+	StartBatteryLevel int
+	EndBatteryLevel   int
+	StartIdealRangeKm float64
+	EndIdealRangeKm   float64
+	StartRatedRangeKm float64
+	EndRatedRangeKm   float64
+	OutsideTempAvg    *float64
+	Odometer          *float64
+}
@@ -0,0 +1,53 @@
+package model
+
+import "time"
+
+// 摘要周期
+const (
+	DigestPeriodDay   = "day"
+	DigestPeriodWeek  = "week"
+	DigestPeriodMonth = "month"
+)
+
+// DigestSchedulesKey 摘要计划在 ui_settings 中的 key，值为 DigestSchedule 数组的 JSON
+const DigestSchedulesKey = "digestSchedules"
+
+// Digest 某辆车在一个周期内的行程和充电摘要
+type Digest struct {
+	CarID       int16     `json:"carId"`
+	CarName     string    `json:"carName"`
+	Period      string    `json:"period"`
+	StartDate   time.Time `json:"startDate"`
+	EndDate     time.Time `json:"endDate"`
+	GeneratedAt time.Time `json:"generatedAt"`
+	// 行程
+	Distance   float64 `json:"distance"`   // km
+	DriveCount int     `json:"driveCount"` // 行程次数
+	MaxSpeed   int     `json:"maxSpeed"`   // km/h
+	// 充电
+	EnergyAdded       float64 `json:"energyAdded"`       // kWh
+	ChargeCount       int     `json:"chargeCount"`       // 充电次数
+	ChargeDurationMin float64 `json:"chargeDurationMin"` // 分钟
+	Cost              float64 `json:"cost"`
+	// 能效，周期内没有行驶时为空
+	EnergyUsed *float64 `json:"energyUsed,omitempty"` // kWh（按续航消耗估算）
+	Efficiency *float64 `json:"efficiency,omitempty"` // Wh/km
+	// EfficiencySource 计算能耗所用能效系数的来源：car / setting / table
+	EfficiencySource string `json:"efficiencySource,omitempty"`
+	// TopChargingLocations 按充入电量排序的充电地点
+	TopChargingLocations []ChargeLocationStat `json:"topChargingLocations"`
+}
+
+// DigestSchedule 定时推送摘要的计划
+type DigestSchedule struct {
+	Name string `json:"name"`
+	// CarID 为空时为每辆车分别推送
+	CarID *int16 `json:"carId,omitempty"`
+	// Period 为 day / week / month
+	Period string `json:"period"`
+	// Cron 为 5 段 cron 表达式（分 时 日 月 周），按服务器时区（TZ）计算，
+	// 也支持 @daily、@weekly、@monthly 等简写
+	Cron       string  `json:"cron"`
+	ChannelIDs []int64 `json:"channelIds"`
+	Enabled    bool    `json:"enabled"`
+}
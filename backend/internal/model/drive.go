@@ -48,6 +48,7 @@ type DriveListItem struct {
 	StartBatteryLevel int        `json:"startBatteryLevel"`
 	EndBatteryLevel   int        `json:"endBatteryLevel"`
 	Efficiency        float64    `json:"efficiency"`
+	EfficiencySource  string     `json:"efficiencySource"` // car / setting / table
 	SpeedMax          int        `json:"speedMax"`
 }
 
@@ -69,12 +70,15 @@ type DriveDetail struct {
 	StartIdealRangeKm float64    `json:"startIdealRangeKm"`
 	EndIdealRangeKm   float64    `json:"endIdealRangeKm"`
 	Efficiency        float64    `json:"efficiency"`
+	EfficiencySource  string     `json:"efficiencySource"` // car / setting / table
 	SpeedMax          int        `json:"speedMax"`
 	SpeedAvg          float64    `json:"speedAvg"`
 	PowerMax          int        `json:"powerMax"`
 	PowerMin          int        `json:"powerMin"`
 	OutsideTempAvg    *float64   `json:"outsideTempAvg,omitempty"`
 	InsideTempAvg     *float64   `json:"insideTempAvg,omitempty"`
+	Ascent            *int       `json:"ascent,omitempty"`  // 累计爬升 (m)
+	Descent           *int       `json:"descent,omitempty"` // 累计下降 (m)
 }
 
 // DrivePosition 驾驶轨迹点
@@ -86,6 +90,7 @@ type DrivePosition struct {
 	Power        int       `json:"power"`
 	BatteryLevel int       `json:"batteryLevel"`
 	Elevation    *int      `json:"elevation,omitempty"`
+	Odometer     *float64  `json:"odometer,omitempty"` // km
 	// 温度数据
 	OutsideTemp *float64 `json:"outsideTemp,omitempty"`
 	InsideTemp  *float64 `json:"insideTemp,omitempty"`
@@ -121,3 +126,66 @@ type DriveTrack struct {
 	StartDate time.Time       `json:"startDate"`
 	Positions []DrivePosition `json:"positions"`
 }
+
+// 累计爬升/下降的数据来源
+const (
+	// ElevationSourceDrive TeslaMate 在 drives 表中记录的 ascent / descent
+	ElevationSourceDrive = "drive"
+	// ElevationSourcePositions 由轨迹点海拔计算
+	ElevationSourcePositions = "positions"
+)
+
+// DriveAnalysis 单次行程的分析：海拔剖面、能量收支、功率区间和每公里分段
+type DriveAnalysis struct {
+	DriveID     int64   `json:"driveId"`
+	Distance    float64 `json:"distance"`    // 轨迹累计距离 (km)
+	DurationSec float64 `json:"durationSec"` // 轨迹首尾时间差
+	// Ascent / Descent 累计爬升和下降 (m)，ElevationSource 为空表示没有海拔数据
+	Ascent          float64 `json:"ascent"`
+	Descent         float64 `json:"descent"`
+	ElevationSource string  `json:"elevationSource,omitempty"`
+	// ElevationProfile 按累计距离的海拔剖面，点数较多时用 LTTB 降采样
+	ElevationProfile []ElevationPoint     `json:"elevationProfile"`
+	Energy           DriveEnergy          `json:"energy"`
+	PowerBands       []DrivePowerBand     `json:"powerBands"`
+	Splits           []DriveDistanceSplit `json:"splits"`
+}
+
+// ElevationPoint 海拔剖面上的一个点
+type ElevationPoint struct {
+	Distance  float64 `json:"distance"`  // km
+	Elevation int     `json:"elevation"` // m
+}
+
+// DriveEnergy 对轨迹点功率按时间积分得到的能量 (kWh)
+type DriveEnergy struct {
+	Consumed    float64 `json:"consumed"`    // 正功率部分
+	Regenerated float64 `json:"regenerated"` // 负功率部分（动能回收），取正值
+	Net         float64 `json:"net"`         // Consumed - Regenerated
+	// Climbing 海拔上升路段消耗的能量，Descending 海拔下降路段回收的能量
+	Climbing     float64 `json:"climbing"`
+	Descending   float64 `json:"descending"`
+	RegenPercent float64 `json:"regenPercent"` // Regenerated / Consumed * 100
+}
+
+// DrivePowerBand 功率区间 [Min, Max) 内的行驶时间，Min / Max 为空表示不设下限 / 上限
+type DrivePowerBand struct {
+	Label       string   `json:"label"`
+	Min         *float64 `json:"min,omitempty"` // kW
+	Max         *float64 `json:"max,omitempty"` // kW
+	DurationSec float64  `json:"durationSec"`
+	Percent     float64  `json:"percent"`
+	Energy      float64  `json:"energy"` // kWh，回收区间为负值
+}
+
+// DriveDistanceSplit 每公里分段，最后一段可能不足 1 km
+type DriveDistanceSplit struct {
+	Index       int     `json:"index"`       // 从 1 开始
+	Distance    float64 `json:"distance"`    // km
+	DurationSec float64 `json:"durationSec"` // s
+	SpeedAvg    float64 `json:"speedAvg"`    // km/h
+	Energy      float64 `json:"energy"`      // kWh，净能耗
+	Efficiency  float64 `json:"efficiency"`  // Wh/km
+	// ElevationDelta 分段终点与起点的海拔差 (m)，缺少海拔时为空
+	ElevationDelta *int `json:"elevationDelta,omitempty"`
+}
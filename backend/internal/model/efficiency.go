@@ -0,0 +1,27 @@
+package model
+
+import "fmt"
+
+// 能效系数来源
+const (
+	// EfficiencySourceCar TeslaMate 根据充电数据为每辆车学习的 cars.efficiency
+	EfficiencySourceCar = "car"
+	// EfficiencySourceSetting ui_settings 中按车辆保存的手动能效系数
+	EfficiencySourceSetting = "setting"
+	// EfficiencySourceTable 按车型查内置能效系数表
+	EfficiencySourceTable = "table"
+)
+
+// CarEfficiencyKeyPrefix 车辆手动能效系数在 ui_settings 中的 key 前缀，完整 key 如 carEfficiency.1，值为 kWh/km
+const CarEfficiencyKeyPrefix = "carEfficiency."
+
+// CarEfficiencyKey 指定车辆手动能效系数的 ui_settings key
+func CarEfficiencyKey(carID int16) string {
+	return fmt.Sprintf("%s%d", CarEfficiencyKeyPrefix, carID)
+}
+
+// CarEfficiency 车辆能效系数 (kWh/km)，续航里程乘以该系数得到能耗
+type CarEfficiency struct {
+	Value  float64 `json:"value"`
+	Source string  `json:"source"`
+}
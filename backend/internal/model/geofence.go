@@ -0,0 +1,42 @@
+package model
+
+import "time"
+
+// 地理围栏计费方式，对应 TeslaMate 的 billing_type 枚举
+const (
+	BillingTypePerKWh    = "per_kwh"
+	BillingTypePerMinute = "per_minute"
+)
+
+// GeofenceDetail 地理围栏及其到访和充电统计
+type GeofenceDetail struct {
+	ID          int64     `json:"id"`
+	Name        string    `json:"name"`
+	Latitude    float64   `json:"latitude"`
+	Longitude   float64   `json:"longitude"`
+	Radius      int       `json:"radius"` // 半径（米）
+	BillingType string    `json:"billingType"`
+	CostPerUnit *float64  `json:"costPerUnit,omitempty"` // 每 kWh 或每分钟的价格
+	SessionFee  *float64  `json:"sessionFee,omitempty"`  // 每次充电的固定费用
+	InsertedAt  time.Time `json:"insertedAt"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+	// ArrivalCount 以该围栏为终点的行程数
+	ArrivalCount int `json:"arrivalCount"`
+	// DepartureCount 从该围栏出发的行程数
+	DepartureCount int        `json:"departureCount"`
+	ChargeCount    int        `json:"chargeCount"`
+	EnergyAdded    float64    `json:"energyAdded"`
+	ChargeCost     float64    `json:"chargeCost"`
+	LastVisit      *time.Time `json:"lastVisit,omitempty"`
+}
+
+// GeofenceInput 创建/更新地理围栏的参数
+type GeofenceInput struct {
+	Name        string   `json:"name"`
+	Latitude    float64  `json:"latitude"`
+	Longitude   float64  `json:"longitude"`
+	Radius      int      `json:"radius"`
+	BillingType string   `json:"billingType"`
+	CostPerUnit *float64 `json:"costPerUnit"`
+	SessionFee  *float64 `json:"sessionFee"`
+}
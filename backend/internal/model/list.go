@@ -0,0 +1,75 @@
+package model
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// 列表排序字段
+const (
+	SortByStartDate  = "start_date"
+	SortByDistance   = "distance"
+	SortByDuration   = "duration"
+	SortByEnergy     = "energy"
+	SortByCost       = "cost"
+	SortByEfficiency = "efficiency"
+	SortBySpeedMax   = "speed_max"
+)
+
+// 列表排序方向
+const (
+	SortAsc  = "asc"
+	SortDesc = "desc"
+)
+
+// DriveSortFields 驾驶记录列表支持的排序字段
+var DriveSortFields = []string{SortByStartDate, SortByDistance, SortByDuration, SortByEnergy, SortByEfficiency, SortBySpeedMax}
+
+// ChargeSortFields 充电记录列表支持的排序字段
+var ChargeSortFields = []string{SortByStartDate, SortByDuration, SortByEnergy, SortByCost, SortByEfficiency}
+
+// ErrInvalidCursor 游标无法解析或与排序参数不一致
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// ListQuery 列表查询参数
+// Cursor 非空时从游标位置继续读取（keyset 分页）并忽略 Page；WithTotal 为 false 时不统计总数
+type ListQuery struct {
+	Page      int
+	PageSize  int
+	Cursor    *ListCursor
+	SortBy    string
+	SortOrder string
+	WithTotal bool
+	StartDate *time.Time
+	EndDate   *time.Time
+}
+
+// ListCursor 游标分页位置，即上一页最后一条记录的排序值和 ID
+type ListCursor struct {
+	SortBy    string `json:"s"`
+	SortOrder string `json:"o"`
+	// Value 排序值的数据库文本表示，查询时转换回原类型
+	Value string `json:"v"`
+	ID    int64  `json:"id"`
+}
+
+// Encode 将游标编码为不透明字符串
+func (c *ListCursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeListCursor 解析 Encode 生成的游标字符串
+func DecodeListCursor(s string) (*ListCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var cursor ListCursor
+	if err := json.Unmarshal(data, &cursor); err != nil || cursor.SortBy == "" {
+		return nil, ErrInvalidCursor
+	}
+	return &cursor, nil
+}
@@ -0,0 +1,54 @@
+package model
+
+import "time"
+
+// VisitedPlace 按地理围栏（或无围栏时按地址）聚合的到访地点
+type VisitedPlace struct {
+	GeofenceID *int64   `json:"geofenceId,omitempty"`
+	AddressID  *int64   `json:"addressId,omitempty"`
+	Name       string   `json:"name"`
+	Address    string   `json:"address,omitempty"`
+	City       string   `json:"city,omitempty"`
+	Country    string   `json:"country,omitempty"`
+	Latitude   *float64 `json:"latitude,omitempty"`
+	Longitude  *float64 `json:"longitude,omitempty"`
+	// VisitCount 以该地点为终点的行程数
+	VisitCount int `json:"visitCount"`
+	// ChargeCount 在该地点的充电次数
+	ChargeCount int     `json:"chargeCount"`
+	EnergyAdded float64 `json:"energyAdded"`
+	// DwellMinutes 累计停留时长（到达到下一次出发），仍停留在此时不计入最后一次
+	DwellMinutes float64   `json:"dwellMinutes"`
+	FirstVisit   time.Time `json:"firstVisit"`
+	LastVisit    time.Time `json:"lastVisit"`
+}
+
+// LocationHeatmap 热力图数据
+// Points 每项为 [纬度, 经度, 权重]，权重为该网格内的分钟数
+type LocationHeatmap struct {
+	Precision int          `json:"precision"` // 坐标保留的小数位数
+	Count     int          `json:"count"`
+	Points    [][3]float64 `json:"points"`
+}
+
+// RegionStat 城市 / 省州 / 国家的到访统计
+type RegionStat struct {
+	Name    string `json:"name"`
+	State   string `json:"state,omitempty"`
+	Country string `json:"country,omitempty"`
+	// Addresses 到访过的不同地址数
+	Addresses int `json:"addresses"`
+	// Visits 行程起终点与充电的次数之和
+	Visits int `json:"visits"`
+}
+
+// RegionSummary 到访区域统计
+type RegionSummary struct {
+	AddressCount int          `json:"addressCount"`
+	CityCount    int          `json:"cityCount"`
+	StateCount   int          `json:"stateCount"`
+	CountryCount int          `json:"countryCount"`
+	Cities       []RegionStat `json:"cities"`
+	States       []RegionStat `json:"states"`
+	Countries    []RegionStat `json:"countries"`
+}
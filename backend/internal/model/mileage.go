@@ -0,0 +1,56 @@
+package model
+
+import "time"
+
+// 行程用途
+const (
+	DrivePurposeBusiness = "business"
+	DrivePurposePrivate  = "private"
+	DrivePurposeCommute  = "commute"
+	// DrivePurposeUntagged 仅用于筛选未标记的行程，不能作为标记值
+	DrivePurposeUntagged = "untagged"
+)
+
+// DriveTag 行程用途标记
+type DriveTag struct {
+	DriveID   int64     `db:"drive_id" json:"driveId"`
+	Purpose   string    `db:"purpose" json:"purpose"`
+	Note      string    `db:"note" json:"note"`
+	UpdatedAt time.Time `db:"updated_at" json:"updatedAt"`
+}
+
+// MileageLogEntry 里程日志中的一次行程
+// 地址格式为“道路 门牌号, 城市”（与 reports/dutch-tax.json 一致），Purpose 为空表示未标记
+type MileageLogEntry struct {
+	DriveID       int64     `json:"driveId"`
+	StartDate     time.Time `json:"startDate"`
+	EndDate       time.Time `json:"endDate"`
+	StartAddress  string    `json:"startAddress"`
+	EndAddress    string    `json:"endAddress"`
+	OdometerStart float64   `json:"odometerStart"`
+	OdometerEnd   float64   `json:"odometerEnd"`
+	Distance      float64   `json:"distance"`
+	DurationMin   int       `json:"durationMin"`
+	Purpose       string    `json:"purpose,omitempty"`
+	Note          string    `json:"note,omitempty"`
+}
+
+// MileagePurposeTotal 按用途汇总的行程数和里程，未标记的行程 Purpose 为空
+type MileagePurposeTotal struct {
+	Purpose  string  `json:"purpose"`
+	Count    int     `json:"count"`
+	Distance float64 `json:"distance"`
+}
+
+// MileageLog 里程日志
+type MileageLog struct {
+	CarID         int16                 `json:"carId"`
+	CarName       string                `json:"carName"`
+	StartDate     *time.Time            `json:"startDate,omitempty"`
+	EndDate       *time.Time            `json:"endDate,omitempty"`
+	Purpose       string                `json:"purpose,omitempty"` // 筛选的用途，为空表示全部
+	GeneratedAt   time.Time             `json:"generatedAt"`
+	TotalDistance float64               `json:"totalDistance"`
+	ByPurpose     []MileagePurposeTotal `json:"byPurpose"`
+	Entries       []MileageLogEntry     `json:"entries"`
+}
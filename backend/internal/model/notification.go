@@ -0,0 +1,91 @@
+package model
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// 通知渠道类型
+const (
+	NotifyTypeWebhook  = "webhook"
+	NotifyTypeNtfy     = "ntfy"
+	NotifyTypeGotify   = "gotify"
+	NotifyTypeTelegram = "telegram"
+	NotifyTypeSMTP     = "smtp"
+)
+
+// JSONObject 以 JSONB 存储的对象
+type JSONObject map[string]interface{}
+
+// Value 实现 driver.Valuer
+func (o JSONObject) Value() (driver.Value, error) {
+	if o == nil {
+		o = JSONObject{}
+	}
+	b, err := json.Marshal(o)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+// Scan 实现 sql.Scanner
+func (o *JSONObject) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*o = JSONObject{}
+		return nil
+	case []byte:
+		return json.Unmarshal(v, o)
+	case string:
+		return json.Unmarshal([]byte(v), o)
+	default:
+		return fmt.Errorf("cannot scan %T into JSONObject", src)
+	}
+}
+
+// IDList 以 JSONB 数组存储的 ID 列表
+type IDList []int64
+
+// Value 实现 driver.Valuer
+func (l IDList) Value() (driver.Value, error) {
+	if l == nil {
+		l = IDList{}
+	}
+	b, err := json.Marshal(l)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+// Scan 实现 sql.Scanner
+func (l *IDList) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*l = IDList{}
+		return nil
+	case []byte:
+		return json.Unmarshal(v, l)
+	case string:
+		return json.Unmarshal([]byte(v), l)
+	default:
+		return fmt.Errorf("cannot scan %T into IDList", src)
+	}
+}
+
+// NotificationChannel 通知渠道
+// Config 的结构取决于 Type；TitleTemplate / BodyTemplate 为 Go text/template 模板，留空时使用原始标题和正文
+type NotificationChannel struct {
+	ID            int64      `db:"id" json:"id"`
+	Name          string     `db:"name" json:"name"`
+	Type          string     `db:"type" json:"type"`
+	Config        JSONObject `db:"config" json:"config"`
+	TitleTemplate string     `db:"title_template" json:"titleTemplate"`
+	BodyTemplate  string     `db:"body_template" json:"bodyTemplate"`
+	Enabled       bool       `db:"enabled" json:"enabled"`
+	CreatedAt     time.Time  `db:"created_at" json:"createdAt"`
+	UpdatedAt     time.Time  `db:"updated_at" json:"updatedAt"`
+}
@@ -1,5 +1,7 @@
 package model
 
+import "time"
+
 // OverviewStats 概览统计
 type OverviewStats struct {
 	TotalDistance       float64  `json:"totalDistance"`
@@ -10,6 +12,7 @@ type OverviewStats struct {
 	TotalDriveDuration  int      `json:"totalDriveDuration"`
 	TotalChargeDuration int      `json:"totalChargeDuration"`
 	AvgEfficiency       float64  `json:"avgEfficiency"`
+	EfficiencySource    string   `json:"efficiencySource"` // car / setting / table
 	CurrentOdometer     float64  `json:"currentOdometer"`
 	// 温度信息
 	OutsideTemp *float64 `json:"outsideTemp,omitempty"`
@@ -34,6 +37,8 @@ type EfficiencyStats struct {
 	Daily   []EfficiencyDataPoint `json:"daily"`
 	Weekly  []EfficiencyDataPoint `json:"weekly"`
 	Monthly []EfficiencyDataPoint `json:"monthly"`
+	// EfficiencySource 计算能耗所用能效系数的来源：car / setting / table
+	EfficiencySource string `json:"efficiencySource"`
 }
 
 // EfficiencyDataPoint 能效数据点
@@ -50,6 +55,8 @@ type BatteryStats struct {
 	CurrentCapacity    float64            `json:"currentCapacity"`
 	OriginalCapacity   float64            `json:"originalCapacity"`
 	DegradationPercent float64            `json:"degradationPercent"`
+	// EfficiencySource 估算容量所用能效系数的来源：car / setting / table
+	EfficiencySource string `json:"efficiencySource"`
 }
 
 // BatteryDataPoint 电池数据点
@@ -76,9 +83,10 @@ type StateTimelineItem struct {
 
 // Pagination 分页参数
 type Pagination struct {
-	Page     int `json:"page"`
-	PageSize int `json:"pageSize"`
-	Total    int `json:"total"`
+	Page       int    `json:"page"`
+	PageSize   int    `json:"pageSize"`
+	Total      *int   `json:"total,omitempty"`      // 请求 withTotal=false 时不统计
+	NextCursor string `json:"nextCursor,omitempty"` // 下一页游标，没有更多记录时为空
 }
 
 // ListResponse 列表响应
@@ -86,3 +94,204 @@ type ListResponse[T any] struct {
 	Items      []T        `json:"items"`
 	Pagination Pagination `json:"pagination"`
 }
+
+// VampireDrainStats 停放耗电（vampire drain）统计
+type VampireDrainStats struct {
+	Periods    []VampireDrainPeriod   `json:"periods"`
+	Daily      []VampireDrainTotal    `json:"daily"`
+	ByGeofence []VampireDrainGeofence `json:"byGeofence"`
+	Total      VampireDrainTotal      `json:"total"`
+	// EfficiencySource 估算损失电量所用能效系数的来源：car / setting / table
+	EfficiencySource string `json:"efficiencySource"`
+}
+
+// VampireDrainPeriod 两次行程/充电之间的一段停放
+type VampireDrainPeriod struct {
+	StartDate   time.Time `json:"startDate"`
+	EndDate     time.Time `json:"endDate"`
+	DurationMin int       `json:"durationMin"`
+	StartSoc    int       `json:"startSoc"`
+	EndSoc      int       `json:"endSoc"`
+	SocLost     int       `json:"socLost"` // 百分点
+	// 低温等原因导致可用电量低于显示电量时，续航和能耗数据不可靠，以下四项为空
+	ReducedRange     bool     `json:"reducedRange"`
+	RangeLost        *float64 `json:"rangeLost,omitempty"`        // km
+	EnergyLost       *float64 `json:"energyLost,omitempty"`       // kWh
+	AvgPower         *float64 `json:"avgPower,omitempty"`         // W
+	RangeLostPerHour *float64 `json:"rangeLostPerHour,omitempty"` // km/h
+	// 状态占比（0-100），来自 states 表，其余时间为在线
+	AsleepPercent  float64 `json:"asleepPercent"`
+	OfflinePercent float64 `json:"offlinePercent"`
+	OnlinePercent  float64 `json:"onlinePercent"`
+	// 停放地点
+	GeofenceID *int64 `json:"geofenceId,omitempty"`
+	Location   string `json:"location"`
+}
+
+// VampireDrainTotal 停放耗电汇总，按日汇总时 Date 为 YYYY-MM-DD
+// 跨天的停放按时长比例分摊到各天
+type VampireDrainTotal struct {
+	Date        string  `json:"date,omitempty"`
+	Periods     int     `json:"periods"`
+	DurationMin float64 `json:"durationMin"`
+	SocLost     float64 `json:"socLost"`
+	RangeLost   float64 `json:"rangeLost"`  // km
+	EnergyLost  float64 `json:"energyLost"` // kWh
+	AvgPower    float64 `json:"avgPower"`   // W，仅统计有能耗数据的停放
+	// AsleepPercent 休眠（含离线）时间占比
+	AsleepPercent float64 `json:"asleepPercent"`
+}
+
+// VampireDrainGeofence 按地理围栏汇总的停放耗电，不在任何围栏内的停放 GeofenceID 为空
+type VampireDrainGeofence struct {
+	GeofenceID *int64 `json:"geofenceId,omitempty"`
+	Name       string `json:"name"`
+	VampireDrainTotal
+}
+
+// ProjectedRangeStats 预估满电续航历史
+type ProjectedRangeStats struct {
+	// PreferredRange 使用的续航类型（ideal / rated），来自 TeslaMate 设置
+	PreferredRange string `json:"preferredRange"`
+	// XAxis 为 time（按 Interval 分组）或 mileage（按 BucketKm 里程分组）
+	XAxis    string                `json:"xAxis"`
+	Interval string                `json:"interval,omitempty"`
+	BucketKm float64               `json:"bucketKm,omitempty"`
+	Points   []ProjectedRangePoint `json:"points"`
+}
+
+// ProjectedRangePoint 预估满电续航数据点
+type ProjectedRangePoint struct {
+	// Date 按时间分组时为分组起始时间，按里程分组时为该组第一个样本的时间
+	Date           time.Time `json:"date"`
+	Mileage        *float64  `json:"mileage,omitempty"` // 平均里程 (km)
+	ProjectedRange float64   `json:"projectedRange"`    // 按可用电量折算的 100% 续航 (km)
+	// 平均电量和可用电量 (%)
+	BatteryLevel       float64  `json:"batteryLevel"`
+	UsableBatteryLevel float64  `json:"usableBatteryLevel"`
+	OutsideTemp        *float64 `json:"outsideTemp,omitempty"`
+	Samples            int      `json:"samples"`
+}
+
+// BatteryHealthStats 电池健康报告
+type BatteryHealthStats struct {
+	// NominalCapacity 请求中指定的新车可用容量 (kWh)，为空时以拟合的初始容量为基准
+	NominalCapacity *float64 `json:"nominalCapacity,omitempty"`
+	// InitialCapacity 计算衰减使用的基准容量 (kWh)
+	InitialCapacity float64 `json:"initialCapacity"`
+	// CurrentCapacity 按里程趋势线估算的当前容量及其 95% 置信区间 (kWh)
+	CurrentCapacity      float64 `json:"currentCapacity"`
+	CurrentCapacityLower float64 `json:"currentCapacityLower"`
+	CurrentCapacityUpper float64 `json:"currentCapacityUpper"`
+	// DegradationPercent 衰减百分比及其 95% 置信区间，均限制在 0-100
+	DegradationPercent      float64 `json:"degradationPercent"`
+	DegradationPercentLower float64 `json:"degradationPercentLower"`
+	DegradationPercentUpper float64 `json:"degradationPercentUpper"`
+	HealthPercent           float64 `json:"healthPercent"`
+	Odometer                float64 `json:"odometer"` // 最近一次有效充电时的里程 (km)
+	AgeDays                 float64 `json:"ageDays"`  // 最近一次有效充电距首条记录的天数
+	SampleCount             int     `json:"sampleCount"`
+	OutlierCount            int     `json:"outlierCount"`
+	// ByOdometer / ByAge 容量相对里程 (km) 和车龄 (天) 的趋势，样本不足时为空
+	ByOdometer *CapacityTrend          `json:"byOdometer,omitempty"`
+	ByAge      *CapacityTrend          `json:"byAge,omitempty"`
+	Samples    []BatteryCapacitySample `json:"samples"`
+}
+
+// BatteryCapacitySample 由单次充电估算的电池容量
+type BatteryCapacitySample struct {
+	ChargeID    int64     `json:"chargeId"`
+	Date        time.Time `json:"date"`
+	Odometer    *float64  `json:"odometer,omitempty"` // km
+	AgeDays     float64   `json:"ageDays"`
+	EnergyAdded float64   `json:"energyAdded"` // kWh
+	StartSoc    int       `json:"startSoc"`
+	EndSoc      int       `json:"endSoc"`
+	// Capacity = EnergyAdded / (EndSoc - StartSoc) * 100
+	Capacity float64 `json:"capacity"`
+	// Fitted 里程趋势线在该点的值，Outlier 表示该样本未参与拟合
+	Fitted  *float64 `json:"fitted,omitempty"`
+	Outlier bool     `json:"outlier"`
+}
+
+// CapacityTrend 容量线性趋势 capacity = intercept + slope * x
+type CapacityTrend struct {
+	Slope     float64 `json:"slope"`
+	Intercept float64 `json:"intercept"`
+	// SlopeLower / SlopeUpper 斜率的 95% 置信区间
+	SlopeLower     float64 `json:"slopeLower"`
+	SlopeUpper     float64 `json:"slopeUpper"`
+	RSquared       float64 `json:"rSquared"`
+	ResidualStdDev float64 `json:"residualStdDev"`
+	N              int     `json:"n"`
+	// Band 在样本范围内等距取点的拟合值和 95% 置信区间
+	Band []TrendBandPoint `json:"band"`
+}
+
+// TrendBandPoint 趋势线上的一个点
+type TrendBandPoint struct {
+	X      float64 `json:"x"`
+	Fitted float64 `json:"fitted"`
+	Lower  float64 `json:"lower"`
+	Upper  float64 `json:"upper"`
+}
+
+// EfficiencyFactorSteps 能耗因素分析各维度的分组宽度
+type EfficiencyFactorSteps struct {
+	Temperature float64 `json:"temperature"` // °C
+	Speed       float64 `json:"speed"`       // km/h
+	Elevation   float64 `json:"elevation"`   // 每公里爬升米数
+}
+
+// EfficiencySample 用于能耗因素分析的单次行程
+type EfficiencySample struct {
+	DriveID     int64    `json:"driveId"`
+	Distance    float64  `json:"distance"`              // km
+	EnergyUsed  float64  `json:"energyUsed"`            // kWh，由续航消耗乘以车辆能效系数得到
+	OutsideTemp *float64 `json:"outsideTemp,omitempty"` // 行程平均车外温度 °C
+	SpeedAvg    *float64 `json:"speedAvg,omitempty"`    // km/h
+	// ElevationGain 行程累计爬升 (m)，轨迹点海拔不足两个时为空
+	ElevationGain *float64 `json:"elevationGain,omitempty"`
+	// ClimateOnRatio 空调开启的轨迹点占比，没有空调状态时为空
+	ClimateOnRatio *float64 `json:"climateOnRatio,omitempty"`
+}
+
+// EfficiencySamples 能耗因素分析的样本集合
+type EfficiencySamples struct {
+	PreferredRange   string
+	EfficiencySource string
+	Samples          []EfficiencySample
+}
+
+// EnergyUsage 时间范围内有续航记录的行程的总里程和估算能耗
+type EnergyUsage struct {
+	Distance         float64 // km
+	EnergyUsed       float64 // kWh，续航消耗乘以车辆能效系数
+	EfficiencySource string
+}
+
+// EfficiencyFactors 按车外温度、平均速度、每公里爬升和空调状态分组的能耗
+type EfficiencyFactors struct {
+	// PreferredRange 计算续航消耗使用的续航类型（ideal / rated），来自 TeslaMate 设置
+	PreferredRange   string                `json:"preferredRange"`
+	EfficiencySource string                `json:"efficiencySource"` // car / setting / table
+	Steps            EfficiencyFactorSteps `json:"steps"`
+	// Overall 全部样本的汇总
+	Overall     EfficiencyBucket   `json:"overall"`
+	Temperature []EfficiencyBucket `json:"temperature"`
+	Speed       []EfficiencyBucket `json:"speed"`
+	Elevation   []EfficiencyBucket `json:"elevation"`
+	Climate     []EfficiencyBucket `json:"climate"`
+}
+
+// EfficiencyBucket 一个分组的能耗，Efficiency 为按距离加权的 Wh/km
+type EfficiencyBucket struct {
+	Label string `json:"label"`
+	// Min / Max 分组区间 [Min, Max)，空调分组没有区间
+	Min        *float64 `json:"min,omitempty"`
+	Max        *float64 `json:"max,omitempty"`
+	Samples    int      `json:"samples"`
+	Distance   float64  `json:"distance"`   // km
+	EnergyUsed float64  `json:"energyUsed"` // kWh
+	Efficiency float64  `json:"efficiency"` // Wh/km
+}
@@ -0,0 +1,140 @@
+package model
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// TariffPeriod 分时电价时段
+// Start / End 为 HH:MM（按方案的 Timezone），允许跨越午夜，例如 22:00-06:00；
+// Weekdays 为生效的星期（0 为周日），为空表示每天
+type TariffPeriod struct {
+	Name        string  `json:"name"`
+	Start       string  `json:"start"`
+	End         string  `json:"end"`
+	Weekdays    []int   `json:"weekdays,omitempty"`
+	PricePerKWh float64 `json:"pricePerKwh"`
+}
+
+// TariffPeriods 以 JSONB 存储的时段列表，按顺序匹配，先匹配的优先
+type TariffPeriods []TariffPeriod
+
+// Value 实现 driver.Valuer
+func (p TariffPeriods) Value() (driver.Value, error) {
+	if p == nil {
+		p = TariffPeriods{}
+	}
+	b, err := json.Marshal(p)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+// Scan 实现 sql.Scanner
+func (p *TariffPeriods) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*p = TariffPeriods{}
+		return nil
+	case []byte:
+		return json.Unmarshal(v, p)
+	case string:
+		return json.Unmarshal([]byte(v), p)
+	default:
+		return fmt.Errorf("cannot scan %T into TariffPeriods", src)
+	}
+}
+
+// Tariff 电价方案
+// GeofenceID 为空表示默认方案，用于没有专属方案的地点；PricePerKWh 为不在任何时段内的基础电价；
+// ValidFrom / ValidUntil 限定方案的生效区间（按充电开始时间判断），为空表示不限；
+// Timezone 为时段使用的 IANA 时区（如 Europe/Berlin），为空时使用服务器时区（TZ 环境变量）
+type Tariff struct {
+	ID          int64         `db:"id" json:"id"`
+	Name        string        `db:"name" json:"name"`
+	GeofenceID  *int64        `db:"geofence_id" json:"geofenceId"`
+	Currency    string        `db:"currency" json:"currency"`
+	PricePerKWh float64       `db:"price_per_kwh" json:"pricePerKwh"`
+	SessionFee  float64       `db:"session_fee" json:"sessionFee"`
+	Periods     TariffPeriods `db:"periods" json:"periods"`
+	Timezone    string        `db:"timezone" json:"timezone"`
+	ValidFrom   *time.Time    `db:"valid_from" json:"validFrom"`
+	ValidUntil  *time.Time    `db:"valid_until" json:"validUntil"`
+	Enabled     bool          `db:"enabled" json:"enabled"`
+	CreatedAt   time.Time     `db:"created_at" json:"createdAt"`
+	UpdatedAt   time.Time     `db:"updated_at" json:"updatedAt"`
+}
+
+// ChargeBilling 计算充电费用所需的充电记录信息
+type ChargeBilling struct {
+	ID          int64
+	CarID       int16
+	StartDate   time.Time
+	EndDate     time.Time
+	GeofenceID  *int64
+	Location    string
+	EnergyAdded float64
+	EnergyUsed  *float64
+	Cost        *float64
+}
+
+// ChargeEnergySample 充电过程中的累计充入电量采样
+type ChargeEnergySample struct {
+	Date        time.Time
+	EnergyAdded float64
+}
+
+// ChargeCostBreakdown 按电价时段拆分的电量和费用
+type ChargeCostBreakdown struct {
+	Period      string  `json:"period"`
+	PricePerKWh float64 `json:"pricePerKwh"`
+	Energy      float64 `json:"energy"`
+	Cost        float64 `json:"cost"`
+}
+
+// 未计算或未写回费用的原因
+const (
+	CostSkipNoTariff   = "no_tariff"
+	CostSkipHasCost    = "has_cost"
+	CostSkipUnchanged  = "unchanged"
+	CostSkipNoEnergy   = "no_energy"
+	CostSkipWriteError = "write_error"
+)
+
+// ChargeCostEstimate 单次充电按电价方案计算的费用
+// EnergyBilled 为计费电量，取从电网取电量和充入电量中的较大值（与 TeslaMate 一致）
+type ChargeCostEstimate struct {
+	ChargeID     int64                 `json:"chargeId"`
+	StartDate    time.Time             `json:"startDate"`
+	EndDate      time.Time             `json:"endDate"`
+	Location     string                `json:"location"`
+	GeofenceID   *int64                `json:"geofenceId,omitempty"`
+	TariffID     *int64                `json:"tariffId,omitempty"`
+	TariffName   string                `json:"tariffName,omitempty"`
+	Currency     string                `json:"currency,omitempty"`
+	EnergyBilled float64               `json:"energyBilled"`
+	SessionFee   float64               `json:"sessionFee"`
+	CurrentCost  *float64              `json:"currentCost,omitempty"`
+	Cost         *float64              `json:"cost,omitempty"`
+	Breakdown    []ChargeCostBreakdown `json:"breakdown"`
+	// Updated 是否已写回 charging_processes.cost
+	Updated bool `json:"updated"`
+	// Skipped 未计算或未写回的原因
+	Skipped string `json:"skipped,omitempty"`
+}
+
+// ChargeCostRecalculation 批量计算（或写回）充电费用的结果
+type ChargeCostRecalculation struct {
+	StartDate    *time.Time `json:"startDate,omitempty"`
+	EndDate      *time.Time `json:"endDate,omitempty"`
+	Overwrite    bool       `json:"overwrite"`
+	Applied      bool       `json:"applied"`
+	Count        int        `json:"count"`
+	UpdatedCount int        `json:"updatedCount"`
+	// Totals 按币种汇总的计算费用
+	Totals map[string]float64   `json:"totals"`
+	Items  []ChargeCostEstimate `json:"items"`
+}
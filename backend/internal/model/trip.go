@@ -0,0 +1,87 @@
+package model
+
+import "time"
+
+// 旅程时间线条目类型
+const (
+	TripItemDrive  = "drive"
+	TripItemCharge = "charge"
+	TripItemPark   = "park"
+)
+
+// TripActivity 参与旅程分组的一段活动（一次行程或一次充电）
+type TripActivity struct {
+	Type      string // drive / charge
+	ID        int64
+	CarID     int16
+	StartDate time.Time
+	EndDate   time.Time
+	// 充电的起止地点相同
+	StartLocation   string
+	EndLocation     string
+	StartGeofenceID *int64
+	EndGeofenceID   *int64
+	// Latitude / Longitude 行程终点或充电地点
+	Latitude          *float64
+	Longitude         *float64
+	Distance          float64 // km
+	DurationMin       int
+	RangeUsed         float64 // km，按 TeslaMate 设置使用 ideal / rated 续航
+	EnergyAdded       float64 // kWh
+	Cost              *float64
+	StartBatteryLevel *int
+	EndBatteryLevel   *int
+}
+
+// Trip 由连续的行程和充电合并而成的旅程
+type Trip struct {
+	// ID 旅程中第一次行程的 ID
+	ID                int64     `json:"id"`
+	StartDate         time.Time `json:"startDate"`
+	EndDate           time.Time `json:"endDate"`
+	StartLocation     string    `json:"startLocation"`
+	EndLocation       string    `json:"endLocation"`
+	Distance          float64   `json:"distance"`    // km
+	DurationMin       int       `json:"durationMin"` // 第一段活动开始到最后一段活动结束
+	DriveDurationMin  int       `json:"driveDurationMin"`
+	ChargeDurationMin int       `json:"chargeDurationMin"`
+	ParkDurationMin   int       `json:"parkDurationMin"` // 既没有行驶也没有充电的停留时间
+	DriveCount        int       `json:"driveCount"`
+	ChargeCount       int       `json:"chargeCount"`
+	EnergyUsed        float64   `json:"energyUsed"`     // kWh，续航消耗乘以车辆能效系数
+	EnergyAdded       float64   `json:"energyAdded"`    // kWh
+	Cost              *float64  `json:"cost,omitempty"` // 有费用记录的充电费用之和
+	Efficiency        float64   `json:"efficiency"`     // Wh/km
+	EfficiencySource  string    `json:"efficiencySource"`
+	StartBatteryLevel *int      `json:"startBatteryLevel,omitempty"`
+	EndBatteryLevel   *int      `json:"endBatteryLevel,omitempty"`
+}
+
+// TripDetail 旅程详情，包含时间线和合并后的轨迹
+type TripDetail struct {
+	Trip
+	Timeline []TripTimelineItem `json:"timeline"`
+	// Track 旅程中所有行程的轨迹点，按时间排序并按行程抽样
+	Track []DrivePosition `json:"track"`
+}
+
+// TripTimelineItem 旅程时间线中的一段行驶、充电或停留
+type TripTimelineItem struct {
+	Type        string    `json:"type"`
+	ID          int64     `json:"id,omitempty"` // 行程或充电 ID，停留没有 ID
+	StartDate   time.Time `json:"startDate"`
+	EndDate     time.Time `json:"endDate"`
+	DurationMin int       `json:"durationMin"`
+	// Location 充电或停留地点，StartLocation / EndLocation 为行驶的起止地点
+	Location          string   `json:"location,omitempty"`
+	StartLocation     string   `json:"startLocation,omitempty"`
+	EndLocation       string   `json:"endLocation,omitempty"`
+	Latitude          *float64 `json:"latitude,omitempty"`
+	Longitude         *float64 `json:"longitude,omitempty"`
+	Distance          float64  `json:"distance,omitempty"`
+	EnergyUsed        float64  `json:"energyUsed,omitempty"`
+	EnergyAdded       float64  `json:"energyAdded,omitempty"`
+	Cost              *float64 `json:"cost,omitempty"`
+	StartBatteryLevel *int     `json:"startBatteryLevel,omitempty"`
+	EndBatteryLevel   *int     `json:"endBatteryLevel,omitempty"`
+}
@@ -0,0 +1,51 @@
+package model
+
+import "time"
+
+// UpdateHistory 软件更新历史
+type UpdateHistory struct {
+	Count int `json:"count"`
+	// MedianDaysBetween 相邻两次更新间隔天数的中位数，少于两次更新时为空
+	MedianDaysBetween *float64         `json:"medianDaysBetween,omitempty"`
+	Items             []SoftwareUpdate `json:"items"`
+	Versions          []VersionStats   `json:"versions"`
+	// EfficiencySource 计算能耗所用能效系数的来源：car / setting / table
+	EfficiencySource string `json:"efficiencySource"`
+}
+
+// SoftwareUpdate 单次软件更新以及该版本生效期间的行驶统计
+type SoftwareUpdate struct {
+	ID          int64      `json:"id"`
+	Version     string     `json:"version"`     // 版本号，如 2024.8.7
+	FullVersion string     `json:"fullVersion"` // TeslaMate 记录的完整版本字符串
+	StartDate   time.Time  `json:"startDate"`
+	EndDate     *time.Time `json:"endDate,omitempty"`
+	// InstallDurationMin 安装耗时（分钟），更新未完成时为空
+	InstallDurationMin *float64 `json:"installDurationMin,omitempty"`
+	// SinceLastUpdateDays 距上一次更新的天数，第一条记录为空
+	SinceLastUpdateDays *float64 `json:"sinceLastUpdateDays,omitempty"`
+	// ActiveUntil 下一次更新开始的时间，当前版本为空
+	ActiveUntil *time.Time `json:"activeUntil,omitempty"`
+	ActiveDays  float64    `json:"activeDays"`
+	VersionUsage
+}
+
+// VersionUsage 某个版本生效期间的行驶和充电统计
+type VersionUsage struct {
+	DriveCount  int     `json:"driveCount"`
+	Distance    float64 `json:"distance"`   // km
+	EnergyUsed  float64 `json:"energyUsed"` // kWh（按续航消耗估算）
+	ChargeCount int     `json:"chargeCount"`
+	EnergyAdded float64 `json:"energyAdded"` // kWh
+	// Efficiency 平均能耗 (Wh/km)，没有行驶时为空
+	Efficiency *float64 `json:"efficiency,omitempty"`
+}
+
+// VersionStats 按版本号汇总的统计（同一版本多次安装时合并）
+type VersionStats struct {
+	Version    string    `json:"version"`
+	FirstSeen  time.Time `json:"firstSeen"`
+	ActiveDays float64   `json:"activeDays"`
+	Installs   int       `json:"installs"`
+	VersionUsage
+}
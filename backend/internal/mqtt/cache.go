@@ -2,33 +2,109 @@ package mqtt
 
 import (
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
 // Cache 存储特斯拉车辆的实时状态，通过 car_id 索引，再通过 topic 索引对应的值
+// 同时为每个指标保留一段有限长度的历史，供前端绘制迷你趋势图
 type Cache struct {
-	mu   sync.RWMutex
-	data map[int16]map[string]string
+	mu          sync.RWMutex
+	data        map[int16]map[string]Entry
+	history     map[int16]map[string]*ring
+	historySize int
+
+	// 订阅者集合，单独加锁，订阅和取消订阅不阻塞数据读写
+	subMu       sync.RWMutex
+	subscribers map[*Subscriber]struct{}
+}
+
+// Update 缓存变更事件
+type Update struct {
+	CarID int16  `json:"carId"`
+	Topic string `json:"topic"`
+	Entry
+}
+
+// Subscriber 缓存变更的订阅者
+// 每个订阅者拥有独立的缓冲通道，消费过慢时丢弃新事件并标记 lagged，
+// 由消费方自行决定是否重新拉取完整快照，保证 MQTT 回调永不阻塞
+type Subscriber struct {
+	carID  int16
+	all    bool
+	ch     chan Update
+	lagged atomic.Bool
 }
 
-// NewCache 创建一个新的缓存实例
-func NewCache() *Cache {
+// Updates 返回变更事件通道，取消订阅后通道会被关闭
+func (s *Subscriber) Updates() <-chan Update {
+	return s.ch
+}
+
+// Lagged 返回自上次调用以来是否因缓冲区已满而丢弃过事件，并重置该标记
+func (s *Subscriber) Lagged() bool {
+	return s.lagged.Swap(false)
+}
+
+// NewCache 创建一个新的缓存实例，historySize 为每个指标保留的历史值数量
+func NewCache(historySize int) *Cache {
+	if historySize < 0 {
+		historySize = 0
+	}
 	return &Cache{
-		data: make(map[int16]map[string]string),
+		data:        make(map[int16]map[string]Entry),
+		history:     make(map[int16]map[string]*ring),
+		historySize: historySize,
+		subscribers: make(map[*Subscriber]struct{}),
 	}
 }
 
-// Set 存入或更新特定车辆特定的 topic 数据
+// Set 存入或更新特定车辆特定的 topic 数据，记录接收时间和解析后的类型，
+// 追加到该指标的历史中；值发生变化时通知订阅者
 func (c *Cache) Set(carID int16, topic string, value string) {
+	c.set(carID, topic, value, false)
+}
+
+// SetRetained 存入订阅时 broker 下发的保留消息。每次（重新）连接都会收到全部保留消息，
+// 其发布时间可能早在数天前，因此接收时间不能代表数据时间：
+// 值与缓存中相同时保持原条目（包括 UpdatedAt）不变；不同时标记为 Retained，且不追加到历史
+func (c *Cache) SetRetained(carID int16, topic string, value string) {
+	c.set(carID, topic, value, true)
+}
+
+func (c *Cache) set(carID int16, topic string, value string, retained bool) {
+	entry := newEntry(value, time.Now().UTC())
+	entry.Retained = retained
+
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
 	if _, ok := c.data[carID]; !ok {
-		c.data[carID] = make(map[string]string)
+		c.data[carID] = make(map[string]Entry)
+		c.history[carID] = make(map[string]*ring)
+	}
+	old, existed := c.data[carID][topic]
+	if retained && existed && old.Raw == value {
+		return
+	}
+	c.data[carID][topic] = entry
+	if c.historySize > 0 && !retained {
+		h, ok := c.history[carID][topic]
+		if !ok {
+			h = newRing(c.historySize)
+			c.history[carID][topic] = h
+		}
+		h.push(Sample{Time: entry.UpdatedAt, Value: entry.Value})
 	}
-	c.data[carID][topic] = value
 
 	// 打印 cache 中的数据更新
 	// logger.Infof("Cache updated: CarID=%d, Topic=%s, Value=%s", carID, topic, value)
+
+	if existed && old.Raw == value {
+		return
+	}
+	// 持有写锁时通知（发送不阻塞），保证订阅者收到事件的顺序与写入缓存的顺序一致
+	c.notify(Update{CarID: carID, Topic: topic, Entry: entry})
 }
 
 // Get 获取特定车辆特定的 topic 数据
@@ -40,8 +116,21 @@ func (c *Cache) Get(carID int16, topic string) (string, bool) {
 	if !ok {
 		return "", false
 	}
-	val, ok := carData[topic]
-	return val, ok
+	entry, ok := carData[topic]
+	return entry.Raw, ok
+}
+
+// GetEntry 获取特定车辆特定 topic 的带类型数据及其接收时间
+func (c *Cache) GetEntry(carID int16, topic string) (Entry, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	carData, ok := c.data[carID]
+	if !ok {
+		return Entry{}, false
+	}
+	entry, ok := carData[topic]
+	return entry, ok
 }
 
 // GetAllForCar 获取特定车辆的所有缓存数据
@@ -56,8 +145,117 @@ func (c *Cache) GetAllForCar(carID int16) map[string]string {
 
 	// 浅拷贝返回，防止外部修改 maps 导致并发问题
 	result := make(map[string]string, len(carData))
+	for k, v := range carData {
+		result[k] = v.Raw
+	}
+	return result
+}
+
+// GetEntriesForCar 获取特定车辆所有指标的带类型数据
+func (c *Cache) GetEntriesForCar(carID int16) map[string]Entry {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	carData, ok := c.data[carID]
+	if !ok {
+		return nil
+	}
+
+	result := make(map[string]Entry, len(carData))
 	for k, v := range carData {
 		result[k] = v
 	}
 	return result
 }
+
+// CarIDs 返回缓存中已有数据的车辆 ID
+func (c *Cache) CarIDs() []int16 {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	ids := make([]int16, 0, len(c.data))
+	for id := range c.data {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// DeleteCar 删除车辆的全部缓存数据和历史（车辆已从 TeslaMate 中移除时调用）
+func (c *Cache) DeleteCar(carID int16) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.data, carID)
+	delete(c.history, carID)
+}
+
+// History 获取特定车辆某个指标最近的 limit 个历史值（按时间升序，limit <= 0 表示全部）
+func (c *Cache) History(carID int16, topic string, limit int) []Sample {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	h, ok := c.history[carID][topic]
+	if !ok {
+		return []Sample{}
+	}
+	return h.snapshot(limit)
+}
+
+// HistorySize 返回每个指标保留的历史值数量
+func (c *Cache) HistorySize() int {
+	return c.historySize
+}
+
+// Subscribe 订阅特定车辆的缓存变更，buffer 为该订阅者的缓冲区大小
+// 使用完毕后必须调用 Unsubscribe 释放
+func (c *Cache) Subscribe(carID int16, buffer int) *Subscriber {
+	return c.addSubscriber(&Subscriber{carID: carID}, buffer)
+}
+
+// SubscribeAll 订阅所有车辆的缓存变更，用于多车复用的场景
+func (c *Cache) SubscribeAll(buffer int) *Subscriber {
+	return c.addSubscriber(&Subscriber{all: true}, buffer)
+}
+
+// addSubscriber 初始化订阅者的缓冲通道并登记
+func (c *Cache) addSubscriber(sub *Subscriber, buffer int) *Subscriber {
+	if buffer < 1 {
+		buffer = 1
+	}
+	sub.ch = make(chan Update, buffer)
+
+	c.subMu.Lock()
+	c.subscribers[sub] = struct{}{}
+	c.subMu.Unlock()
+	return sub
+}
+
+// Unsubscribe 取消订阅并关闭其事件通道，可重复调用
+func (c *Cache) Unsubscribe(sub *Subscriber) {
+	c.subMu.Lock()
+	defer c.subMu.Unlock()
+
+	if _, ok := c.subscribers[sub]; !ok {
+		return
+	}
+	delete(c.subscribers, sub)
+	close(sub.ch)
+}
+
+// notify 将变更事件分发给所有匹配的订阅者（非阻塞），调用方需持有 c.mu 写锁
+// 持有 subMu 读锁期间发送，保证不会向已关闭的通道写入
+func (c *Cache) notify(u Update) {
+	c.subMu.RLock()
+	defer c.subMu.RUnlock()
+
+	for sub := range c.subscribers {
+		if !sub.all && sub.carID != u.CarID {
+			continue
+		}
+		select {
+		case sub.ch <- u:
+		default:
+			sub.lagged.Store(true)
+		}
+	}
+}
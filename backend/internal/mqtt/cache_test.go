@@ -0,0 +1,64 @@
+package mqtt
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestCacheNotifiesChangesOnly(t *testing.T) {
+	c := NewCache(10)
+	sub := c.Subscribe(1, 10)
+	defer c.Unsubscribe(sub)
+	other := c.Subscribe(2, 10)
+	defer c.Unsubscribe(other)
+
+	c.Set(1, "battery_level", "80")
+	c.Set(1, "battery_level", "80")
+	c.SetRetained(1, "battery_level", "80")
+	c.Set(1, "battery_level", "79")
+
+	var got []string
+	for len(sub.Updates()) > 0 {
+		got = append(got, (<-sub.Updates()).Raw)
+	}
+	if fmt.Sprint(got) != "[80 79]" {
+		t.Errorf("updates = %v, want [80 79]", got)
+	}
+	if len(other.Updates()) != 0 {
+		t.Errorf("subscriber for another car received %d updates", len(other.Updates()))
+	}
+}
+
+func TestCacheConcurrentSetDeliversInCacheOrder(t *testing.T) {
+	const writers, writes = 8, 200
+	for round := 0; round < 20; round++ {
+		c := NewCache(0)
+		sub := c.Subscribe(1, writers*writes)
+
+		var wg sync.WaitGroup
+		for w := 0; w < writers; w++ {
+			wg.Add(1)
+			go func(w int) {
+				defer wg.Done()
+				for i := 0; i < writes; i++ {
+					c.Set(1, "speed", fmt.Sprintf("%d-%d", w, i))
+				}
+			}(w)
+		}
+		wg.Wait()
+
+		// 最后收到的事件必须与缓存中的最终值一致，否则前端会停留在旧值
+		var last string
+		for len(sub.Updates()) > 0 {
+			last = (<-sub.Updates()).Raw
+		}
+		c.Unsubscribe(sub)
+		if sub.Lagged() {
+			t.Fatal("subscriber lagged; buffer too small for the test")
+		}
+		if cached, _ := c.Get(1, "speed"); last != cached {
+			t.Fatalf("round %d: last update %q, cache holds %q", round, last, cached)
+		}
+	}
+}
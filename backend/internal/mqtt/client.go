@@ -1,11 +1,9 @@
 package mqtt
 
 import (
-	"crypto/tls"
+	"context"
 	"fmt"
 	"math/rand"
-	"strconv"
-	"strings"
 	"time"
 
 	"teslamate-cyberui/internal/config"
@@ -14,28 +12,24 @@ import (
 	mqtt "github.com/eclipse/paho.mqtt.golang"
 )
 
-// Client 包含 MQTT 连接和缓存
+// Client 包含 MQTT 连接、缓存和订阅管理
 type Client struct {
 	client mqtt.Client
 	cache  *Cache
+	subs   *subscriptionManager
+	// prefix 车辆 topic 前缀，包含 namespace
+	prefix string
 }
 
 // GlobalCache 用于应用内部直接读取全局的车辆缓存 (方便在 Handler 里面读取)
-var GlobalCache = NewCache()
-
-// connectHandler 连接成功时的回调
-var connectHandler mqtt.OnConnectHandler = func(client mqtt.Client) {
-	logger.Info("Connected to Teslamate MQTT Broker")
-}
-
-// connectionLostHandler 连接丢失时的回调
-var connectionLostHandler mqtt.ConnectionLostHandler = func(client mqtt.Client, err error) {
-	logger.Errorf("Lost connection to Teslamate MQTT Broker: %v", err)
-}
+var GlobalCache = NewCache(DefaultHistorySize)
 
 // NewClient 初始化并返回新的 MQTT Client
 func NewClient(cfg config.MQTTConfig) (*Client, error) {
-	broker := fmt.Sprintf("tcp://%s:%s", cfg.Host, cfg.Port)
+	broker, err := brokerURL(cfg)
+	if err != nil {
+		return nil, err
+	}
 
 	opts := mqtt.NewClientOptions()
 	opts.AddBroker(broker)
@@ -54,53 +48,88 @@ func NewClient(cfg config.MQTTConfig) (*Client, error) {
 	opts.SetMaxReconnectInterval(10 * time.Second)
 	opts.SetPingTimeout(10 * time.Second)
 	opts.SetKeepAlive(60 * time.Second)
-	// Allow insecure TLS for local instances if they use self-signed certificates
-	opts.SetTLSConfig(&tls.Config{InsecureSkipVerify: true})
+	if isTLSScheme(cfg.Scheme) {
+		tlsConfig, err := newTLSConfig(cfg)
+		if err != nil {
+			return nil, err
+		}
+		opts.SetTLSConfig(tlsConfig)
+	}
+
+	c := &Client{cache: GlobalCache, prefix: topicPrefix(cfg.Namespace)}
+	c.subs = newSubscriptionManager(c.prefix, c.cache, c.handleMessage)
 
-	opts.OnConnect = connectHandler
-	opts.OnConnectionLost = connectionLostHandler
+	// 连接成功时的回调（包括自动重连），paho 会在独立的协程中调用
+	opts.OnConnect = func(client mqtt.Client) {
+		logger.Infof("Connected to Teslamate MQTT Broker %s", broker)
+		c.subs.onConnect()
+	}
+	// 连接丢失时的回调
+	opts.OnConnectionLost = func(client mqtt.Client, err error) {
+		logger.Errorf("Lost connection to Teslamate MQTT Broker: %v", err)
+		c.subs.onConnectionLost()
+	}
 
-	client := mqtt.NewClient(opts)
+	c.client = mqtt.NewClient(opts)
+	c.subs.client = c.client
 
-	if token := client.Connect(); token.Wait() && token.Error() != nil {
+	if token := c.client.Connect(); token.Wait() && token.Error() != nil {
 		return nil, fmt.Errorf("MQTT connect error: %v", token.Error())
 	}
 
-	return &Client{
-		client: client,
-		cache:  GlobalCache,
-	}, nil
+	return c, nil
 }
 
-// SubscribeCars 为指定的所有的 cars 订阅 teslamate 的 topic
-func (c *Client) SubscribeCars(carIDs []int16) {
-	for _, id := range carIDs {
-		topic := fmt.Sprintf("teslamate/cars/%d/#", id)
-
-		// messagePubHandler 处理收到的所有主题的消息
-		var messagePubHandler mqtt.MessageHandler = func(client mqtt.Client, msg mqtt.Message) {
-			// topic 格式: teslamate/cars/1/battery_level
-			parts := strings.Split(msg.Topic(), "/")
-			if len(parts) >= 4 {
-				carIDStr := parts[2]
-				metric := parts[3]
-
-				carID, err := strconv.ParseInt(carIDStr, 10, 16)
-				if err == nil {
-					c.cache.Set(int16(carID), metric, string(msg.Payload()))
-					// logger.Debugf("MQTT Update Car %d %s: %s", carID, metric, string(msg.Payload()))
-				}
-			}
+// Run 立即同步一次车辆订阅，之后每隔 interval 与 cars 重新对齐，直到 ctx 结束后断开连接
+func (c *Client) Run(ctx context.Context, cars CarLister, interval time.Duration) {
+	defer c.Disconnect()
+
+	if interval <= 0 {
+		interval = DefaultSyncInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		c.SyncCars(ctx, cars)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
 		}
+	}
+}
 
-		token := c.client.Subscribe(topic, 1, messagePubHandler)
-		token.Wait()
-		if token.Error() != nil {
-			logger.Errorf("Failed to subscribe to MQTT topic %s: %v", topic, token.Error())
-		} else {
-			logger.Infof("Subscribed to MQTT topic: %s", topic)
+// SyncCars 获取当前所有车辆并更新订阅：新增车辆订阅、已删除车辆取消订阅
+func (c *Client) SyncCars(ctx context.Context, cars CarLister) {
+	list, err := cars.GetAll(ctx)
+	if err != nil {
+		if ctx.Err() == nil {
+			logger.Errorf("Failed to get cars for MQTT subscription: %v", err)
 		}
+		return
+	}
+
+	carIDs := make([]int16, 0, len(list))
+	for _, car := range list {
+		carIDs = append(carIDs, car.ID)
+	}
+	c.subs.sync(carIDs)
+}
+
+// handleMessage 处理收到的所有主题的消息
+func (c *Client) handleMessage(client mqtt.Client, msg mqtt.Message) {
+	// topic 格式: teslamate/cars/1/battery_level 或 teslamate/<namespace>/cars/1/battery_level
+	carID, metric, ok := parseTopic(c.prefix, msg.Topic())
+	if !ok {
+		return
+	}
+	if msg.Retained() {
+		c.cache.SetRetained(carID, metric, string(msg.Payload()))
+		return
 	}
+	c.cache.Set(carID, metric, string(msg.Payload()))
+	// logger.Debugf("MQTT Update Car %d %s: %s", carID, metric, string(msg.Payload()))
 }
 
 // Disconnect 断开 MQTT 连接
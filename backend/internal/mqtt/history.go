@@ -0,0 +1,51 @@
+package mqtt
+
+import "time"
+
+// DefaultHistorySize 每个指标默认保留的历史值数量
+const DefaultHistorySize = 512
+
+// Sample 指标历史中的一个数据点
+type Sample struct {
+	Time  time.Time   `json:"time"`
+	Value interface{} `json:"value"`
+}
+
+// ring 固定容量的环形缓冲区，写满后覆盖最旧的数据
+type ring struct {
+	buf   []Sample
+	start int
+	size  int
+}
+
+func newRing(capacity int) *ring {
+	return &ring{buf: make([]Sample, capacity)}
+}
+
+// push 追加一个数据点
+func (r *ring) push(s Sample) {
+	if len(r.buf) == 0 {
+		return
+	}
+	idx := (r.start + r.size) % len(r.buf)
+	r.buf[idx] = s
+	if r.size < len(r.buf) {
+		r.size++
+	} else {
+		r.start = (r.start + 1) % len(r.buf)
+	}
+}
+
+// snapshot 按时间顺序返回最近的 limit 个数据点（limit <= 0 表示全部）
+func (r *ring) snapshot(limit int) []Sample {
+	n := r.size
+	if limit > 0 && limit < n {
+		n = limit
+	}
+	result := make([]Sample, n)
+	offset := r.size - n
+	for i := 0; i < n; i++ {
+		result[i] = r.buf[(r.start+offset+i)%len(r.buf)]
+	}
+	return result
+}
@@ -0,0 +1,140 @@
+package mqtt
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"teslamate-cyberui/internal/logger"
+	"teslamate-cyberui/internal/model"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+
+// DefaultSyncInterval 默认的车辆列表同步间隔
+const DefaultSyncInterval = 5 * time.Minute
+
+// subscribeTimeout 单次订阅/取消订阅等待 broker 确认的最长时间
+const subscribeTimeout = 10 * time.Second
+
+// CarLister 提供需要订阅的车辆列表，由 repository.CarRepository 实现
+type CarLister interface {
+	GetAll(ctx context.Context) ([]model.Car, error)
+}
+
+// subscriptionManager 维护需要订阅的车辆集合
+// 每次（重新）连接时重新订阅全部车辆，并定期与数据库中的车辆列表对比，
+// 为新增车辆订阅、为已删除车辆取消订阅
+type subscriptionManager struct {
+	client  mqtt.Client
+	prefix  string
+	handler mqtt.MessageHandler
+	cache   *Cache
+
+	mu sync.Mutex
+	// cars 记录期望订阅的车辆，值表示当前连接上是否已订阅成功
+	cars map[int16]bool
+}
+
+func newSubscriptionManager(prefix string, cache *Cache, handler mqtt.MessageHandler) *subscriptionManager {
+	return &subscriptionManager{
+		prefix:  prefix,
+		handler: handler,
+		cache:   cache,
+		cars:    make(map[int16]bool),
+	}
+}
+
+// carTopic 返回车辆对应的通配 topic
+func (s *subscriptionManager) carTopic(carID int16) string {
+	return fmt.Sprintf("%s%d/#", s.prefix, carID)
+}
+
+// onConnect 连接（或自动重连）成功后重新订阅全部车辆
+// 默认使用 clean session，重连后 broker 不会保留之前的订阅
+func (s *subscriptionManager) onConnect() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for carID := range s.cars {
+		s.cars[carID] = s.subscribe(carID)
+	}
+}
+
+// onConnectionLost 连接断开后所有订阅都需要在重连时重新建立
+func (s *subscriptionManager) onConnectionLost() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for carID := range s.cars {
+		s.cars[carID] = false
+	}
+}
+
+// sync 将订阅集合与给定的车辆列表对齐，同时重试之前订阅失败的车辆
+func (s *subscriptionManager) sync(carIDs []int16) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	wanted := make(map[int16]bool, len(carIDs))
+	for _, id := range carIDs {
+		wanted[id] = true
+	}
+
+	for carID := range s.cars {
+		if wanted[carID] {
+			continue
+		}
+		if s.cars[carID] {
+			s.unsubscribe(carID)
+		}
+		delete(s.cars, carID)
+		s.cache.DeleteCar(carID)
+	}
+
+	for carID := range wanted {
+		if subscribed := s.cars[carID]; !subscribed {
+			s.cars[carID] = s.subscribe(carID)
+		}
+	}
+}
+
+// subscribe 订阅单辆车的 topic，返回是否成功；调用方需持有 mu
+func (s *subscriptionManager) subscribe(carID int16) bool {
+	topic := s.carTopic(carID)
+	if !s.client.IsConnectionOpen() {
+		return false
+	}
+
+	token := s.client.Subscribe(topic, 1, s.handler)
+	if !token.WaitTimeout(subscribeTimeout) {
+		logger.Errorf("Timed out subscribing to MQTT topic %s", topic)
+		return false
+	}
+	if token.Error() != nil {
+		logger.Errorf("Failed to subscribe to MQTT topic %s: %v", topic, token.Error())
+		return false
+	}
+	logger.Infof("Subscribed to MQTT topic: %s", topic)
+	return true
+}
+
+// unsubscribe 取消单辆车的订阅；调用方需持有 mu
+func (s *subscriptionManager) unsubscribe(carID int16) {
+	topic := s.carTopic(carID)
+	if !s.client.IsConnectionOpen() {
+		return
+	}
+
+	token := s.client.Unsubscribe(topic)
+	if !token.WaitTimeout(subscribeTimeout) {
+		logger.Errorf("Timed out unsubscribing from MQTT topic %s", topic)
+		return
+	}
+	if token.Error() != nil {
+		logger.Errorf("Failed to unsubscribe from MQTT topic %s: %v", topic, token.Error())
+		return
+	}
+	logger.Infof("Unsubscribed from MQTT topic: %s", topic)
+}
@@ -0,0 +1,105 @@
+package mqtt
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+
+	"teslamate-cyberui/internal/config"
+)
+
+// brokerURL 根据配置拼接 broker 地址，支持 tcp / ssl / ws / wss
+func brokerURL(cfg config.MQTTConfig) (string, error) {
+	scheme := strings.ToLower(strings.TrimSpace(cfg.Scheme))
+	if scheme == "" {
+		scheme = "tcp"
+	}
+	hostPort := net.JoinHostPort(cfg.Host, cfg.Port)
+
+	switch scheme {
+	case "tcp", "ssl":
+		return fmt.Sprintf("%s://%s", scheme, hostPort), nil
+	case "ws", "wss":
+		path := cfg.WSPath
+		if !strings.HasPrefix(path, "/") {
+			path = "/" + path
+		}
+		return fmt.Sprintf("%s://%s%s", scheme, hostPort, path), nil
+	default:
+		return "", fmt.Errorf("unsupported MQTT scheme %q (expected tcp, ssl, ws or wss)", cfg.Scheme)
+	}
+}
+
+// isTLSScheme 判断协议是否需要 TLS
+func isTLSScheme(scheme string) bool {
+	switch strings.ToLower(strings.TrimSpace(scheme)) {
+	case "ssl", "wss":
+		return true
+	}
+	return false
+}
+
+// newTLSConfig 根据配置加载私有 CA 和客户端证书
+func newTLSConfig(cfg config.MQTTConfig) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		ServerName:         cfg.Host,
+		InsecureSkipVerify: !cfg.TLSVerify,
+	}
+
+	if cfg.CACert != "" {
+		pem, err := os.ReadFile(cfg.CACert)
+		if err != nil {
+			return nil, fmt.Errorf("read MQTT CA certificate: %v", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no valid certificates found in %s", cfg.CACert)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if cfg.ClientCert != "" || cfg.ClientKey != "" {
+		if cfg.ClientCert == "" || cfg.ClientKey == "" {
+			return nil, fmt.Errorf("both MQTT client certificate and key must be set")
+		}
+		cert, err := tls.LoadX509KeyPair(cfg.ClientCert, cfg.ClientKey)
+		if err != nil {
+			return nil, fmt.Errorf("load MQTT client certificate: %v", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
+
+// topicPrefix 返回车辆 topic 的公共前缀，与 TeslaMate 的 MQTT_NAMESPACE 保持一致
+// 未设置 namespace 时为 teslamate/cars/，否则为 teslamate/<namespace>/cars/
+func topicPrefix(namespace string) string {
+	namespace = strings.Trim(namespace, "/")
+	if namespace == "" {
+		return "teslamate/cars/"
+	}
+	return "teslamate/" + namespace + "/cars/"
+}
+
+// parseTopic 从完整 topic 中解析车辆 ID 和指标名
+// 例如 teslamate/home/cars/1/battery_level -> (1, battery_level)
+func parseTopic(prefix, topic string) (int16, string, bool) {
+	rest, ok := strings.CutPrefix(topic, prefix)
+	if !ok {
+		return 0, "", false
+	}
+	parts := strings.Split(rest, "/")
+	if len(parts) < 2 || parts[1] == "" {
+		return 0, "", false
+	}
+	carID, err := strconv.ParseInt(parts[0], 10, 16)
+	if err != nil {
+		return 0, "", false
+	}
+	return int16(carID), parts[1], true
+}
@@ -0,0 +1,89 @@
+package mqtt
+
+import (
+	"encoding/json"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ValueType MQTT 指标值解析后的类型
+type ValueType string
+
+const (
+	TypeString ValueType = "string"
+	TypeBool   ValueType = "bool"
+	TypeInt    ValueType = "int"
+	TypeFloat  ValueType = "float"
+	TypeTime   ValueType = "time"
+	TypeJSON   ValueType = "json"
+)
+
+// Entry 缓存中的单个指标值
+// Raw 为 MQTT 原始负载，Value 为按 Type 解析后的值（bool / int64 / float64 / time.Time / JSON / string）
+type Entry struct {
+	Raw       string      `json:"raw"`
+	Type      ValueType   `json:"type"`
+	Value     interface{} `json:"value"`
+	UpdatedAt time.Time   `json:"updatedAt"`
+	// Retained 来自订阅时 broker 下发的保留消息且与缓存值不同，UpdatedAt 仅为接收时间，数据的实际时间未知
+	Retained bool `json:"retained,omitempty"`
+}
+
+// ParseValue 将 MQTT 原始字符串解析为带类型的值
+// TeslaMate 发布的负载均为纯文本：布尔为 true/false，时间为 RFC3339，
+// location / active_route 等为 JSON 对象，其余无法识别的按字符串处理
+func ParseValue(raw string) (ValueType, interface{}) {
+	switch raw {
+	case "true":
+		return TypeBool, true
+	case "false":
+		return TypeBool, false
+	}
+	if i, err := strconv.ParseInt(raw, 10, 64); err == nil {
+		return TypeInt, i
+	}
+	if f, err := strconv.ParseFloat(raw, 64); err == nil && !math.IsNaN(f) && !math.IsInf(f, 0) {
+		return TypeFloat, f
+	}
+	if t, err := time.Parse(time.RFC3339, raw); err == nil {
+		return TypeTime, t
+	}
+	if strings.HasPrefix(raw, "{") || strings.HasPrefix(raw, "[") {
+		var v interface{}
+		if err := json.Unmarshal([]byte(raw), &v); err == nil {
+			return TypeJSON, v
+		}
+	}
+	return TypeString, raw
+}
+
+// newEntry 解析原始值并构建缓存条目
+func newEntry(raw string, at time.Time) Entry {
+	typ, value := ParseValue(raw)
+	return Entry{Raw: raw, Type: typ, Value: value, UpdatedAt: at}
+}
+
+// Bool 以布尔值读取
+func (e Entry) Bool() (bool, bool) {
+	b, ok := e.Value.(bool)
+	return b, ok
+}
+
+// Float 以浮点数读取，整数值同样适用
+func (e Entry) Float() (float64, bool) {
+	switch v := e.Value.(type) {
+	case int64:
+		return float64(v), true
+	case float64:
+		return v, true
+	}
+	return 0, false
+}
+
+// Time 以时间读取
+func (e Entry) Time() (time.Time, bool) {
+	t, ok := e.Value.(time.Time)
+	return t, ok
+}
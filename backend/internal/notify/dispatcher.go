@@ -0,0 +1,43 @@
+package notify
+
+import (
+	"context"
+
+	"teslamate-cyberui/internal/logger"
+	"teslamate-cyberui/internal/repository"
+)
+
+// Dispatcher 按渠道 ID 加载配置并发送消息
+type Dispatcher struct {
+	repo repository.NotificationRepository
+}
+
+// NewDispatcher 创建分发器
+func NewDispatcher(repo repository.NotificationRepository) *Dispatcher {
+	return &Dispatcher{repo: repo}
+}
+
+// Dispatch 向指定的全部已启用渠道发送消息，单个渠道失败只记录日志
+func (d *Dispatcher) Dispatch(ctx context.Context, channelIDs []int64, msg Message) {
+	if len(channelIDs) == 0 {
+		return
+	}
+	channels, err := d.repo.GetByIDs(ctx, channelIDs)
+	if err != nil {
+		return
+	}
+	if len(channels) < len(channelIDs) {
+		logger.Warnf("Some notification channels in %v no longer exist", channelIDs)
+	}
+
+	for _, ch := range channels {
+		if !ch.Enabled {
+			continue
+		}
+		if err := Send(ctx, ch, msg); err != nil {
+			logger.Errorf("Failed to send %s notification via channel %d (%s): %v", msg.Kind, ch.ID, ch.Name, err)
+			continue
+		}
+		logger.Debugf("Sent %s notification via channel %d (%s)", msg.Kind, ch.ID, ch.Name)
+	}
+}
@@ -0,0 +1,298 @@
+// Package notify 实现告警和周期摘要的外部推送渠道
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"text/template"
+	"time"
+
+	"teslamate-cyberui/internal/model"
+)
+
+// 消息类型
+const (
+	KindAlert  = "alert"
+	KindDigest = "digest"
+	KindTest   = "test"
+)
+
+// maskedSecret 返回给前端时替换敏感配置的占位符，更新时原样提交表示保留原值
+const maskedSecret = "******"
+
+// httpTimeout 单次 HTTP 推送的超时时间
+const httpTimeout = 15 * time.Second
+
+var httpClient = &http.Client{Timeout: httpTimeout}
+
+// Message 待发送的消息，同时作为标题/正文模板的数据
+// 模板中可以引用 {{.Title}}、{{.Body}}、{{.Kind}}、{{.CarID}}、{{.Time}} 以及 {{.Data.xxx}}
+type Message struct {
+	Kind  string                 `json:"kind"`
+	Title string                 `json:"title"`
+	Body  string                 `json:"body"`
+	CarID int16                  `json:"carId,omitempty"`
+	Time  time.Time              `json:"time"`
+	Data  map[string]interface{} `json:"data,omitempty"`
+	// HTML 可选的 HTML 正文，仅邮件渠道使用
+	HTML string `json:"-"`
+}
+
+// Channel 通知渠道
+type Channel interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// secretFields 各类型渠道配置中的敏感字段
+var secretFields = map[string][]string{
+	model.NotifyTypeWebhook:  {"secret"},
+	model.NotifyTypeNtfy:     {"token", "password"},
+	model.NotifyTypeGotify:   {"token"},
+	model.NotifyTypeTelegram: {"botToken"},
+	model.NotifyTypeSMTP:     {"password"},
+}
+
+// secretHeaderFields 渠道配置中值为请求头对象的字段，所有请求头的值都视为敏感（如 Authorization）
+var secretHeaderFields = map[string][]string{
+	model.NotifyTypeWebhook: {"headers"},
+}
+
+// secretURLFields 渠道配置中可能携带密钥的 URL 字段（如 Slack / Discord 的 Webhook 地址），
+// 只保留协议和主机，路径、查询参数和用户信息一律隐藏
+var secretURLFields = map[string][]string{
+	model.NotifyTypeWebhook: {"url"},
+}
+
+// New 根据渠道配置创建对应的实现，配置不完整时返回错误
+func New(ch model.NotificationChannel) (Channel, error) {
+	switch ch.Type {
+	case model.NotifyTypeWebhook:
+		var cfg WebhookConfig
+		if err := decodeConfig(ch.Config, &cfg); err != nil {
+			return nil, err
+		}
+		return NewWebhook(cfg)
+	case model.NotifyTypeNtfy:
+		var cfg NtfyConfig
+		if err := decodeConfig(ch.Config, &cfg); err != nil {
+			return nil, err
+		}
+		return NewNtfy(cfg)
+	case model.NotifyTypeGotify:
+		var cfg GotifyConfig
+		if err := decodeConfig(ch.Config, &cfg); err != nil {
+			return nil, err
+		}
+		return NewGotify(cfg)
+	case model.NotifyTypeTelegram:
+		var cfg TelegramConfig
+		if err := decodeConfig(ch.Config, &cfg); err != nil {
+			return nil, err
+		}
+		return NewTelegram(cfg)
+	case model.NotifyTypeSMTP:
+		var cfg SMTPConfig
+		if err := decodeConfig(ch.Config, &cfg); err != nil {
+			return nil, err
+		}
+		return NewSMTP(cfg)
+	default:
+		return nil, fmt.Errorf("unsupported channel type %q", ch.Type)
+	}
+}
+
+// Validate 校验渠道配置和模板
+func Validate(ch model.NotificationChannel) error {
+	if strings.TrimSpace(ch.Name) == "" {
+		return fmt.Errorf("name is required")
+	}
+	if _, err := New(ch); err != nil {
+		return err
+	}
+	if _, err := parseTemplate("title", ch.TitleTemplate); err != nil {
+		return fmt.Errorf("invalid title template: %v", err)
+	}
+	if _, err := parseTemplate("body", ch.BodyTemplate); err != nil {
+		return fmt.Errorf("invalid body template: %v", err)
+	}
+	return nil
+}
+
+// Send 按渠道模板渲染消息后发送
+func Send(ctx context.Context, ch model.NotificationChannel, msg Message) error {
+	impl, err := New(ch)
+	if err != nil {
+		return err
+	}
+	rendered, err := Render(ch, msg)
+	if err != nil {
+		return err
+	}
+	return impl.Send(ctx, rendered)
+}
+
+// Render 使用渠道的标题/正文模板渲染消息，模板为空时保留原值
+func Render(ch model.NotificationChannel, msg Message) (Message, error) {
+	out := msg
+	if ch.TitleTemplate != "" {
+		title, err := execTemplate("title", ch.TitleTemplate, msg)
+		if err != nil {
+			return msg, fmt.Errorf("render title template: %v", err)
+		}
+		out.Title = title
+	}
+	if ch.BodyTemplate != "" {
+		body, err := execTemplate("body", ch.BodyTemplate, msg)
+		if err != nil {
+			return msg, fmt.Errorf("render body template: %v", err)
+		}
+		out.Body = body
+	}
+	return out, nil
+}
+
+func parseTemplate(name, text string) (*template.Template, error) {
+	return template.New(name).Option("missingkey=zero").Parse(text)
+}
+
+func execTemplate(name, text string, msg Message) (string, error) {
+	tmpl, err := parseTemplate(name, text)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, msg); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// MaskConfig 返回隐藏敏感字段后的配置副本
+func MaskConfig(channelType string, cfg model.JSONObject) model.JSONObject {
+	out := make(model.JSONObject, len(cfg))
+	for k, v := range cfg {
+		out[k] = v
+	}
+	for _, field := range secretFields[channelType] {
+		if s, ok := out[field].(string); ok && s != "" {
+			out[field] = maskedSecret
+		}
+	}
+	for _, field := range secretHeaderFields[channelType] {
+		if headers, ok := out[field].(map[string]interface{}); ok {
+			masked := make(map[string]interface{}, len(headers))
+			for k := range headers {
+				masked[k] = maskedSecret
+			}
+			out[field] = masked
+		}
+	}
+	for _, field := range secretURLFields[channelType] {
+		if s, ok := out[field].(string); ok {
+			out[field] = maskURL(s)
+		}
+	}
+	return out
+}
+
+// MergeSecrets 更新配置时，将仍为占位符的敏感字段替换为原有的值
+// 请求头按名称逐个恢复；URL 与原值隐藏后的形式相同时视为未修改
+func MergeSecrets(channelType string, cfg, old model.JSONObject) {
+	for _, field := range secretFields[channelType] {
+		if s, ok := cfg[field].(string); ok && s == maskedSecret {
+			cfg[field] = old[field]
+		}
+	}
+	for _, field := range secretHeaderFields[channelType] {
+		headers, ok := cfg[field].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		oldHeaders, _ := old[field].(map[string]interface{})
+		for k, v := range headers {
+			if s, ok := v.(string); ok && s == maskedSecret {
+				if oldValue, ok := oldHeaders[k]; ok {
+					headers[k] = oldValue
+				} else {
+					delete(headers, k)
+				}
+			}
+		}
+	}
+	for _, field := range secretURLFields[channelType] {
+		s, ok := cfg[field].(string)
+		oldURL, oldOK := old[field].(string)
+		if ok && oldOK && s != oldURL && s == maskURL(oldURL) {
+			cfg[field] = oldURL
+		}
+	}
+}
+
+// maskURL 隐藏 URL 中除协议和主机以外的部分，无法解析时整体隐藏
+func maskURL(raw string) string {
+	if raw == "" {
+		return raw
+	}
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return maskedSecret
+	}
+	if u.User == nil && (u.Path == "" || u.Path == "/") && u.RawQuery == "" && u.Fragment == "" {
+		return raw
+	}
+	return u.Scheme + "://" + u.Host + "/" + maskedSecret
+}
+
+// decodeConfig 将 JSON 对象解析为具体渠道的配置结构
+func decodeConfig(cfg model.JSONObject, out interface{}) error {
+	b, err := json.Marshal(cfg)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(b, out); err != nil {
+		return fmt.Errorf("invalid channel config: %v", err)
+	}
+	return nil
+}
+
+// postJSON 以 JSON 发送请求，非 2xx 响应视为失败
+func postJSON(ctx context.Context, url string, payload interface{}, headers map[string]string) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	return doRequest(ctx, http.MethodPost, url, body, "application/json", headers)
+}
+
+// doRequest 发送 HTTP 请求，非 2xx 响应时返回包含响应内容的错误
+func doRequest(ctx context.Context, method, url string, body []byte, contentType string, headers map[string]string) error {
+	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		snippet, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("unexpected status %d: %s", resp.StatusCode, strings.TrimSpace(string(snippet)))
+	}
+	io.Copy(io.Discard, resp.Body)
+	return nil
+}
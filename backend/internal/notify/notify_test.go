@@ -0,0 +1,62 @@
+package notify
+
+import (
+	"testing"
+
+	"teslamate-cyberui/internal/model"
+)
+
+func TestMaskConfigAndMergeSecrets(t *testing.T) {
+	stored := model.JSONObject{
+		"url":     "https://discord.com/api/webhooks/1/token",
+		"secret":  "s3cret",
+		"headers": map[string]interface{}{"Authorization": "Bearer abc"},
+	}
+
+	masked := MaskConfig(model.NotifyTypeWebhook, stored)
+	if masked["secret"] != maskedSecret {
+		t.Errorf("secret = %v", masked["secret"])
+	}
+	if masked["url"] != "https://discord.com/"+maskedSecret {
+		t.Errorf("url = %v", masked["url"])
+	}
+	if h := masked["headers"].(map[string]interface{}); h["Authorization"] != maskedSecret {
+		t.Errorf("headers = %v", h)
+	}
+	if h := stored["headers"].(map[string]interface{}); h["Authorization"] != "Bearer abc" {
+		t.Errorf("MaskConfig modified the stored headers: %v", h)
+	}
+
+	// 前端原样提交隐藏后的配置，并新增一个请求头
+	submitted := model.JSONObject{
+		"url":    masked["url"],
+		"secret": masked["secret"],
+		"headers": map[string]interface{}{
+			"Authorization": maskedSecret,
+			"X-Extra":       "1",
+		},
+	}
+	MergeSecrets(model.NotifyTypeWebhook, submitted, stored)
+	if submitted["url"] != stored["url"] || submitted["secret"] != "s3cret" {
+		t.Errorf("merged = %v", submitted)
+	}
+	h := submitted["headers"].(map[string]interface{})
+	if h["Authorization"] != "Bearer abc" || h["X-Extra"] != "1" {
+		t.Errorf("merged headers = %v", h)
+	}
+}
+
+func TestMergeSecretsKeepsChangedURL(t *testing.T) {
+	stored := model.JSONObject{"url": "https://example.com/hook?token=old"}
+	submitted := model.JSONObject{"url": "https://example.com/hook?token=new"}
+	MergeSecrets(model.NotifyTypeWebhook, submitted, stored)
+	if submitted["url"] != "https://example.com/hook?token=new" {
+		t.Errorf("url = %v", submitted["url"])
+	}
+}
+
+func TestMaskURLWithoutSecrets(t *testing.T) {
+	if got := maskURL("https://example.com"); got != "https://example.com" {
+		t.Errorf("maskURL = %q", got)
+	}
+}
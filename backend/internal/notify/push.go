@@ -0,0 +1,102 @@
+package notify
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"strings"
+)
+
+// NtfyConfig ntfy 推送配置
+type NtfyConfig struct {
+	// Server 默认为 https://ntfy.sh
+	Server string `json:"server"`
+	Topic  string `json:"topic"`
+	// Token 访问令牌，与 Username / Password 二选一
+	Token    string `json:"token"`
+	Username string `json:"username"`
+	Password string `json:"password"`
+	// Priority 为 1-5，0 表示使用服务器默认优先级
+	Priority int      `json:"priority"`
+	Tags     []string `json:"tags"`
+}
+
+// Ntfy 通过 ntfy 的 JSON 发布接口推送
+type Ntfy struct {
+	cfg NtfyConfig
+}
+
+// NewNtfy 创建 ntfy 渠道
+func NewNtfy(cfg NtfyConfig) (*Ntfy, error) {
+	if cfg.Server == "" {
+		cfg.Server = "https://ntfy.sh"
+	}
+	cfg.Server = strings.TrimRight(cfg.Server, "/")
+	if cfg.Topic == "" {
+		return nil, fmt.Errorf("ntfy topic is required")
+	}
+	if cfg.Priority < 0 || cfg.Priority > 5 {
+		return nil, fmt.Errorf("ntfy priority must be between 1 and 5, or 0 for the server default")
+	}
+	return &Ntfy{cfg: cfg}, nil
+}
+
+// Send 发送消息
+func (n *Ntfy) Send(ctx context.Context, msg Message) error {
+	payload := map[string]interface{}{
+		"topic":   n.cfg.Topic,
+		"title":   msg.Title,
+		"message": msg.Body,
+	}
+	if n.cfg.Priority > 0 {
+		payload["priority"] = n.cfg.Priority
+	}
+	if len(n.cfg.Tags) > 0 {
+		payload["tags"] = n.cfg.Tags
+	}
+
+	headers := map[string]string{}
+	switch {
+	case n.cfg.Token != "":
+		headers["Authorization"] = "Bearer " + n.cfg.Token
+	case n.cfg.Username != "":
+		cred := base64.StdEncoding.EncodeToString([]byte(n.cfg.Username + ":" + n.cfg.Password))
+		headers["Authorization"] = "Basic " + cred
+	}
+	// JSON 发布需要发送到服务根路径
+	return postJSON(ctx, n.cfg.Server+"/", payload, headers)
+}
+
+// GotifyConfig Gotify 推送配置
+type GotifyConfig struct {
+	Server   string `json:"server"`
+	Token    string `json:"token"`
+	Priority int    `json:"priority"`
+}
+
+// Gotify 通过 Gotify 的 /message 接口推送
+type Gotify struct {
+	cfg GotifyConfig
+}
+
+// NewGotify 创建 Gotify 渠道
+func NewGotify(cfg GotifyConfig) (*Gotify, error) {
+	if cfg.Server == "" {
+		return nil, fmt.Errorf("gotify server is required")
+	}
+	if cfg.Token == "" {
+		return nil, fmt.Errorf("gotify application token is required")
+	}
+	cfg.Server = strings.TrimRight(cfg.Server, "/")
+	return &Gotify{cfg: cfg}, nil
+}
+
+// Send 发送消息
+func (g *Gotify) Send(ctx context.Context, msg Message) error {
+	payload := map[string]interface{}{
+		"title":    msg.Title,
+		"message":  msg.Body,
+		"priority": g.cfg.Priority,
+	}
+	return postJSON(ctx, g.cfg.Server+"/message", payload, map[string]string{"X-Gotify-Key": g.cfg.Token})
+}
@@ -0,0 +1,122 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// capture 记录测试服务器收到的请求
+type capture struct {
+	path    string
+	header  http.Header
+	payload map[string]interface{}
+}
+
+func newCaptureServer(t *testing.T, c *capture, response string) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c.path = r.URL.Path
+		c.header = r.Header.Clone()
+		if err := json.NewDecoder(r.Body).Decode(&c.payload); err != nil {
+			t.Errorf("decode payload: %v", err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(response))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestNtfySend(t *testing.T) {
+	var c capture
+	srv := newCaptureServer(t, &c, `{}`)
+
+	n, err := NewNtfy(NtfyConfig{Server: srv.URL + "/", Topic: "tesla", Token: "tk", Priority: 4, Tags: []string{"car"}})
+	if err != nil {
+		t.Fatalf("NewNtfy: %v", err)
+	}
+	if err := n.Send(context.Background(), Message{Title: "hello", Body: "world"}); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	if c.path != "/" {
+		t.Errorf("path = %q, want /", c.path)
+	}
+	if got := c.header.Get("Authorization"); got != "Bearer tk" {
+		t.Errorf("Authorization = %q", got)
+	}
+	if c.payload["topic"] != "tesla" || c.payload["title"] != "hello" || c.payload["message"] != "world" {
+		t.Errorf("payload = %v", c.payload)
+	}
+	if c.payload["priority"] != float64(4) {
+		t.Errorf("priority = %v", c.payload["priority"])
+	}
+	if tags, _ := c.payload["tags"].([]interface{}); len(tags) != 1 || tags[0] != "car" {
+		t.Errorf("tags = %v", c.payload["tags"])
+	}
+}
+
+func TestNtfySendBasicAuth(t *testing.T) {
+	var c capture
+	srv := newCaptureServer(t, &c, `{}`)
+
+	n, err := NewNtfy(NtfyConfig{Server: srv.URL, Topic: "tesla", Username: "user", Password: "pass"})
+	if err != nil {
+		t.Fatalf("NewNtfy: %v", err)
+	}
+	if err := n.Send(context.Background(), Message{Title: "hello"}); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	// base64("user:pass")
+	if got := c.header.Get("Authorization"); got != "Basic dXNlcjpwYXNz" {
+		t.Errorf("Authorization = %q", got)
+	}
+	if _, ok := c.payload["priority"]; ok {
+		t.Errorf("priority should be omitted, got %v", c.payload["priority"])
+	}
+}
+
+func TestGotifySend(t *testing.T) {
+	var c capture
+	srv := newCaptureServer(t, &c, `{"id":1}`)
+
+	g, err := NewGotify(GotifyConfig{Server: srv.URL + "/", Token: "app-token", Priority: 7})
+	if err != nil {
+		t.Fatalf("NewGotify: %v", err)
+	}
+	if err := g.Send(context.Background(), Message{Title: "hello", Body: "world"}); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	if c.path != "/message" {
+		t.Errorf("path = %q, want /message", c.path)
+	}
+	if got := c.header.Get("X-Gotify-Key"); got != "app-token" {
+		t.Errorf("X-Gotify-Key = %q", got)
+	}
+	if c.payload["title"] != "hello" || c.payload["message"] != "world" || c.payload["priority"] != float64(7) {
+		t.Errorf("payload = %v", c.payload)
+	}
+}
+
+func TestGotifyRequiresToken(t *testing.T) {
+	if _, err := NewGotify(GotifyConfig{Server: "http://localhost"}); err == nil {
+		t.Error("expected error without token")
+	}
+}
+
+func TestNewNtfyPriority(t *testing.T) {
+	for _, p := range []int{0, 1, 5} {
+		if _, err := NewNtfy(NtfyConfig{Topic: "tesla", Priority: p}); err != nil {
+			t.Errorf("priority %d: %v", p, err)
+		}
+	}
+	for _, p := range []int{-1, 6} {
+		if _, err := NewNtfy(NtfyConfig{Topic: "tesla", Priority: p}); err == nil {
+			t.Errorf("priority %d: expected error", p)
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package notify
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"mime"
+	"net"
+	"net/mail"
+	"strings"
+	"testing"
+)
+
+// smtpSession 测试用 SMTP 服务收到的信封和邮件内容
+type smtpSession struct {
+	from string
+	to   []string
+	data string
+}
+
+// startSMTPServer 启动一个只处理一次会话的最简 SMTP 服务（无加密、无认证），会话结束后通过通道返回
+func startSMTPServer(t *testing.T) (string, int, <-chan smtpSession) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	done := make(chan smtpSession, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		var s smtpSession
+		r := bufio.NewReader(conn)
+		reply := func(line string) { fmt.Fprintf(conn, "%s\r\n", line) }
+		reply("220 localhost ESMTP test")
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			line = strings.TrimRight(line, "\r\n")
+			cmd := strings.ToUpper(line)
+			switch {
+			case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+				reply("250 localhost")
+			case strings.HasPrefix(cmd, "MAIL FROM:"):
+				s.from = strings.Trim(line[len("MAIL FROM:"):], "<>")
+				reply("250 OK")
+			case strings.HasPrefix(cmd, "RCPT TO:"):
+				s.to = append(s.to, strings.Trim(line[len("RCPT TO:"):], "<>"))
+				reply("250 OK")
+			case cmd == "DATA":
+				reply("354 End data with <CR><LF>.<CR><LF>")
+				var data strings.Builder
+				for {
+					l, err := r.ReadString('\n')
+					if err != nil {
+						return
+					}
+					if l == ".\r\n" {
+						break
+					}
+					data.WriteString(l)
+				}
+				s.data = data.String()
+				reply("250 OK")
+			case cmd == "QUIT":
+				reply("221 Bye")
+				done <- s
+				return
+			default:
+				reply("502 Command not implemented")
+			}
+		}
+	}()
+
+	addr := ln.Addr().(*net.TCPAddr)
+	return addr.IP.String(), addr.Port, done
+}
+
+func TestSMTPSendPlainText(t *testing.T) {
+	host, port, done := startSMTPServer(t)
+
+	s, err := NewSMTP(SMTPConfig{
+		Host:     host,
+		Port:     port,
+		Security: SMTPSecurityNone,
+		From:     "CyberUI <cyberui@example.com>",
+		To:       []string{"owner@example.com", "Other <other@example.com>"},
+	})
+	if err != nil {
+		t.Fatalf("NewSMTP: %v", err)
+	}
+	if err := s.Send(context.Background(), Message{Title: "电量提醒", Body: "battery low"}); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	session := <-done
+	if session.from != "cyberui@example.com" {
+		t.Errorf("MAIL FROM = %q", session.from)
+	}
+	if len(session.to) != 2 || session.to[0] != "owner@example.com" || session.to[1] != "other@example.com" {
+		t.Errorf("RCPT TO = %v", session.to)
+	}
+
+	m, err := mail.ReadMessage(strings.NewReader(session.data))
+	if err != nil {
+		t.Fatalf("parse message: %v", err)
+	}
+	subject, err := new(mime.WordDecoder).DecodeHeader(m.Header.Get("Subject"))
+	if err != nil || subject != "电量提醒" {
+		t.Errorf("Subject = %q (%v)", subject, err)
+	}
+	if got := m.Header.Get("Content-Type"); !strings.HasPrefix(got, "text/plain") {
+		t.Errorf("Content-Type = %q", got)
+	}
+	if !strings.Contains(session.data, "battery low") {
+		t.Errorf("body missing from message:\n%s", session.data)
+	}
+}
+
+func TestSMTPSendHTMLAlternative(t *testing.T) {
+	host, port, done := startSMTPServer(t)
+
+	s, err := NewSMTP(SMTPConfig{
+		Host:     host,
+		Port:     port,
+		Security: SMTPSecurityNone,
+		From:     "cyberui@example.com",
+		To:       []string{"owner@example.com"},
+	})
+	if err != nil {
+		t.Fatalf("NewSMTP: %v", err)
+	}
+	if err := s.Send(context.Background(), Message{Title: "digest", Body: "plain", HTML: "<b>rich</b>"}); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	session := <-done
+	m, err := mail.ReadMessage(strings.NewReader(session.data))
+	if err != nil {
+		t.Fatalf("parse message: %v", err)
+	}
+	if got := m.Header.Get("Content-Type"); !strings.HasPrefix(got, "multipart/alternative") {
+		t.Errorf("Content-Type = %q", got)
+	}
+	if !strings.Contains(session.data, "plain") || !strings.Contains(session.data, "<b>rich</b>") {
+		t.Errorf("message missing text or html part:\n%s", session.data)
+	}
+}
+
+func TestNewSMTPDefaults(t *testing.T) {
+	s, err := NewSMTP(SMTPConfig{Host: "mail.example.com", From: "a@example.com", To: []string{"b@example.com"}})
+	if err != nil {
+		t.Fatalf("NewSMTP: %v", err)
+	}
+	if s.cfg.Security != SMTPSecurityStartTLS || s.cfg.Port != 587 {
+		t.Errorf("defaults = %s:%d, want starttls:587", s.cfg.Security, s.cfg.Port)
+	}
+	if _, err := NewSMTP(SMTPConfig{Host: "mail.example.com", From: "a@example.com", To: []string{"b@example.com"}, Security: "ssl"}); err == nil {
+		t.Error("expected error for unknown security")
+	}
+}
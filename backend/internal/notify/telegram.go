@@ -0,0 +1,90 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// TelegramConfig Telegram Bot 配置
+type TelegramConfig struct {
+	BotToken string `json:"botToken"`
+	ChatID   string `json:"chatId"`
+	// APIURL 默认为 https://api.telegram.org，可指向自建的 Bot API 服务
+	APIURL string `json:"apiUrl"`
+	// ParseMode 可选 MarkdownV2 / HTML，为空时按纯文本发送
+	ParseMode           string `json:"parseMode"`
+	DisableNotification bool   `json:"disableNotification"`
+}
+
+// Telegram 通过 Bot API 的 sendMessage 推送
+type Telegram struct {
+	cfg TelegramConfig
+}
+
+// NewTelegram 创建 Telegram 渠道
+func NewTelegram(cfg TelegramConfig) (*Telegram, error) {
+	if cfg.BotToken == "" {
+		return nil, fmt.Errorf("telegram bot token is required")
+	}
+	if cfg.ChatID == "" {
+		return nil, fmt.Errorf("telegram chat id is required")
+	}
+	if cfg.APIURL == "" {
+		cfg.APIURL = "https://api.telegram.org"
+	}
+	cfg.APIURL = strings.TrimRight(cfg.APIURL, "/")
+	return &Telegram{cfg: cfg}, nil
+}
+
+// Send 发送消息，标题和正文之间空一行
+func (t *Telegram) Send(ctx context.Context, msg Message) error {
+	text := msg.Body
+	if msg.Title != "" {
+		text = msg.Title + "\n\n" + msg.Body
+	}
+	payload := map[string]interface{}{
+		"chat_id":              t.cfg.ChatID,
+		"text":                 text,
+		"disable_notification": t.cfg.DisableNotification,
+	}
+	if t.cfg.ParseMode != "" {
+		payload["parse_mode"] = t.cfg.ParseMode
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	url := fmt.Sprintf("%s/bot%s/sendMessage", t.cfg.APIURL, t.cfg.BotToken)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		// 错误信息中包含 URL，避免泄露 bot token
+		return fmt.Errorf("telegram request failed: %v", strings.ReplaceAll(err.Error(), t.cfg.BotToken, maskedSecret))
+	}
+	defer resp.Body.Close()
+
+	// Bot API 在失败时返回 {"ok":false,"description":"..."}
+	var result struct {
+		OK          bool   `json:"ok"`
+		Description string `json:"description"`
+	}
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	if err := json.Unmarshal(data, &result); err != nil {
+		return fmt.Errorf("unexpected telegram response (status %d)", resp.StatusCode)
+	}
+	if !result.OK {
+		return fmt.Errorf("telegram error: %s", result.Description)
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package notify
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestTelegramSend(t *testing.T) {
+	var c capture
+	srv := newCaptureServer(t, &c, `{"ok":true,"result":{}}`)
+
+	tg, err := NewTelegram(TelegramConfig{BotToken: "123:abc", ChatID: "42", APIURL: srv.URL + "/", ParseMode: "HTML"})
+	if err != nil {
+		t.Fatalf("NewTelegram: %v", err)
+	}
+	if err := tg.Send(context.Background(), Message{Title: "hello", Body: "world"}); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	if c.path != "/bot123:abc/sendMessage" {
+		t.Errorf("path = %q", c.path)
+	}
+	if c.payload["chat_id"] != "42" || c.payload["text"] != "hello\n\nworld" || c.payload["parse_mode"] != "HTML" {
+		t.Errorf("payload = %v", c.payload)
+	}
+}
+
+func TestTelegramSendAPIError(t *testing.T) {
+	var c capture
+	srv := newCaptureServer(t, &c, `{"ok":false,"description":"Bad Request: chat not found"}`)
+
+	tg, err := NewTelegram(TelegramConfig{BotToken: "123:abc", ChatID: "42", APIURL: srv.URL})
+	if err != nil {
+		t.Fatalf("NewTelegram: %v", err)
+	}
+	err = tg.Send(context.Background(), Message{Body: "world"})
+	if err == nil || !strings.Contains(err.Error(), "chat not found") {
+		t.Fatalf("err = %v, want telegram API error", err)
+	}
+}
+
+func TestTelegramRequestErrorHidesToken(t *testing.T) {
+	// 端口 1 上没有服务，请求失败时错误信息中包含 URL
+	tg, err := NewTelegram(TelegramConfig{BotToken: "123:abc", ChatID: "42", APIURL: "http://127.0.0.1:1"})
+	if err != nil {
+		t.Fatalf("NewTelegram: %v", err)
+	}
+	err = tg.Send(context.Background(), Message{Body: "world"})
+	if err == nil {
+		t.Fatal("expected connection error")
+	}
+	if strings.Contains(err.Error(), "123:abc") {
+		t.Errorf("error leaks bot token: %v", err)
+	}
+}
@@ -0,0 +1,70 @@
+package notify
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// SignatureHeader Webhook 签名请求头，值为 sha256=<hex(HMAC-SHA256(secret, body))>
+const SignatureHeader = "X-CyberUI-Signature"
+
+// WebhookConfig 通用 HTTP Webhook 配置
+type WebhookConfig struct {
+	URL string `json:"url"`
+	// Method 默认为 POST
+	Method string `json:"method"`
+	// Secret 非空时对请求体做 HMAC-SHA256 签名
+	Secret  string            `json:"secret"`
+	Headers map[string]string `json:"headers"`
+}
+
+// Webhook 将消息以 JSON 发送到任意 HTTP 地址
+type Webhook struct {
+	cfg WebhookConfig
+}
+
+// NewWebhook 创建 Webhook 渠道
+func NewWebhook(cfg WebhookConfig) (*Webhook, error) {
+	if !strings.HasPrefix(cfg.URL, "http://") && !strings.HasPrefix(cfg.URL, "https://") {
+		return nil, fmt.Errorf("webhook url must start with http:// or https://")
+	}
+	cfg.Method = strings.ToUpper(cfg.Method)
+	switch cfg.Method {
+	case "":
+		cfg.Method = http.MethodPost
+	case http.MethodPost, http.MethodPut:
+	default:
+		return nil, fmt.Errorf("webhook method must be POST or PUT")
+	}
+	return &Webhook{cfg: cfg}, nil
+}
+
+// Sign 计算请求体的签名，接收方可用同样的方式校验
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Send 发送消息
+func (w *Webhook) Send(ctx context.Context, msg Message) error {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	headers := make(map[string]string, len(w.cfg.Headers)+1)
+	for k, v := range w.cfg.Headers {
+		headers[k] = v
+	}
+	if w.cfg.Secret != "" {
+		headers[SignatureHeader] = Sign(w.cfg.Secret, body)
+	}
+	return doRequest(ctx, w.cfg.Method, w.cfg.URL, body, "application/json", headers)
+}
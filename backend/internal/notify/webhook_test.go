@@ -0,0 +1,113 @@
+package notify
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestWebhookSendSignsBody(t *testing.T) {
+	var (
+		method    string
+		header    http.Header
+		body      []byte
+		requested bool
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = true
+		method = r.Method
+		header = r.Header.Clone()
+		body, _ = io.ReadAll(r.Body)
+	}))
+	defer srv.Close()
+
+	w, err := NewWebhook(WebhookConfig{
+		URL:     srv.URL + "/hook",
+		Method:  "put",
+		Secret:  "s3cret",
+		Headers: map[string]string{"Authorization": "Bearer abc"},
+	})
+	if err != nil {
+		t.Fatalf("NewWebhook: %v", err)
+	}
+	msg := Message{Kind: KindTest, Title: "hello", Body: "world", CarID: 1, Time: time.Unix(0, 0).UTC()}
+	if err := w.Send(context.Background(), msg); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	if !requested {
+		t.Fatal("webhook was not called")
+	}
+	if method != http.MethodPut {
+		t.Errorf("method = %s, want PUT", method)
+	}
+	if got := header.Get("Content-Type"); got != "application/json" {
+		t.Errorf("Content-Type = %q", got)
+	}
+	if got := header.Get("Authorization"); got != "Bearer abc" {
+		t.Errorf("Authorization = %q", got)
+	}
+	mac := hmac.New(sha256.New, []byte("s3cret"))
+	mac.Write(body)
+	if got, want := header.Get(SignatureHeader), "sha256="+hex.EncodeToString(mac.Sum(nil)); got != want {
+		t.Errorf("%s = %q, want %q", SignatureHeader, got, want)
+	}
+
+	var received Message
+	if err := json.Unmarshal(body, &received); err != nil {
+		t.Fatalf("decode body: %v", err)
+	}
+	if received.Title != "hello" || received.Body != "world" || received.Kind != KindTest {
+		t.Errorf("received %+v", received)
+	}
+}
+
+func TestWebhookSendWithoutSecret(t *testing.T) {
+	var signature string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		signature = r.Header.Get(SignatureHeader)
+	}))
+	defer srv.Close()
+
+	w, err := NewWebhook(WebhookConfig{URL: srv.URL})
+	if err != nil {
+		t.Fatalf("NewWebhook: %v", err)
+	}
+	if err := w.Send(context.Background(), Message{Title: "t"}); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if signature != "" {
+		t.Errorf("unexpected signature %q", signature)
+	}
+}
+
+func TestWebhookSendErrorStatus(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "nope", http.StatusBadGateway)
+	}))
+	defer srv.Close()
+
+	w, err := NewWebhook(WebhookConfig{URL: srv.URL})
+	if err != nil {
+		t.Fatalf("NewWebhook: %v", err)
+	}
+	if err := w.Send(context.Background(), Message{Title: "t"}); err == nil {
+		t.Fatal("expected error for 502 response")
+	}
+}
+
+func TestNewWebhookValidation(t *testing.T) {
+	if _, err := NewWebhook(WebhookConfig{URL: "ftp://example.com"}); err == nil {
+		t.Error("expected error for non-http url")
+	}
+	if _, err := NewWebhook(WebhookConfig{URL: "http://example.com", Method: "GET"}); err == nil {
+		t.Error("expected error for GET method")
+	}
+}
@@ -0,0 +1,217 @@
+// Package report 生成可导出的报表，如用于报税或差旅报销的里程日志
+package report
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"strconv"
+	"time"
+
+	"teslamate-cyberui/internal/export"
+	"teslamate-cyberui/internal/model"
+	"teslamate-cyberui/internal/repository"
+)
+
+// 输出格式
+const (
+	FormatJSON = "json"
+	FormatCSV  = "csv"
+	FormatHTML = "html"
+)
+
+// ErrCarNotFound 车辆不存在
+var ErrCarNotFound = errors.New("car not found")
+
+// purposeOrder 汇总中用途的排列顺序，未标记的排在最后
+var purposeOrder = []string{model.DrivePurposeBusiness, model.DrivePurposeCommute, model.DrivePurposePrivate, ""}
+
+var purposeLabels = map[string]string{
+	model.DrivePurposeBusiness: "Business",
+	model.DrivePurposeCommute:  "Commute",
+	model.DrivePurposePrivate:  "Private",
+	"":                         "Untagged",
+}
+
+// ValidPurpose 判断是否为可标记的行程用途
+func ValidPurpose(purpose string) bool {
+	switch purpose {
+	case model.DrivePurposeBusiness, model.DrivePurposePrivate, model.DrivePurposeCommute:
+		return true
+	}
+	return false
+}
+
+// BuildMileageLog 生成车辆在时间范围内的里程日志，车辆不存在时返回 ErrCarNotFound
+// purpose 为空表示全部行程，为 untagged 时只包含未标记的行程
+func BuildMileageLog(ctx context.Context, repo *repository.Repository, carID int16, startDate, endDate *time.Time, purpose string) (*model.MileageLog, error) {
+	car, err := repo.Car.GetByID(ctx, carID)
+	if err != nil {
+		return nil, err
+	}
+	if car == nil {
+		return nil, ErrCarNotFound
+	}
+
+	entries, err := repo.DriveTag.GetMileageLog(ctx, carID, startDate, endDate, purpose)
+	if err != nil {
+		return nil, err
+	}
+
+	log := &model.MileageLog{
+		CarID:       carID,
+		CarName:     car.DisplayName(),
+		StartDate:   startDate,
+		EndDate:     endDate,
+		Purpose:     purpose,
+		GeneratedAt: time.Now().UTC(),
+		ByPurpose:   []model.MileagePurposeTotal{},
+		Entries:     entries,
+	}
+
+	totals := make(map[string]*model.MileagePurposeTotal)
+	for _, e := range entries {
+		log.TotalDistance += e.Distance
+		t, ok := totals[e.Purpose]
+		if !ok {
+			t = &model.MileagePurposeTotal{Purpose: e.Purpose}
+			totals[e.Purpose] = t
+		}
+		t.Count++
+		t.Distance += e.Distance
+	}
+	for _, p := range purposeOrder {
+		if t, ok := totals[p]; ok {
+			log.ByPurpose = append(log.ByPurpose, *t)
+		}
+	}
+	return log, nil
+}
+
+// FileName 导出文件名，如 mileage-log-1-20250101-20251231.csv
+func FileName(log *model.MileageLog, ext string) string {
+	name := fmt.Sprintf("mileage-log-%d", log.CarID)
+	if log.StartDate != nil {
+		name += "-" + log.StartDate.In(time.Local).Format("20060102")
+	}
+	if log.EndDate != nil {
+		name += "-" + log.EndDate.In(time.Local).Format("20060102")
+	}
+	return name + "." + ext
+}
+
+func formatTime(t time.Time) string {
+	return t.In(time.Local).Format("2006-01-02 15:04")
+}
+
+// WriteMileageCSV 以 CSV 输出里程日志，时间为服务器本地时区
+// 开头写入 UTF-8 BOM，便于 Excel 正确识别非 ASCII 地址；地址和备注按文本输出，避免被 Excel 当作公式
+func WriteMileageCSV(w io.Writer, log *model.MileageLog) error {
+	if _, err := w.Write([]byte("\xEF\xBB\xBF")); err != nil {
+		return err
+	}
+	cw := csv.NewWriter(w)
+	header := []string{
+		"Drive ID", "Start", "End", "Start address", "End address",
+		"Odometer start (km)", "Odometer end (km)", "Distance (km)", "Duration (min)", "Purpose", "Note",
+	}
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	for _, e := range log.Entries {
+		record := []string{
+			strconv.FormatInt(e.DriveID, 10),
+			formatTime(e.StartDate),
+			formatTime(e.EndDate),
+			export.EscapeCSVFormula(e.StartAddress),
+			export.EscapeCSVFormula(e.EndAddress),
+			strconv.FormatFloat(e.OdometerStart, 'f', 1, 64),
+			strconv.FormatFloat(e.OdometerEnd, 'f', 1, 64),
+			strconv.FormatFloat(e.Distance, 'f', 1, 64),
+			strconv.Itoa(e.DurationMin),
+			e.Purpose,
+			export.EscapeCSVFormula(e.Note),
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+var mileageTemplate = template.Must(template.New("mileage").Funcs(template.FuncMap{
+	"time":    formatTime,
+	"km":      func(v float64) string { return strconv.FormatFloat(v, 'f', 1, 64) },
+	"purpose": func(p string) string { return purposeLabels[p] },
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Mileage log · {{.Log.CarName}}</title>
+<style>
+body { font-family: -apple-system, 'Segoe UI', Helvetica, Arial, sans-serif; color: #1f2933; font-size: 12px; margin: 24px; }
+h1 { font-size: 20px; margin-bottom: 4px; }
+p.meta { color: #7b8794; margin-top: 0; }
+table { border-collapse: collapse; width: 100%; margin-bottom: 24px; }
+th, td { border-bottom: 1px solid #e4e7eb; padding: 4px 6px; text-align: left; vertical-align: top; }
+th { background: #f5f7fa; }
+td.num, th.num { text-align: right; white-space: nowrap; }
+tfoot td { font-weight: 600; }
+@media print {
+  body { margin: 0; }
+  thead { display: table-header-group; }
+  tr { page-break-inside: avoid; }
+}
+</style>
+</head>
+<body>
+<h1>Mileage log · {{.Log.CarName}}</h1>
+<p class="meta">{{.Range}}{{if .Log.Purpose}} · {{purpose .Log.Purpose}}{{end}} · generated {{time .Log.GeneratedAt}}</p>
+<table>
+<thead><tr><th>Purpose</th><th class="num">Drives</th><th class="num">Distance (km)</th></tr></thead>
+<tbody>
+{{- range .Log.ByPurpose}}
+<tr><td>{{purpose .Purpose}}</td><td class="num">{{.Count}}</td><td class="num">{{km .Distance}}</td></tr>
+{{- end}}
+</tbody>
+<tfoot><tr><td>Total</td><td class="num">{{len .Log.Entries}}</td><td class="num">{{km .Log.TotalDistance}}</td></tr></tfoot>
+</table>
+<table>
+<thead><tr><th>#</th><th>Start</th><th>End</th><th>From</th><th>To</th><th class="num">Odometer start</th><th class="num">Odometer end</th><th class="num">km</th><th>Purpose</th><th>Note</th></tr></thead>
+<tbody>
+{{- range .Log.Entries}}
+<tr><td>{{.DriveID}}</td><td>{{time .StartDate}}</td><td>{{time .EndDate}}</td><td>{{.StartAddress}}</td><td>{{.EndAddress}}</td><td class="num">{{km .OdometerStart}}</td><td class="num">{{km .OdometerEnd}}</td><td class="num">{{km .Distance}}</td><td>{{purpose .Purpose}}</td><td>{{.Note}}</td></tr>
+{{- end}}
+</tbody>
+</table>
+</body>
+</html>
+`))
+
+// MileageHTML 渲染为可打印的 HTML 页面
+func MileageHTML(log *model.MileageLog) (string, error) {
+	rangeText := "All drives"
+	switch {
+	case log.StartDate != nil && log.EndDate != nil:
+		rangeText = formatTime(*log.StartDate) + " – " + formatTime(*log.EndDate)
+	case log.StartDate != nil:
+		rangeText = "Since " + formatTime(*log.StartDate)
+	case log.EndDate != nil:
+		rangeText = "Until " + formatTime(*log.EndDate)
+	}
+
+	var buf bytes.Buffer
+	err := mileageTemplate.Execute(&buf, map[string]interface{}{
+		"Log":   log,
+		"Range": rangeText,
+	})
+	if err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
@@ -0,0 +1,161 @@
+package report
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"strings"
+	"testing"
+	"time"
+
+	"teslamate-cyberui/internal/model"
+	"teslamate-cyberui/internal/repository"
+)
+
+// fakeCars 只实现 GetByID 的车辆仓储
+type fakeCars struct {
+	repository.CarRepository
+	car *model.Car
+}
+
+func (f fakeCars) GetByID(ctx context.Context, id int16) (*model.Car, error) {
+	return f.car, nil
+}
+
+// fakeTags 只实现 GetMileageLog 的行程标记仓储
+type fakeTags struct {
+	repository.DriveTagRepository
+	entries []model.MileageLogEntry
+}
+
+func (f fakeTags) GetMileageLog(ctx context.Context, carID int16, startDate, endDate *time.Time, purpose string) ([]model.MileageLogEntry, error) {
+	return f.entries, nil
+}
+
+func testEntries() []model.MileageLogEntry {
+	start := time.Date(2025, 3, 3, 8, 0, 0, 0, time.UTC)
+	entry := func(id int64, distance float64, purpose, note string) model.MileageLogEntry {
+		return model.MileageLogEntry{
+			DriveID:       id,
+			StartDate:     start.Add(time.Duration(id) * 24 * time.Hour),
+			EndDate:       start.Add(time.Duration(id)*24*time.Hour + time.Hour),
+			StartAddress:  "Home",
+			EndAddress:    "Office",
+			OdometerStart: 1000,
+			OdometerEnd:   1000 + distance,
+			Distance:      distance,
+			DurationMin:   60,
+			Purpose:       purpose,
+			Note:          note,
+		}
+	}
+	return []model.MileageLogEntry{
+		entry(1, 12.5, model.DrivePurposePrivate, ""),
+		entry(2, 40, model.DrivePurposeBusiness, "Client visit"),
+		entry(3, 7.5, "", ""),
+		entry(4, 20, model.DrivePurposeBusiness, ""),
+	}
+}
+
+func TestBuildMileageLogTotals(t *testing.T) {
+	repo := &repository.Repository{
+		Car:      fakeCars{car: &model.Car{ID: 1, Name: sql.NullString{String: "Model 3", Valid: true}}},
+		DriveTag: fakeTags{entries: testEntries()},
+	}
+	log, err := BuildMileageLog(context.Background(), repo, 1, nil, nil, "")
+	if err != nil {
+		t.Fatalf("BuildMileageLog: %v", err)
+	}
+	if log.CarName != "Model 3" || log.TotalDistance != 80 || len(log.Entries) != 4 {
+		t.Errorf("log = %s, %v km, %d entries", log.CarName, log.TotalDistance, len(log.Entries))
+	}
+
+	// 按 business、commute、private、未标记的顺序汇总，没有行程的用途不出现
+	want := []model.MileagePurposeTotal{
+		{Purpose: model.DrivePurposeBusiness, Count: 2, Distance: 60},
+		{Purpose: model.DrivePurposePrivate, Count: 1, Distance: 12.5},
+		{Purpose: "", Count: 1, Distance: 7.5},
+	}
+	if len(log.ByPurpose) != len(want) {
+		t.Fatalf("ByPurpose = %+v, want %+v", log.ByPurpose, want)
+	}
+	for i := range want {
+		if log.ByPurpose[i] != want[i] {
+			t.Errorf("ByPurpose[%d] = %+v, want %+v", i, log.ByPurpose[i], want[i])
+		}
+	}
+}
+
+func TestBuildMileageLogCarNotFound(t *testing.T) {
+	repo := &repository.Repository{Car: fakeCars{}, DriveTag: fakeTags{}}
+	if _, err := BuildMileageLog(context.Background(), repo, 9, nil, nil, ""); err != ErrCarNotFound {
+		t.Errorf("err = %v, want ErrCarNotFound", err)
+	}
+}
+
+func TestWriteMileageCSV(t *testing.T) {
+	entries := testEntries()
+	entries[0].StartAddress = "=HYPERLINK(\"http://evil\",\"Home\")"
+	entries[1].Note = "+1 call"
+	entries[2].EndAddress = "@SUM(A1)"
+	log := &model.MileageLog{CarID: 1, Entries: entries}
+
+	var buf bytes.Buffer
+	if err := WriteMileageCSV(&buf, log); err != nil {
+		t.Fatalf("WriteMileageCSV: %v", err)
+	}
+	out := buf.String()
+	if !strings.HasPrefix(out, "\xEF\xBB\xBF") {
+		t.Fatal("missing UTF-8 BOM")
+	}
+	records, err := csv.NewReader(strings.NewReader(strings.TrimPrefix(out, "\xEF\xBB\xBF"))).ReadAll()
+	if err != nil {
+		t.Fatalf("parse csv: %v", err)
+	}
+	if len(records) != 5 || records[0][0] != "Drive ID" || len(records[0]) != 11 {
+		t.Fatalf("got %d records, header %q", len(records), records[0])
+	}
+	if got := records[1]; got[0] != "1" || got[3] != "'=HYPERLINK(\"http://evil\",\"Home\")" || got[7] != "12.5" || got[9] != model.DrivePurposePrivate {
+		t.Errorf("row 1 = %q", got)
+	}
+	if got := records[2][10]; got != "'+1 call" {
+		t.Errorf("note = %q", got)
+	}
+	if got := records[3][4]; got != "'@SUM(A1)" {
+		t.Errorf("end address = %q", got)
+	}
+}
+
+func TestMileageHTML(t *testing.T) {
+	entries := testEntries()
+	entries[1].Note = "<script>alert(1)</script>"
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	log := &model.MileageLog{
+		CarName:       "Model 3",
+		StartDate:     &start,
+		Purpose:       model.DrivePurposeBusiness,
+		TotalDistance: 80,
+		ByPurpose:     []model.MileagePurposeTotal{{Purpose: model.DrivePurposeBusiness, Count: 2, Distance: 60}},
+		Entries:       entries,
+	}
+
+	html, err := MileageHTML(log)
+	if err != nil {
+		t.Fatalf("MileageHTML: %v", err)
+	}
+	for _, want := range []string{
+		"<title>Mileage log · Model 3</title>",
+		"Since " + formatTime(start) + " · Business",
+		"<td>Business</td><td class=\"num\">2</td><td class=\"num\">60.0</td>",
+		"<td>Total</td><td class=\"num\">4</td><td class=\"num\">80.0</td>",
+		"&lt;script&gt;alert(1)&lt;/script&gt;",
+	} {
+		if !strings.Contains(html, want) {
+			t.Errorf("HTML missing %q", want)
+		}
+	}
+	if strings.Contains(html, "<script>") {
+		t.Error("note is not escaped")
+	}
+}
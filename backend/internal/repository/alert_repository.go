@@ -0,0 +1,423 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"teslamate-cyberui/internal/logger"
+	"teslamate-cyberui/internal/model"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// AlertRepository 告警规则和告警历史仓储接口
+type AlertRepository interface {
+	InitTable() error
+	ListRules(ctx context.Context) ([]model.AlertRule, error)
+	GetRule(ctx context.Context, id int64) (*model.AlertRule, error)
+	CreateRule(ctx context.Context, rule *model.AlertRule) error
+	UpdateRule(ctx context.Context, rule *model.AlertRule) (bool, error)
+	DeleteRule(ctx context.Context, id int64) (bool, error)
+	InsertEvent(ctx context.Context, event *model.AlertEvent) error
+	ListEvents(ctx context.Context, carID *int16, ruleID *int64, page, pageSize int) (*model.ListResponse[model.AlertEvent], error)
+	DeleteEvent(ctx context.Context, id int64) (bool, error)
+	GetLastTriggered(ctx context.Context) (map[int64]map[int16]time.Time, error)
+	GetFinishedCharges(ctx context.Context, carID *int16, after, before time.Time) ([]model.AlertRecord, error)
+	GetFinishedDrives(ctx context.Context, carID *int16, after, before time.Time) ([]model.AlertRecord, error)
+}
+
+type alertRepository struct {
+	db *sqlx.DB
+}
+
+// NewAlertRepository 创建告警仓储
+func NewAlertRepository(db *sqlx.DB) AlertRepository {
+	return &alertRepository{db: db}
+}
+
+// InitTable 创建告警相关的表（与 ui_settings 一样存放在 TeslaMate 数据库中）
+func (r *alertRepository) InitTable() error {
+	schema := `
+	CREATE TABLE IF NOT EXISTS alert_rules (
+		id SERIAL PRIMARY KEY,
+		name TEXT NOT NULL,
+		car_id SMALLINT,
+		source TEXT NOT NULL,
+		conditions JSONB NOT NULL DEFAULT '[]',
+		time_start TEXT,
+		time_end TEXT,
+		debounce_seconds INTEGER NOT NULL DEFAULT 0,
+		cooldown_seconds INTEGER NOT NULL DEFAULT 0,
+		channel_ids JSONB NOT NULL DEFAULT '[]',
+		enabled BOOLEAN NOT NULL DEFAULT TRUE,
+		created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	);
+	CREATE TABLE IF NOT EXISTS alert_events (
+		id SERIAL PRIMARY KEY,
+		rule_id INTEGER REFERENCES alert_rules(id) ON DELETE SET NULL,
+		rule_name TEXT NOT NULL,
+		car_id SMALLINT NOT NULL,
+		source TEXT NOT NULL,
+		message TEXT NOT NULL,
+		details JSONB NOT NULL DEFAULT '{}',
+		triggered_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	);
+	CREATE INDEX IF NOT EXISTS alert_events_triggered_at_idx ON alert_events (triggered_at DESC);
+	`
+	_, err := r.db.Exec(schema)
+	if err != nil {
+		return fmt.Errorf("failed to create alert tables: %w", err)
+	}
+	return nil
+}
+
+const alertRuleColumns = `id, name, car_id, source, conditions, time_start, time_end,
+	debounce_seconds, cooldown_seconds, channel_ids, enabled, created_at, updated_at`
+
+// ListRules 获取全部告警规则
+func (r *alertRepository) ListRules(ctx context.Context) ([]model.AlertRule, error) {
+	rules := []model.AlertRule{}
+	query := fmt.Sprintf(`SELECT %s FROM alert_rules ORDER BY id`, alertRuleColumns)
+	if err := r.db.SelectContext(ctx, &rules, query); err != nil {
+		logger.Errorf("Failed to list alert rules: %v", err)
+		return nil, err
+	}
+	return rules, nil
+}
+
+// GetRule 获取单条告警规则，不存在时返回 nil
+func (r *alertRepository) GetRule(ctx context.Context, id int64) (*model.AlertRule, error) {
+	var rule model.AlertRule
+	query := fmt.Sprintf(`SELECT %s FROM alert_rules WHERE id = $1`, alertRuleColumns)
+	if err := r.db.GetContext(ctx, &rule, query, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		logger.Errorf("Failed to get alert rule %d: %v", id, err)
+		return nil, err
+	}
+	return &rule, nil
+}
+
+// CreateRule 创建告警规则，回填 ID 和时间
+func (r *alertRepository) CreateRule(ctx context.Context, rule *model.AlertRule) error {
+	query := `
+		INSERT INTO alert_rules (name, car_id, source, conditions, time_start, time_end,
+			debounce_seconds, cooldown_seconds, channel_ids, enabled)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		RETURNING id, created_at, updated_at
+	`
+	err := r.db.QueryRowxContext(ctx, query,
+		rule.Name, rule.CarID, rule.Source, rule.Conditions, rule.TimeStart, rule.TimeEnd,
+		rule.DebounceSeconds, rule.CooldownSeconds, rule.ChannelIDs, rule.Enabled,
+	).Scan(&rule.ID, &rule.CreatedAt, &rule.UpdatedAt)
+	if err != nil {
+		logger.Errorf("Failed to create alert rule: %v", err)
+		return err
+	}
+	return nil
+}
+
+// UpdateRule 更新告警规则，规则不存在时返回 false
+func (r *alertRepository) UpdateRule(ctx context.Context, rule *model.AlertRule) (bool, error) {
+	query := `
+		UPDATE alert_rules SET
+			name = $2, car_id = $3, source = $4, conditions = $5, time_start = $6, time_end = $7,
+			debounce_seconds = $8, cooldown_seconds = $9, channel_ids = $10, enabled = $11,
+			updated_at = NOW()
+		WHERE id = $1
+		RETURNING created_at, updated_at
+	`
+	err := r.db.QueryRowxContext(ctx, query,
+		rule.ID, rule.Name, rule.CarID, rule.Source, rule.Conditions, rule.TimeStart, rule.TimeEnd,
+		rule.DebounceSeconds, rule.CooldownSeconds, rule.ChannelIDs, rule.Enabled,
+	).Scan(&rule.CreatedAt, &rule.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		logger.Errorf("Failed to update alert rule %d: %v", rule.ID, err)
+		return false, err
+	}
+	return true, nil
+}
+
+// DeleteRule 删除告警规则，已产生的告警历史保留
+func (r *alertRepository) DeleteRule(ctx context.Context, id int64) (bool, error) {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM alert_rules WHERE id = $1`, id)
+	if err != nil {
+		logger.Errorf("Failed to delete alert rule %d: %v", id, err)
+		return false, err
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
+
+// InsertEvent 写入一条告警历史，回填 ID
+func (r *alertRepository) InsertEvent(ctx context.Context, event *model.AlertEvent) error {
+	details, err := json.Marshal(event.Details)
+	if err != nil {
+		return err
+	}
+	query := `
+		INSERT INTO alert_events (rule_id, rule_name, car_id, source, message, details, triggered_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id
+	`
+	err = r.db.QueryRowxContext(ctx, query,
+		event.RuleID, event.RuleName, event.CarID, event.Source, event.Message, string(details), event.TriggeredAt,
+	).Scan(&event.ID)
+	if err != nil {
+		logger.Errorf("Failed to insert alert event: %v", err)
+		return err
+	}
+	return nil
+}
+
+// ListEvents 分页获取告警历史，按触发时间倒序
+func (r *alertRepository) ListEvents(ctx context.Context, carID *int16, ruleID *int64, page, pageSize int) (*model.ListResponse[model.AlertEvent], error) {
+	whereClause := "WHERE 1=1"
+	args := []interface{}{}
+	argIdx := 1
+
+	if carID != nil {
+		whereClause += fmt.Sprintf(" AND car_id = $%d", argIdx)
+		args = append(args, *carID)
+		argIdx++
+	}
+	if ruleID != nil {
+		whereClause += fmt.Sprintf(" AND rule_id = $%d", argIdx)
+		args = append(args, *ruleID)
+		argIdx++
+	}
+
+	var total int
+	countQuery := fmt.Sprintf(`SELECT COUNT(*) FROM alert_events %s`, whereClause)
+	if err := r.db.GetContext(ctx, &total, countQuery, args...); err != nil {
+		logger.Errorf("Failed to count alert events: %v", err)
+		return nil, err
+	}
+
+	offset := (page - 1) * pageSize
+	query := fmt.Sprintf(`
+		SELECT id, rule_id, rule_name, car_id, source, message, details, triggered_at
+		FROM alert_events
+		%s
+		ORDER BY triggered_at DESC, id DESC
+		LIMIT $%d OFFSET $%d
+	`, whereClause, argIdx, argIdx+1)
+	args = append(args, pageSize, offset)
+
+	rows, err := r.db.QueryxContext(ctx, query, args...)
+	if err != nil {
+		logger.Errorf("Failed to get alert events: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []model.AlertEvent{}
+	for rows.Next() {
+		var row struct {
+			ID          int64         `db:"id"`
+			RuleID      sql.NullInt64 `db:"rule_id"`
+			RuleName    string        `db:"rule_name"`
+			CarID       int16         `db:"car_id"`
+			Source      string        `db:"source"`
+			Message     string        `db:"message"`
+			Details     []byte        `db:"details"`
+			TriggeredAt time.Time     `db:"triggered_at"`
+		}
+		if err := rows.StructScan(&row); err != nil {
+			logger.Errorf("Failed to scan alert event row: %v", err)
+			continue
+		}
+
+		item := model.AlertEvent{
+			ID:          row.ID,
+			RuleName:    row.RuleName,
+			CarID:       row.CarID,
+			Source:      row.Source,
+			Message:     row.Message,
+			TriggeredAt: row.TriggeredAt,
+		}
+		if row.RuleID.Valid {
+			item.RuleID = &row.RuleID.Int64
+		}
+		if err := json.Unmarshal(row.Details, &item.Details); err != nil {
+			item.Details = map[string]string{}
+		}
+		items = append(items, item)
+	}
+
+	return &model.ListResponse[model.AlertEvent]{
+		Items: items,
+		Pagination: model.Pagination{
+			Page:     page,
+			PageSize: pageSize,
+			Total:    &total,
+		},
+	}, nil
+}
+
+// DeleteEvent 删除一条告警历史
+func (r *alertRepository) DeleteEvent(ctx context.Context, id int64) (bool, error) {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM alert_events WHERE id = $1`, id)
+	if err != nil {
+		logger.Errorf("Failed to delete alert event %d: %v", id, err)
+		return false, err
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
+
+// GetLastTriggered 获取每条规则在每辆车上最近一次触发的时间，用于重启后继续遵守冷却时间
+func (r *alertRepository) GetLastTriggered(ctx context.Context) (map[int64]map[int16]time.Time, error) {
+	query := `
+		SELECT rule_id, car_id, MAX(triggered_at) as triggered_at
+		FROM alert_events
+		WHERE rule_id IS NOT NULL
+		GROUP BY rule_id, car_id
+	`
+	rows, err := r.db.QueryxContext(ctx, query)
+	if err != nil {
+		logger.Errorf("Failed to get last alert trigger times: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make(map[int64]map[int16]time.Time)
+	for rows.Next() {
+		var ruleID int64
+		var carID int16
+		var triggeredAt time.Time
+		if err := rows.Scan(&ruleID, &carID, &triggeredAt); err != nil {
+			logger.Errorf("Failed to scan alert trigger row: %v", err)
+			continue
+		}
+		if result[ruleID] == nil {
+			result[ruleID] = make(map[int16]time.Time)
+		}
+		result[ruleID][carID] = triggeredAt
+	}
+	return result, nil
+}
+
+// GetFinishedCharges 获取在 (after, before] 区间内结束的充电记录
+func (r *alertRepository) GetFinishedCharges(ctx context.Context, carID *int16, after, before time.Time) ([]model.AlertRecord, error) {
+	query := `
+		SELECT
+			cp.id,
+			cp.car_id,
+			cp.end_date,
+			cp.charge_energy_added,
+			cp.charge_energy_used,
+			cp.duration_min,
+			cp.start_battery_level,
+			cp.end_battery_level,
+			cp.cost,
+			cp.outside_temp_avg,
+			COALESCE(g.name, a.display_name) as location
+		FROM charging_processes cp
+		LEFT JOIN addresses a ON cp.address_id = a.id
+		LEFT JOIN geofences g ON cp.geofence_id = g.id
+		WHERE cp.end_date > $1 AND cp.end_date <= $2
+			AND ($3::smallint IS NULL OR cp.car_id = $3)
+		ORDER BY cp.end_date
+	`
+	return r.queryAlertRecords(ctx, query, after, before, carID)
+}
+
+// GetFinishedDrives 获取在 (after, before] 区间内结束的行程记录
+func (r *alertRepository) GetFinishedDrives(ctx context.Context, carID *int16, after, before time.Time) ([]model.AlertRecord, error) {
+	query := `
+		SELECT
+			d.id,
+			d.car_id,
+			d.end_date,
+			d.distance,
+			d.duration_min,
+			d.speed_max,
+			d.power_max,
+			d.outside_temp_avg,
+			sp.battery_level as start_battery_level,
+			ep.battery_level as end_battery_level,
+			COALESCE(sg.name, sa.display_name) as start_location,
+			COALESCE(eg.name, ea.display_name) as end_location
+		FROM drives d
+		LEFT JOIN addresses sa ON d.start_address_id = sa.id
+		LEFT JOIN addresses ea ON d.end_address_id = ea.id
+		LEFT JOIN geofences sg ON d.start_geofence_id = sg.id
+		LEFT JOIN geofences eg ON d.end_geofence_id = eg.id
+		LEFT JOIN positions sp ON d.start_position_id = sp.id
+		LEFT JOIN positions ep ON d.end_position_id = ep.id
+		WHERE d.end_date > $1 AND d.end_date <= $2
+			AND ($3::smallint IS NULL OR d.car_id = $3)
+		ORDER BY d.end_date
+	`
+	return r.queryAlertRecords(ctx, query, after, before, carID)
+}
+
+// queryAlertRecords 执行查询并将除 id / car_id / end_date 以外的列转换为字符串字段
+func (r *alertRepository) queryAlertRecords(ctx context.Context, query string, args ...interface{}) ([]model.AlertRecord, error) {
+	rows, err := r.db.QueryxContext(ctx, query, args...)
+	if err != nil {
+		logger.Errorf("Failed to query finished records for alerts: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var records []model.AlertRecord
+	for rows.Next() {
+		row := make(map[string]interface{})
+		if err := rows.MapScan(row); err != nil {
+			logger.Errorf("Failed to scan alert record row: %v", err)
+			continue
+		}
+
+		record := model.AlertRecord{Fields: make(map[string]string, len(row))}
+		for col, v := range row {
+			switch col {
+			case "id":
+				record.ID, _ = v.(int64)
+			case "car_id":
+				if id, ok := v.(int64); ok {
+					record.CarID = int16(id)
+				}
+			case "end_date":
+				record.EndDate, _ = v.(time.Time)
+			default:
+				if v != nil {
+					record.Fields[col] = formatAlertField(v)
+				}
+			}
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+// formatAlertField 将数据库驱动返回的值格式化为字符串（NUMERIC 列以 []byte 返回）
+func formatAlertField(v interface{}) string {
+	switch val := v.(type) {
+	case []byte:
+		return string(val)
+	case string:
+		return val
+	case int64:
+		return strconv.FormatInt(val, 10)
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(val)
+	case time.Time:
+		return val.Format(time.RFC3339)
+	default:
+		return fmt.Sprint(val)
+	}
+}
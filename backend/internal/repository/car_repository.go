@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"time"
 
 	"teslamate-cyberui/internal/logger"
 	"teslamate-cyberui/internal/model"
@@ -78,7 +79,8 @@ func (r *carRepository) GetStatus(ctx context.Context, carID int16) (*model.CarS
 	}
 
 	status := &model.CarStatus{
-		CarID: carID,
+		CarID:   carID,
+		Sources: make(map[string]model.FieldSource),
 	}
 
 	if car.Name.Valid {
@@ -129,6 +131,7 @@ func (r *carRepository) GetStatus(ctx context.Context, carID int16) (*model.CarS
 		status.State = state.State
 		if state.StartDate.Valid {
 			status.Since = &state.StartDate.Time
+			setDBSource(status, state.StartDate.Time, "state", "since", "healthy")
 		}
 	}
 
@@ -179,7 +182,7 @@ func (r *carRepository) GetStatus(ctx context.Context, carID int16) (*model.CarS
 	// 获取最新位置信息
 	positionQuery := `
 		SELECT
-			p.latitude, p.longitude, p.odometer, p.ideal_battery_range_km,
+			p.date, p.latitude, p.longitude, p.odometer, p.ideal_battery_range_km,
 			p.est_battery_range_km, p.rated_battery_range_km, p.usable_battery_level,
 			p.inside_temp, p.outside_temp, p.is_climate_on,
 			g.name as geofence_name
@@ -193,6 +196,7 @@ func (r *carRepository) GetStatus(ctx context.Context, carID int16) (*model.CarS
 		LIMIT 1
 	`
 	var pos struct {
+		Date               sql.NullTime    `db:"date"`
 		Latitude           sql.NullFloat64 `db:"latitude"`
 		Longitude          sql.NullFloat64 `db:"longitude"`
 		Odometer           sql.NullFloat64 `db:"odometer"`
@@ -220,6 +224,9 @@ func (r *carRepository) GetStatus(ctx context.Context, carID int16) (*model.CarS
 		if pos.RatedBatteryRangeKm.Valid {
 			status.RatedRange = pos.RatedBatteryRangeKm.Float64
 		}
+		if pos.EstBatteryRangeKm.Valid {
+			status.EstRange = pos.EstBatteryRangeKm.Float64
+		}
 		if pos.UsableBatteryLevel.Valid {
 			status.UsableBatteryLevel = int(pos.UsableBatteryLevel.Int64)
 		}
@@ -235,21 +242,39 @@ func (r *carRepository) GetStatus(ctx context.Context, carID int16) (*model.CarS
 		if pos.GeofenceName.Valid {
 			status.Geofence = &pos.GeofenceName.String
 		}
+		// 位置记录的时间即为以上字段的数据时间
+		if pos.Date.Valid {
+			setDBSource(status, pos.Date.Time,
+				"latitude", "longitude", "odometer", "batteryLevel", "usableBatteryLevel",
+				"estRange", "ratedRange", "insideTemp", "outsideTemp", "isClimateOn", "geofence")
+		}
 	}
 
 	// 获取软件版本
 	versionQuery := `
-		SELECT version FROM updates
+		SELECT version, start_date FROM updates
 		WHERE car_id = $1
 		ORDER BY start_date DESC
 		LIMIT 1
 	`
-	var version sql.NullString
-	if err := r.db.GetContext(ctx, &version, versionQuery, carID); err == nil && version.Valid {
-		status.SoftwareVersion = version.String
+	var version struct {
+		Version   sql.NullString `db:"version"`
+		StartDate time.Time      `db:"start_date"`
+	}
+	if err := r.db.GetContext(ctx, &version, versionQuery, carID); err == nil && version.Version.Valid {
+		status.SoftwareVersion = version.Version.String
+		setDBSource(status, version.StartDate, "softwareVersion")
 	}
 
+	// 数据库中没有健康状态，按最新状态记录视为正常，数据时间与 state 相同
 	status.Healthy = true
 
 	return status, nil
 }
+
+// setDBSource 将若干字段标记为来自数据库，t 为对应记录的时间
+func setDBSource(status *model.CarStatus, t time.Time, fields ...string) {
+	for _, f := range fields {
+		status.Sources[f] = model.FieldSource{Source: model.FieldSourceDB, UpdatedAt: t}
+	}
+}
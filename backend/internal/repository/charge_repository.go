@@ -10,14 +10,21 @@ import (
 	"teslamate-cyberui/internal/model"
 
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 )
 
 // ChargeRepository 充电数据仓储接口
 type ChargeRepository interface {
-	GetList(ctx context.Context, carID int16, page, pageSize int, startDate, endDate *time.Time) (*model.ListResponse[model.ChargeListItem], error)
+	GetList(ctx context.Context, carID int16, q model.ListQuery) (*model.ListResponse[model.ChargeListItem], error)
 	GetDetail(ctx context.Context, chargeID int64) (*model.ChargeDetail, error)
-	GetStats(ctx context.Context, chargeID int64) (*model.ChargeStats, error)
+	GetCurve(ctx context.Context, chargeID int64) ([]model.ChargeDataPoint, error)
 	GetStatsSummary(ctx context.Context, carID int16, startDate, endDate *time.Time) (*model.ChargeStatsSummary, error)
+	GetBillingRecords(ctx context.Context, carID int16, startDate, endDate *time.Time) ([]model.ChargeBilling, error)
+	GetBillingRecord(ctx context.Context, chargeID int64) (*model.ChargeBilling, error)
+	GetEnergySamples(ctx context.Context, chargeIDs []int64) (map[int64][]model.ChargeEnergySample, error)
+	UpdateCost(ctx context.Context, chargeID int64, cost *float64) (bool, error)
+	EachExportRow(ctx context.Context, carID int16, startDate, endDate *time.Time, fn func(*model.ChargeExportRow) error) error
+	EachSample(ctx context.Context, chargeID int64, fn func(*model.Charge) error) error
 }
 
 type chargeRepository struct {
@@ -29,41 +36,55 @@ func NewChargeRepository(db *sqlx.DB) ChargeRepository {
 	return &chargeRepository{db: db}
 }
 
-// GetList 获取充电记录列表
-func (r *chargeRepository) GetList(ctx context.Context, carID int16, page, pageSize int, startDate, endDate *time.Time) (*model.ListResponse[model.ChargeListItem], error) {
+// chargeSortColumns 充电记录列表的排序字段，没有费用或充电效率的充电按 0 排序
+var chargeSortColumns = map[string]sortColumn{
+	model.SortByStartDate:  {expr: "cp.start_date", cast: "timestamp"},
+	model.SortByDuration:   {expr: "COALESCE(cp.duration_min, 0)::float8", cast: "float8"},
+	model.SortByEnergy:     {expr: "COALESCE(cp.charge_energy_added, 0)::float8", cast: "float8"},
+	model.SortByCost:       {expr: "COALESCE(cp.cost, 0)::float8", cast: "float8"},
+	model.SortByEfficiency: {expr: "COALESCE(cp.charge_energy_added / NULLIF(cp.charge_energy_used, 0), 0)::float8", cast: "float8"},
+}
+
+// GetList 获取充电记录列表，支持页码或游标分页
+func (r *chargeRepository) GetList(ctx context.Context, carID int16, q model.ListQuery) (*model.ListResponse[model.ChargeListItem], error) {
 	// 构建查询条件
 	whereClause := "WHERE cp.car_id = $1"
 	args := []interface{}{carID}
 	argIdx := 2
 
-	if startDate != nil {
+	if q.StartDate != nil {
 		whereClause += fmt.Sprintf(" AND cp.start_date >= $%d", argIdx)
-		args = append(args, *startDate)
+		args = append(args, *q.StartDate)
 		argIdx++
 	}
-	if endDate != nil {
+	if q.EndDate != nil {
 		whereClause += fmt.Sprintf(" AND cp.start_date <= $%d", argIdx)
-		args = append(args, *endDate)
-		argIdx++
+		args = append(args, *q.EndDate)
 	}
 
 	// 获取总数
-	countQuery := fmt.Sprintf(`SELECT COUNT(*) FROM charging_processes cp %s`, whereClause)
-	var total int
-	if err := r.db.GetContext(ctx, &total, countQuery, args...); err != nil {
-		logger.Errorf("Failed to count charges for car %d: %v", carID, err)
-		return nil, err
+	var total *int
+	if q.WithTotal {
+		countQuery := fmt.Sprintf(`SELECT COUNT(*) FROM charging_processes cp %s`, whereClause)
+		var count int
+		if err := r.db.GetContext(ctx, &count, countQuery, args...); err != nil {
+			logger.Errorf("Failed to count charges for car %d: %v", carID, err)
+			return nil, err
+		}
+		total = &count
 	}
 
 	// 获取列表
-	offset := (page - 1) * pageSize
+	sortKey, pageClause, args := keysetPage(q, chargeSortColumns, "cp.id", args)
 	query := fmt.Sprintf(`
 		SELECT 
 			cp.id,
+			%s as sort_key,
 			cp.start_date,
 			cp.end_date,
 			COALESCE(cp.duration_min, 0) as duration_min,
 			COALESCE(cp.charge_energy_added, 0) as charge_energy_added,
+			cp.charge_energy_used,
 			COALESCE(cp.start_battery_level, 0) as start_battery_level,
 			COALESCE(cp.end_battery_level, 0) as end_battery_level,
 			COALESCE(g.name, a.display_name, 'Unknown') as location,
@@ -79,12 +100,8 @@ func (r *chargeRepository) GetList(ctx context.Context, carID int16, page, pageS
 		LEFT JOIN addresses a ON cp.address_id = a.id
 		LEFT JOIN geofences g ON cp.geofence_id = g.id
 		LEFT JOIN positions p ON cp.position_id = p.id
-		%s
-		ORDER BY cp.start_date DESC
-		LIMIT $%d OFFSET $%d
-	`, whereClause, argIdx, argIdx+1)
-
-	args = append(args, pageSize, offset)
+		%s%s
+	`, sortKey, whereClause, pageClause)
 
 	rows, err := r.db.QueryxContext(ctx, query, args...)
 	if err != nil {
@@ -94,13 +111,17 @@ func (r *chargeRepository) GetList(ctx context.Context, carID int16, page, pageS
 	defer rows.Close()
 
 	var items []model.ChargeListItem
+	var keys []string
+	var ids []int64
 	for rows.Next() {
 		var item struct {
 			ID                int64           `db:"id"`
+			SortKey           string          `db:"sort_key"`
 			StartDate         sql.NullTime    `db:"start_date"`
 			EndDate           sql.NullTime    `db:"end_date"`
 			DurationMin       int             `db:"duration_min"`
 			ChargeEnergyAdded float64         `db:"charge_energy_added"`
+			ChargeEnergyUsed  sql.NullFloat64 `db:"charge_energy_used"`
 			StartBatteryLevel int             `db:"start_battery_level"`
 			EndBatteryLevel   int             `db:"end_battery_level"`
 			Location          string          `db:"location"`
@@ -134,6 +155,11 @@ func (r *chargeRepository) GetList(ctx context.Context, carID int16, page, pageS
 		if item.Cost.Valid {
 			listItem.Cost = &item.Cost.Float64
 		}
+		// 与 GetDetail 相同的充电效率计算方式
+		if item.ChargeEnergyUsed.Valid && item.ChargeEnergyUsed.Float64 > 0 && item.ChargeEnergyAdded > 0 {
+			eff := item.ChargeEnergyAdded / item.ChargeEnergyUsed.Float64 * 100
+			listItem.Efficiency = &eff
+		}
 		if item.Latitude.Valid {
 			listItem.Latitude = &item.Latitude.Float64
 		}
@@ -142,14 +168,22 @@ func (r *chargeRepository) GetList(ctx context.Context, carID int16, page, pageS
 		}
 
 		items = append(items, listItem)
+		keys = append(keys, item.SortKey)
+		ids = append(ids, item.ID)
+	}
+
+	cursor := nextCursor(q, keys, ids)
+	if len(items) > q.PageSize {
+		items = items[:q.PageSize]
 	}
 
 	return &model.ListResponse[model.ChargeListItem]{
 		Items: items,
 		Pagination: model.Pagination{
-			Page:     page,
-			PageSize: pageSize,
-			Total:    total,
+			Page:       q.Page,
+			PageSize:   q.PageSize,
+			Total:      total,
+			NextCursor: cursor,
 		},
 	}, nil
 }
@@ -261,8 +295,8 @@ func (r *chargeRepository) GetDetail(ctx context.Context, chargeID int64) (*mode
 	return detail, nil
 }
 
-// GetStats 获取充电过程统计数据
-func (r *chargeRepository) GetStats(ctx context.Context, chargeID int64) (*model.ChargeStats, error) {
+// GetCurve 获取充电过程的全部曲线数据点，按时间排序，采样由调用方决定
+func (r *chargeRepository) GetCurve(ctx context.Context, chargeID int64) ([]model.ChargeDataPoint, error) {
 	query := `
 		SELECT 
 			date,
@@ -279,12 +313,12 @@ func (r *chargeRepository) GetStats(ctx context.Context, chargeID int64) (*model
 
 	rows, err := r.db.QueryxContext(ctx, query, chargeID)
 	if err != nil {
-		logger.Errorf("Failed to get charge stats %d: %v", chargeID, err)
+		logger.Errorf("Failed to get charge curve %d: %v", chargeID, err)
 		return nil, err
 	}
 	defer rows.Close()
 
-	var allPoints []model.ChargeDataPoint
+	allPoints := []model.ChargeDataPoint{}
 	for rows.Next() {
 		var row struct {
 			Date           sql.NullTime    `db:"date"`
@@ -318,50 +352,7 @@ func (r *chargeRepository) GetStats(ctx context.Context, chargeID int64) (*model
 		allPoints = append(allPoints, point)
 	}
 
-	// 采样优化：只保留关键数据点（电量变化点、功率显著变化点）
-	// 这样可以减少锯齿效果，同时保持曲线的关键特征
-	var sampledPoints []model.ChargeDataPoint
-
-	if len(allPoints) > 0 {
-		// 始终保留第一个点
-		sampledPoints = append(sampledPoints, allPoints[0])
-		lastAddedBatteryLevel := allPoints[0].BatteryLevel
-		lastAddedPower := allPoints[0].ChargerPower
-
-		for i := 1; i < len(allPoints)-1; i++ {
-			point := allPoints[i]
-
-			// 保留条件：
-			// 1. 电量变化了（每变化1%保留一个点）
-			// 2. 功率变化超过3kW（捕捉充电功率的显著变化）
-			batteryChanged := point.BatteryLevel != lastAddedBatteryLevel
-			powerChangedSignificantly := abs(point.ChargerPower-lastAddedPower) >= 3
-
-			if batteryChanged || powerChangedSignificantly {
-				sampledPoints = append(sampledPoints, point)
-				lastAddedBatteryLevel = point.BatteryLevel
-				lastAddedPower = point.ChargerPower
-			}
-		}
-
-		// 始终保留最后一个点
-		if len(allPoints) > 1 {
-			sampledPoints = append(sampledPoints, allPoints[len(allPoints)-1])
-		}
-	}
-
-	return &model.ChargeStats{
-		ChargingProcessID: chargeID,
-		DataPoints:        sampledPoints,
-	}, nil
-}
-
-// abs 返回整数的绝对值
-func abs(x int) int {
-	if x < 0 {
-		return -x
-	}
-	return x
+	return allPoints, nil
 }
 
 // GetStatsSummary 获取充电统计概览（包括总计、每日热力图数据、位置热力图数据）
@@ -492,3 +483,332 @@ func (r *chargeRepository) GetStatsSummary(ctx context.Context, carID int16, sta
 
 	return summary, nil
 }
+
+// GetBillingRecords 获取时间范围内已结束的充电记录，用于按电价方案计算费用
+func (r *chargeRepository) GetBillingRecords(ctx context.Context, carID int16, startDate, endDate *time.Time) ([]model.ChargeBilling, error) {
+	whereClause := "WHERE cp.car_id = $1 AND cp.end_date IS NOT NULL"
+	args := []interface{}{carID}
+	argIdx := 2
+	if startDate != nil {
+		whereClause += fmt.Sprintf(" AND cp.start_date >= $%d", argIdx)
+		args = append(args, *startDate)
+		argIdx++
+	}
+	if endDate != nil {
+		whereClause += fmt.Sprintf(" AND cp.start_date <= $%d", argIdx)
+		args = append(args, *endDate)
+		argIdx++
+	}
+	return r.queryBilling(ctx, whereClause, args...)
+}
+
+// GetBillingRecord 获取单次已结束充电的计费信息，不存在或未结束时返回 nil
+func (r *chargeRepository) GetBillingRecord(ctx context.Context, chargeID int64) (*model.ChargeBilling, error) {
+	records, err := r.queryBilling(ctx, "WHERE cp.id = $1 AND cp.end_date IS NOT NULL", chargeID)
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+	return &records[0], nil
+}
+
+func (r *chargeRepository) queryBilling(ctx context.Context, whereClause string, args ...interface{}) ([]model.ChargeBilling, error) {
+	query := fmt.Sprintf(`
+		SELECT
+			cp.id,
+			cp.car_id,
+			cp.start_date,
+			cp.end_date,
+			cp.geofence_id,
+			COALESCE(g.name, a.display_name, 'Unknown') as location,
+			COALESCE(cp.charge_energy_added, 0)::float8 as charge_energy_added,
+			cp.charge_energy_used::float8 as charge_energy_used,
+			cp.cost::float8 as cost
+		FROM charging_processes cp
+		LEFT JOIN addresses a ON cp.address_id = a.id
+		LEFT JOIN geofences g ON cp.geofence_id = g.id
+		%s
+		ORDER BY cp.start_date
+	`, whereClause)
+
+	rows, err := r.db.QueryxContext(ctx, query, args...)
+	if err != nil {
+		logger.Errorf("Failed to get charge billing records: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	records := []model.ChargeBilling{}
+	for rows.Next() {
+		var row struct {
+			ID          int64           `db:"id"`
+			CarID       int16           `db:"car_id"`
+			StartDate   time.Time       `db:"start_date"`
+			EndDate     time.Time       `db:"end_date"`
+			GeofenceID  sql.NullInt64   `db:"geofence_id"`
+			Location    string          `db:"location"`
+			EnergyAdded float64         `db:"charge_energy_added"`
+			EnergyUsed  sql.NullFloat64 `db:"charge_energy_used"`
+			Cost        sql.NullFloat64 `db:"cost"`
+		}
+		if err := rows.StructScan(&row); err != nil {
+			logger.Warnf("Failed to scan charge billing record: %v", err)
+			continue
+		}
+
+		record := model.ChargeBilling{
+			ID:          row.ID,
+			CarID:       row.CarID,
+			StartDate:   row.StartDate,
+			EndDate:     row.EndDate,
+			Location:    row.Location,
+			EnergyAdded: row.EnergyAdded,
+		}
+		if row.GeofenceID.Valid {
+			record.GeofenceID = &row.GeofenceID.Int64
+		}
+		if row.EnergyUsed.Valid {
+			record.EnergyUsed = &row.EnergyUsed.Float64
+		}
+		if row.Cost.Valid {
+			record.Cost = &row.Cost.Float64
+		}
+		records = append(records, record)
+	}
+
+	return records, nil
+}
+
+// GetEnergySamples 获取充电过程的累计充入电量采样，按时间排序
+func (r *chargeRepository) GetEnergySamples(ctx context.Context, chargeIDs []int64) (map[int64][]model.ChargeEnergySample, error) {
+	samples := make(map[int64][]model.ChargeEnergySample, len(chargeIDs))
+	if len(chargeIDs) == 0 {
+		return samples, nil
+	}
+
+	query := `
+		SELECT charging_process_id, date, charge_energy_added::float8
+		FROM charges
+		WHERE charging_process_id = ANY($1) AND charge_energy_added IS NOT NULL
+		ORDER BY charging_process_id, date
+	`
+	rows, err := r.db.QueryxContext(ctx, query, pq.Array(chargeIDs))
+	if err != nil {
+		logger.Errorf("Failed to get charge energy samples: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var chargeID int64
+		var sample model.ChargeEnergySample
+		if err := rows.Scan(&chargeID, &sample.Date, &sample.EnergyAdded); err != nil {
+			logger.Warnf("Failed to scan charge energy sample: %v", err)
+			continue
+		}
+		samples[chargeID] = append(samples[chargeID], sample)
+	}
+
+	return samples, nil
+}
+
+// UpdateCost 写回 TeslaMate 的充电费用，cost 为空时清除；充电不存在时返回 false
+func (r *chargeRepository) UpdateCost(ctx context.Context, chargeID int64, cost *float64) (bool, error) {
+	res, err := r.db.ExecContext(ctx, `UPDATE charging_processes SET cost = $2 WHERE id = $1`, chargeID, cost)
+	if err != nil {
+		logger.Errorf("Failed to update cost of charge %d: %v", chargeID, err)
+		return false, err
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
+
+// EachExportRow 按开始时间升序逐行读取充电记录并交给 fn 处理，用于流式导出
+// fn 返回错误时停止读取并返回该错误
+func (r *chargeRepository) EachExportRow(ctx context.Context, carID int16, startDate, endDate *time.Time, fn func(*model.ChargeExportRow) error) error {
+	whereClause := "WHERE cp.car_id = $1"
+	args := []interface{}{carID}
+	argIdx := 2
+
+	if startDate != nil {
+		whereClause += fmt.Sprintf(" AND cp.start_date >= $%d", argIdx)
+		args = append(args, *startDate)
+		argIdx++
+	}
+	if endDate != nil {
+		whereClause += fmt.Sprintf(" AND cp.start_date <= $%d", argIdx)
+		args = append(args, *endDate)
+	}
+
+	query := fmt.Sprintf(`
+		SELECT
+			cp.id,
+			cp.start_date,
+			cp.end_date,
+			COALESCE(cp.duration_min, 0) as duration_min,
+			COALESCE(g.name, a.display_name, 'Unknown') as location,
+			p.latitude,
+			p.longitude,
+			p.odometer,
+			CASE WHEN NULLIF((
+				SELECT mode() WITHIN GROUP (ORDER BY c.charger_phases)
+				FROM charges c
+				WHERE c.charging_process_id = cp.id
+			), 0) IS NULL THEN 'DC' ELSE 'AC' END as charge_type,
+			COALESCE(cp.charge_energy_added, 0) as charge_energy_added,
+			cp.charge_energy_used,
+			cp.cost,
+			COALESCE(cp.start_battery_level, 0) as start_battery_level,
+			COALESCE(cp.end_battery_level, 0) as end_battery_level,
+			COALESCE(cp.start_ideal_range_km, 0) as start_ideal_range_km,
+			COALESCE(cp.end_ideal_range_km, 0) as end_ideal_range_km,
+			COALESCE(cp.start_rated_range_km, 0) as start_rated_range_km,
+			COALESCE(cp.end_rated_range_km, 0) as end_rated_range_km,
+			cp.outside_temp_avg
+		FROM charging_processes cp
+		LEFT JOIN addresses a ON cp.address_id = a.id
+		LEFT JOIN geofences g ON cp.geofence_id = g.id
+		LEFT JOIN positions p ON cp.position_id = p.id
+		%s
+		ORDER BY cp.start_date
+	`, whereClause)
+
+	rows, err := r.db.QueryxContext(ctx, query, args...)
+	if err != nil {
+		logger.Errorf("Failed to export charges for car %d: %v", carID, err)
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var row struct {
+			ID                int64           `db:"id"`
+			StartDate         sql.NullTime    `db:"start_date"`
+			EndDate           sql.NullTime    `db:"end_date"`
+			DurationMin       int             `db:"duration_min"`
+			Location          string          `db:"location"`
+			Latitude          sql.NullFloat64 `db:"latitude"`
+			Longitude         sql.NullFloat64 `db:"longitude"`
+			Odometer          sql.NullFloat64 `db:"odometer"`
+			ChargeType        string          `db:"charge_type"`
+			ChargeEnergyAdded float64         `db:"charge_energy_added"`
+			ChargeEnergyUsed  sql.NullFloat64 `db:"charge_energy_used"`
+			Cost              sql.NullFloat64 `db:"cost"`
+			StartBatteryLevel int             `db:"start_battery_level"`
+			EndBatteryLevel   int             `db:"end_battery_level"`
+			StartIdealRangeKm float64         `db:"start_ideal_range_km"`
+			EndIdealRangeKm   float64         `db:"end_ideal_range_km"`
+			StartRatedRangeKm float64         `db:"start_rated_range_km"`
+			EndRatedRangeKm   float64         `db:"end_rated_range_km"`
+			OutsideTempAvg    sql.NullFloat64 `db:"outside_temp_avg"`
+		}
+		if err := rows.StructScan(&row); err != nil {
+			logger.Warnf("Failed to scan charge export row: %v", err)
+			continue
+		}
+
+		item := &model.ChargeExportRow{
+			ID:                row.ID,
+			DurationMin:       row.DurationMin,
+			Location:          row.Location,
+			ChargeType:        row.ChargeType,
+			ChargeEnergyAdded: row.ChargeEnergyAdded,
+			StartBatteryLevel: row.StartBatteryLevel,
+			EndBatteryLevel:   row.EndBatteryLevel,
+			StartIdealRangeKm: row.StartIdealRangeKm,
+			EndIdealRangeKm:   row.EndIdealRangeKm,
+			StartRatedRangeKm: row.StartRatedRangeKm,
+			EndRatedRangeKm:   row.EndRatedRangeKm,
+		}
+		if row.StartDate.Valid {
+			item.StartDate = row.StartDate.Time
+		}
+		if row.EndDate.Valid {
+			item.EndDate = &row.EndDate.Time
+		}
+		if row.Latitude.Valid {
+			item.Latitude = &row.Latitude.Float64
+		}
+		if row.Longitude.Valid {
+			item.Longitude = &row.Longitude.Float64
+		}
+		if row.Odometer.Valid {
+			item.Odometer = &row.Odometer.Float64
+		}
+		if row.ChargeEnergyUsed.Valid {
+			item.ChargeEnergyUsed = &row.ChargeEnergyUsed.Float64
+		}
+		if row.Cost.Valid {
+			item.Cost = &row.Cost.Float64
+		}
+		if row.OutsideTempAvg.Valid {
+			item.OutsideTempAvg = &row.OutsideTempAvg.Float64
+		}
+
+		// 与 GetDetail 相同的充电效率计算方式
+		if item.ChargeEnergyUsed != nil && *item.ChargeEnergyUsed > 0 && item.ChargeEnergyAdded > 0 {
+			eff := item.ChargeEnergyAdded / *item.ChargeEnergyUsed * 100
+			item.Efficiency = &eff
+		}
+
+		if err := fn(item); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+// EachSample 按时间顺序逐条读取充电过程的原始采样（charges 表），不做任何采样精简
+func (r *chargeRepository) EachSample(ctx context.Context, chargeID int64, fn func(*model.Charge) error) error {
+	query := `
+		SELECT
+			id,
+			date,
+			battery_level,
+			usable_battery_level,
+			charge_energy_added,
+			charger_actual_current,
+			charger_phases,
+			charger_pilot_current,
+			charger_power,
+			charger_voltage,
+			ideal_battery_range_km,
+			not_enough_power_to_heat,
+			rated_battery_range_km,
+			outside_temp,
+			charging_process_id,
+			battery_heater,
+			battery_heater_on,
+			battery_heater_no_power,
+			conn_charge_cable,
+			fast_charger_brand,
+			fast_charger_present,
+			fast_charger_type
+		FROM charges
+		WHERE charging_process_id = $1
+		ORDER BY date
+	`
+
+	rows, err := r.db.QueryxContext(ctx, query, chargeID)
+	if err != nil {
+		logger.Errorf("Failed to get charge samples %d: %v", chargeID, err)
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var sample model.Charge
+		if err := rows.StructScan(&sample); err != nil {
+			logger.Warnf("Failed to scan charge sample: %v", err)
+			continue
+		}
+		if err := fn(&sample); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
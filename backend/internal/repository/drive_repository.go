@@ -143,57 +143,88 @@ func getEfficiencyByModel(model string, marketingName string) float64 {
 	return defaultEfficiency
 }
 
+// getPreferredRange 获取 TeslaMate 设置中的续航类型（ideal / rated）
+func getPreferredRange(ctx context.Context, db *sqlx.DB) string {
+	var preferredRange sql.NullString
+	query := `SELECT preferred_range FROM settings ORDER BY id DESC LIMIT 1`
+	if err := db.GetContext(ctx, &preferredRange, query); err == nil && preferredRange.String == "rated" {
+		return "rated"
+	}
+	return "ideal"
+}
+
 // DriveRepository 驾驶数据仓储接口
 type DriveRepository interface {
-	GetList(ctx context.Context, carID int16, page, pageSize int, startDate, endDate *time.Time) (*model.ListResponse[model.DriveListItem], error)
+	GetList(ctx context.Context, carID int16, q model.ListQuery) (*model.ListResponse[model.DriveListItem], error)
 	GetDetail(ctx context.Context, driveID int64) (*model.DriveDetail, error)
 	GetPositions(ctx context.Context, driveID int64) ([]model.DrivePosition, error)
 	GetAllDrivesPositions(ctx context.Context, carID int16, startDate, endDate *time.Time) ([]model.DriveTrack, error)
+	GetIDs(ctx context.Context, carID int16, startDate, endDate *time.Time) ([]int64, error)
 	GetStatsSummary(ctx context.Context, carID int16, startDate, endDate *time.Time) (*model.DriveStatsSummary, error)
 	GetSpeedHistogram(ctx context.Context, carID int16, startDate, endDate *time.Time) ([]model.SpeedHistogramItem, error)
 	GetDriveSpeedHistogram(ctx context.Context, driveID int64) ([]model.SpeedHistogramItem, error)
 }
 
 type driveRepository struct {
-	db *sqlx.DB
+	db         *sqlx.DB
+	efficiency EfficiencyProvider
 }
 
 // NewDriveRepository 创建驾驶仓储
-func NewDriveRepository(db *sqlx.DB) DriveRepository {
-	return &driveRepository{db: db}
+func NewDriveRepository(db *sqlx.DB, efficiency EfficiencyProvider) DriveRepository {
+	return &driveRepository{db: db, efficiency: efficiency}
+}
+
+// driveSortColumns 驾驶记录列表的排序字段，rangeType 为续航类型
+// 能耗和能效按续航消耗排序，同一辆车的能效系数相同，排序结果与展示值一致
+func driveSortColumns(rangeType string) map[string]sortColumn {
+	rangeUsed := fmt.Sprintf("(d.start_%[1]s_range_km - d.end_%[1]s_range_km)", rangeType)
+	return map[string]sortColumn{
+		model.SortByStartDate:  {expr: "d.start_date", cast: "timestamp"},
+		model.SortByDistance:   {expr: "COALESCE(d.distance, 0)::float8", cast: "float8"},
+		model.SortByDuration:   {expr: "COALESCE(d.duration_min, 0)::float8", cast: "float8"},
+		model.SortByEnergy:     {expr: fmt.Sprintf("GREATEST(COALESCE(%s, 0), 0)::float8", rangeUsed), cast: "float8"},
+		model.SortByEfficiency: {expr: fmt.Sprintf("(CASE WHEN d.distance > 0 THEN GREATEST(COALESCE(%s / d.distance, 0), 0) ELSE 0 END)::float8", rangeUsed), cast: "float8"},
+		model.SortBySpeedMax:   {expr: "COALESCE(d.speed_max, 0)::float8", cast: "float8"},
+	}
 }
 
-// GetList 获取驾驶记录列表
-func (r *driveRepository) GetList(ctx context.Context, carID int16, page, pageSize int, startDate, endDate *time.Time) (*model.ListResponse[model.DriveListItem], error) {
+// GetList 获取驾驶记录列表，支持页码或游标分页
+func (r *driveRepository) GetList(ctx context.Context, carID int16, q model.ListQuery) (*model.ListResponse[model.DriveListItem], error) {
 	// 构建查询条件
 	whereClause := "WHERE d.car_id = $1"
 	args := []interface{}{carID}
 	argIdx := 2
 
-	if startDate != nil {
+	if q.StartDate != nil {
 		whereClause += fmt.Sprintf(" AND d.start_date >= $%d", argIdx)
-		args = append(args, *startDate)
+		args = append(args, *q.StartDate)
 		argIdx++
 	}
-	if endDate != nil {
+	if q.EndDate != nil {
 		whereClause += fmt.Sprintf(" AND d.start_date <= $%d", argIdx)
-		args = append(args, *endDate)
-		argIdx++
+		args = append(args, *q.EndDate)
 	}
 
 	// 获取总数
-	countQuery := fmt.Sprintf(`SELECT COUNT(*) FROM drives d %s`, whereClause)
-	var total int
-	if err := r.db.GetContext(ctx, &total, countQuery, args...); err != nil {
-		logger.Errorf("Failed to count drives for car %d: %v", carID, err)
-		return nil, err
+	var total *int
+	if q.WithTotal {
+		countQuery := fmt.Sprintf(`SELECT COUNT(*) FROM drives d %s`, whereClause)
+		var count int
+		if err := r.db.GetContext(ctx, &count, countQuery, args...); err != nil {
+			logger.Errorf("Failed to count drives for car %d: %v", carID, err)
+			return nil, err
+		}
+		total = &count
 	}
 
-	// 获取列表
-	offset := (page - 1) * pageSize
+	// 获取列表，续航消耗按 TeslaMate 设置使用 ideal / rated 续航，与车辆能效系数的标定口径一致
+	rangeType := getPreferredRange(ctx, r.db)
+	sortKey, pageClause, args := keysetPage(q, driveSortColumns(rangeType), "d.id", args)
 	query := fmt.Sprintf(`
 		SELECT
 			d.id,
+			%[1]s as sort_key,
 			d.start_date,
 			d.end_date,
 			COALESCE(d.duration_min, 0) as duration_min,
@@ -203,24 +234,16 @@ func (r *driveRepository) GetList(ctx context.Context, carID int16, page, pageSi
 			COALESCE(sp.battery_level, 0) as start_battery_level,
 			COALESCE(ep.battery_level, 0) as end_battery_level,
 			COALESCE(d.speed_max, 0) as speed_max,
-			d.start_ideal_range_km,
-			d.end_ideal_range_km,
-			c.model as car_model,
-			c.marketing_name as car_marketing_name
+			(d.start_%[4]s_range_km - d.end_%[4]s_range_km)::float8 as range_used
 		FROM drives d
-		LEFT JOIN cars c ON c.id = d.car_id
 		LEFT JOIN addresses sa ON d.start_address_id = sa.id
 		LEFT JOIN addresses ea ON d.end_address_id = ea.id
 		LEFT JOIN geofences sg ON d.start_geofence_id = sg.id
 		LEFT JOIN geofences eg ON d.end_geofence_id = eg.id
 		LEFT JOIN positions sp ON d.start_position_id = sp.id
 		LEFT JOIN positions ep ON d.end_position_id = ep.id
-		%s
-		ORDER BY d.start_date DESC
-		LIMIT $%d OFFSET $%d
-	`, whereClause, argIdx, argIdx+1)
-
-	args = append(args, pageSize, offset)
+		%[2]s%[3]s
+	`, sortKey, whereClause, pageClause, rangeType)
 
 	rows, err := r.db.QueryxContext(ctx, query, args...)
 	if err != nil {
@@ -229,10 +252,16 @@ func (r *driveRepository) GetList(ctx context.Context, carID int16, page, pageSi
 	}
 	defer rows.Close()
 
+	// 列表中的行程都属于同一辆车，能效系数只需获取一次
+	carEfficiency := r.efficiency.Get(ctx, carID)
+
 	var items []model.DriveListItem
+	var keys []string
+	var ids []int64
 	for rows.Next() {
 		var row struct {
 			ID                int64           `db:"id"`
+			SortKey           string          `db:"sort_key"`
 			StartDate         sql.NullTime    `db:"start_date"`
 			EndDate           sql.NullTime    `db:"end_date"`
 			DurationMin       int             `db:"duration_min"`
@@ -242,10 +271,7 @@ func (r *driveRepository) GetList(ctx context.Context, carID int16, page, pageSi
 			StartBatteryLevel int             `db:"start_battery_level"`
 			EndBatteryLevel   int             `db:"end_battery_level"`
 			SpeedMax          int             `db:"speed_max"`
-			StartIdealRangeKm sql.NullFloat64 `db:"start_ideal_range_km"`
-			EndIdealRangeKm   sql.NullFloat64 `db:"end_ideal_range_km"`
-			CarModel          sql.NullString  `db:"car_model"`
-			CarMarketingName  sql.NullString  `db:"car_marketing_name"`
+			RangeUsed         sql.NullFloat64 `db:"range_used"`
 		}
 
 		if err := rows.StructScan(&row); err != nil {
@@ -262,6 +288,7 @@ func (r *driveRepository) GetList(ctx context.Context, carID int16, page, pageSi
 			StartBatteryLevel: row.StartBatteryLevel,
 			EndBatteryLevel:   row.EndBatteryLevel,
 			SpeedMax:          row.SpeedMax,
+			EfficiencySource:  carEfficiency.Source,
 		}
 
 		if row.StartDate.Valid {
@@ -274,41 +301,38 @@ func (r *driveRepository) GetList(ctx context.Context, carID int16, page, pageSi
 		// 计算能效 (Wh/km)
 		// 公式: (续航消耗 / 行驶距离) * 车辆能效系数 * 1000
 		// 车辆能效系数单位: kWh/km，乘以1000后转为 Wh/km
-		// 根据车型硬编码能效系数
-		carModel := ""
-		carMarketingName := ""
-		if row.CarModel.Valid {
-			carModel = row.CarModel.String
-		}
-		if row.CarMarketingName.Valid {
-			carMarketingName = row.CarMarketingName.String
-		}
-		carEfficiency := getEfficiencyByModel(carModel, carMarketingName)
-		if row.Distance > 0 && row.StartIdealRangeKm.Valid && row.EndIdealRangeKm.Valid {
-			rangeUsed := row.StartIdealRangeKm.Float64 - row.EndIdealRangeKm.Float64
-			if rangeUsed > 0 {
-				item.Efficiency = rangeUsed / row.Distance * carEfficiency * 1000
-			}
+		if row.Distance > 0 && row.RangeUsed.Valid && row.RangeUsed.Float64 > 0 {
+			item.Efficiency = row.RangeUsed.Float64 / row.Distance * carEfficiency.Value * 1000
 		}
 
 		items = append(items, item)
+		keys = append(keys, row.SortKey)
+		ids = append(ids, row.ID)
+	}
+
+	cursor := nextCursor(q, keys, ids)
+	if len(items) > q.PageSize {
+		items = items[:q.PageSize]
 	}
 
 	return &model.ListResponse[model.DriveListItem]{
 		Items: items,
 		Pagination: model.Pagination{
-			Page:     page,
-			PageSize: pageSize,
-			Total:    total,
+			Page:       q.Page,
+			PageSize:   q.PageSize,
+			Total:      total,
+			NextCursor: cursor,
 		},
 	}, nil
 }
 
 // GetDetail 获取驾驶详情
 func (r *driveRepository) GetDetail(ctx context.Context, driveID int64) (*model.DriveDetail, error) {
-	query := `
+	rangeType := getPreferredRange(ctx, r.db)
+	query := fmt.Sprintf(`
 		SELECT
 			d.id,
+			d.car_id,
 			d.start_date,
 			d.end_date,
 			COALESCE(d.duration_min, 0) as duration_min,
@@ -323,15 +347,15 @@ func (r *driveRepository) GetDetail(ctx context.Context, driveID int64) (*model.
 			COALESCE(ep.battery_level, 0) as end_battery_level,
 			COALESCE(d.start_ideal_range_km, 0) as start_ideal_range_km,
 			COALESCE(d.end_ideal_range_km, 0) as end_ideal_range_km,
+			COALESCE(d.start_%[1]s_range_km - d.end_%[1]s_range_km, 0)::float8 as range_used,
 			COALESCE(d.speed_max, 0) as speed_max,
 			COALESCE(d.power_max, 0) as power_max,
 			COALESCE(d.power_min, 0) as power_min,
 			d.outside_temp_avg,
 			d.inside_temp_avg,
-			c.model as car_model,
-			c.marketing_name as car_marketing_name
+			d.ascent,
+			d.descent
 		FROM drives d
-		LEFT JOIN cars c ON c.id = d.car_id
 		LEFT JOIN addresses sa ON d.start_address_id = sa.id
 		LEFT JOIN addresses ea ON d.end_address_id = ea.id
 		LEFT JOIN geofences sg ON d.start_geofence_id = sg.id
@@ -339,10 +363,11 @@ func (r *driveRepository) GetDetail(ctx context.Context, driveID int64) (*model.
 		LEFT JOIN positions sp ON d.start_position_id = sp.id
 		LEFT JOIN positions ep ON d.end_position_id = ep.id
 		WHERE d.id = $1
-	`
+	`, rangeType)
 
 	var row struct {
 		ID                int64           `db:"id"`
+		CarID             int16           `db:"car_id"`
 		StartDate         sql.NullTime    `db:"start_date"`
 		EndDate           sql.NullTime    `db:"end_date"`
 		DurationMin       int             `db:"duration_min"`
@@ -357,13 +382,14 @@ func (r *driveRepository) GetDetail(ctx context.Context, driveID int64) (*model.
 		EndBatteryLevel   int             `db:"end_battery_level"`
 		StartIdealRangeKm float64         `db:"start_ideal_range_km"`
 		EndIdealRangeKm   float64         `db:"end_ideal_range_km"`
+		RangeUsed         float64         `db:"range_used"`
 		SpeedMax          int             `db:"speed_max"`
 		PowerMax          int             `db:"power_max"`
 		PowerMin          int             `db:"power_min"`
 		OutsideTempAvg    sql.NullFloat64 `db:"outside_temp_avg"`
 		InsideTempAvg     sql.NullFloat64 `db:"inside_temp_avg"`
-		CarModel          sql.NullString  `db:"car_model"`
-		CarMarketingName  sql.NullString  `db:"car_marketing_name"`
+		Ascent            sql.NullInt64   `db:"ascent"`
+		Descent           sql.NullInt64   `db:"descent"`
 	}
 
 	if err := r.db.GetContext(ctx, &row, query, driveID); err != nil {
@@ -405,6 +431,14 @@ func (r *driveRepository) GetDetail(ctx context.Context, driveID int64) (*model.
 	if row.InsideTempAvg.Valid {
 		detail.InsideTempAvg = &row.InsideTempAvg.Float64
 	}
+	if row.Ascent.Valid {
+		ascent := int(row.Ascent.Int64)
+		detail.Ascent = &ascent
+	}
+	if row.Descent.Valid {
+		descent := int(row.Descent.Int64)
+		detail.Descent = &descent
+	}
 
 	// 计算平均速度
 	if row.DurationMin > 0 {
@@ -412,21 +446,10 @@ func (r *driveRepository) GetDetail(ctx context.Context, driveID int64) (*model.
 	}
 
 	// 计算能效 (Wh/km)
-	// 根据车型获取能效系数
-	carModel := ""
-	carMarketingName := ""
-	if row.CarModel.Valid {
-		carModel = row.CarModel.String
-	}
-	if row.CarMarketingName.Valid {
-		carMarketingName = row.CarMarketingName.String
-	}
-	carEfficiency := getEfficiencyByModel(carModel, carMarketingName)
-	if row.Distance > 0 {
-		rangeUsed := row.StartIdealRangeKm - row.EndIdealRangeKm
-		if rangeUsed > 0 {
-			detail.Efficiency = rangeUsed / row.Distance * carEfficiency * 1000
-		}
+	carEfficiency := r.efficiency.Get(ctx, row.CarID)
+	detail.EfficiencySource = carEfficiency.Source
+	if row.Distance > 0 && row.RangeUsed > 0 {
+		detail.Efficiency = row.RangeUsed / row.Distance * carEfficiency.Value * 1000
 	}
 
 	return detail, nil
@@ -443,6 +466,7 @@ func (r *driveRepository) GetPositions(ctx context.Context, driveID int64) ([]mo
 			COALESCE(power, 0) as power,
 			COALESCE(battery_level, 0) as battery_level,
 			elevation,
+			odometer,
 			outside_temp,
 			inside_temp,
 			tpms_pressure_fl,
@@ -471,6 +495,7 @@ func (r *driveRepository) GetPositions(ctx context.Context, driveID int64) ([]mo
 			Power          int             `db:"power"`
 			BatteryLevel   int             `db:"battery_level"`
 			Elevation      sql.NullInt64   `db:"elevation"`
+			Odometer       sql.NullFloat64 `db:"odometer"`
 			OutsideTemp    sql.NullFloat64 `db:"outside_temp"`
 			InsideTemp     sql.NullFloat64 `db:"inside_temp"`
 			TpmsPressureFL sql.NullFloat64 `db:"tpms_pressure_fl"`
@@ -498,6 +523,9 @@ func (r *driveRepository) GetPositions(ctx context.Context, driveID int64) ([]mo
 			elev := int(row.Elevation.Int64)
 			pos.Elevation = &elev
 		}
+		if row.Odometer.Valid {
+			pos.Odometer = &row.Odometer.Float64
+		}
 		if row.OutsideTemp.Valid {
 			pos.OutsideTemp = &row.OutsideTemp.Float64
 		}
@@ -668,6 +696,32 @@ func (r *driveRepository) GetAllDrivesPositions(ctx context.Context, carID int16
 	return tracks, nil
 }
 
+// GetIDs 获取时间范围内已结束行程的 ID，按开始时间升序
+func (r *driveRepository) GetIDs(ctx context.Context, carID int16, startDate, endDate *time.Time) ([]int64, error) {
+	whereClause := "WHERE car_id = $1 AND end_date IS NOT NULL"
+	args := []interface{}{carID}
+	argIdx := 2
+
+	if startDate != nil {
+		whereClause += fmt.Sprintf(" AND start_date >= $%d", argIdx)
+		args = append(args, *startDate)
+		argIdx++
+	}
+	if endDate != nil {
+		whereClause += fmt.Sprintf(" AND start_date <= $%d", argIdx)
+		args = append(args, *endDate)
+	}
+
+	query := fmt.Sprintf(`SELECT id FROM drives %s ORDER BY start_date`, whereClause)
+
+	ids := []int64{}
+	if err := r.db.SelectContext(ctx, &ids, query, args...); err != nil {
+		logger.Errorf("Failed to get drive IDs for car %d: %v", carID, err)
+		return nil, err
+	}
+	return ids, nil
+}
+
 // GetStatsSummary 获取驾驶统计摘要
 func (r *driveRepository) GetStatsSummary(ctx context.Context, carID int16, startDate, endDate *time.Time) (*model.DriveStatsSummary, error) {
 	// 构建查询条件
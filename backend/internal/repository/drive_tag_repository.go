@@ -0,0 +1,210 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"teslamate-cyberui/internal/logger"
+	"teslamate-cyberui/internal/model"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// DriveTagRepository 行程用途标记和里程日志仓储接口
+type DriveTagRepository interface {
+	InitTable() error
+	Get(ctx context.Context, driveID int64) (*model.DriveTag, error)
+	Set(ctx context.Context, tag *model.DriveTag) (bool, error)
+	SetMany(ctx context.Context, driveIDs []int64, purpose, note string) (int, error)
+	Delete(ctx context.Context, driveID int64) (bool, error)
+	GetMileageLog(ctx context.Context, carID int16, startDate, endDate *time.Time, purpose string) ([]model.MileageLogEntry, error)
+}
+
+type driveTagRepository struct {
+	db *sqlx.DB
+}
+
+// NewDriveTagRepository 创建行程标记仓储
+func NewDriveTagRepository(db *sqlx.DB) DriveTagRepository {
+	return &driveTagRepository{db: db}
+}
+
+// InitTable 创建行程标记表
+// drive_id 不设外键，避免影响 TeslaMate 对 drives 表的迁移；行程被删除后遗留的标记不会出现在查询结果中
+func (r *driveTagRepository) InitTable() error {
+	schema := `
+	CREATE TABLE IF NOT EXISTS drive_tags (
+		drive_id INTEGER PRIMARY KEY,
+		purpose TEXT NOT NULL,
+		note TEXT NOT NULL DEFAULT '',
+		created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	);
+	`
+	_, err := r.db.Exec(schema)
+	if err != nil {
+		return fmt.Errorf("failed to create drive_tags table: %w", err)
+	}
+	return nil
+}
+
+// Get 获取行程的用途标记，未标记时返回 nil
+func (r *driveTagRepository) Get(ctx context.Context, driveID int64) (*model.DriveTag, error) {
+	var tag model.DriveTag
+	query := `SELECT drive_id, purpose, note, updated_at FROM drive_tags WHERE drive_id = $1`
+	if err := r.db.GetContext(ctx, &tag, query, driveID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		logger.Errorf("Failed to get tag of drive %d: %v", driveID, err)
+		return nil, err
+	}
+	return &tag, nil
+}
+
+// Set 标记行程用途，回填更新时间；行程不存在时返回 false
+func (r *driveTagRepository) Set(ctx context.Context, tag *model.DriveTag) (bool, error) {
+	query := `
+		INSERT INTO drive_tags (drive_id, purpose, note)
+		SELECT id, $2, $3 FROM drives WHERE id = $1
+		ON CONFLICT (drive_id) DO UPDATE SET
+			purpose = EXCLUDED.purpose, note = EXCLUDED.note, updated_at = NOW()
+		RETURNING updated_at
+	`
+	err := r.db.QueryRowxContext(ctx, query, tag.DriveID, tag.Purpose, tag.Note).Scan(&tag.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		logger.Errorf("Failed to tag drive %d: %v", tag.DriveID, err)
+		return false, err
+	}
+	return true, nil
+}
+
+// SetMany 批量标记行程用途，忽略不存在的行程，返回标记的行程数
+func (r *driveTagRepository) SetMany(ctx context.Context, driveIDs []int64, purpose, note string) (int, error) {
+	query := `
+		INSERT INTO drive_tags (drive_id, purpose, note)
+		SELECT id, $2, $3 FROM drives WHERE id = ANY($1)
+		ON CONFLICT (drive_id) DO UPDATE SET
+			purpose = EXCLUDED.purpose, note = EXCLUDED.note, updated_at = NOW()
+	`
+	res, err := r.db.ExecContext(ctx, query, pq.Array(driveIDs), purpose, note)
+	if err != nil {
+		logger.Errorf("Failed to tag drives: %v", err)
+		return 0, err
+	}
+	n, _ := res.RowsAffected()
+	return int(n), nil
+}
+
+// Delete 清除行程的用途标记
+func (r *driveTagRepository) Delete(ctx context.Context, driveID int64) (bool, error) {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM drive_tags WHERE drive_id = $1`, driveID)
+	if err != nil {
+		logger.Errorf("Failed to delete tag of drive %d: %v", driveID, err)
+		return false, err
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
+
+// GetMileageLog 获取里程日志，参考 teslamate-grafana/reports/dutch-tax.json
+// purpose 为空表示全部行程，为 untagged 时只返回未标记的行程
+func (r *driveTagRepository) GetMileageLog(ctx context.Context, carID int16, startDate, endDate *time.Time, purpose string) ([]model.MileageLogEntry, error) {
+	whereClause := "WHERE d.car_id = $1 AND d.end_date IS NOT NULL"
+	args := []interface{}{carID}
+	argIdx := 2
+	if startDate != nil {
+		whereClause += fmt.Sprintf(" AND d.start_date >= $%d", argIdx)
+		args = append(args, *startDate)
+		argIdx++
+	}
+	if endDate != nil {
+		whereClause += fmt.Sprintf(" AND d.start_date <= $%d", argIdx)
+		args = append(args, *endDate)
+		argIdx++
+	}
+	switch purpose {
+	case "":
+	case model.DrivePurposeUntagged:
+		whereClause += " AND t.drive_id IS NULL"
+	default:
+		whereClause += fmt.Sprintf(" AND t.purpose = $%d", argIdx)
+		args = append(args, purpose)
+		argIdx++
+	}
+
+	query := fmt.Sprintf(`
+		SELECT
+			d.id,
+			d.start_date,
+			d.end_date,
+			COALESCE(NULLIF(CONCAT_WS(', ', NULLIF(CONCAT_WS(' ', sa.road, sa.house_number), ''), sa.city), ''), sa.display_name, 'Unknown') AS start_address,
+			COALESCE(NULLIF(CONCAT_WS(', ', NULLIF(CONCAT_WS(' ', ea.road, ea.house_number), ''), ea.city), ''), ea.display_name, 'Unknown') AS end_address,
+			COALESCE(d.start_km, 0)::float8 AS start_km,
+			COALESCE(d.end_km, 0)::float8 AS end_km,
+			COALESCE(d.distance, 0)::float8 AS distance,
+			COALESCE(d.duration_min, 0) AS duration_min,
+			COALESCE(t.purpose, '') AS purpose,
+			COALESCE(t.note, '') AS note
+		FROM drives d
+		LEFT JOIN addresses sa ON d.start_address_id = sa.id
+		LEFT JOIN addresses ea ON d.end_address_id = ea.id
+		LEFT JOIN drive_tags t ON t.drive_id = d.id
+		%s
+		ORDER BY d.start_date
+	`, whereClause)
+
+	rows, err := r.db.QueryxContext(ctx, query, args...)
+	if err != nil {
+		logger.Errorf("Failed to get mileage log for car %d: %v", carID, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []model.MileageLogEntry{}
+	for rows.Next() {
+		var row struct {
+			ID           int64     `db:"id"`
+			StartDate    time.Time `db:"start_date"`
+			EndDate      time.Time `db:"end_date"`
+			StartAddress string    `db:"start_address"`
+			EndAddress   string    `db:"end_address"`
+			StartKm      float64   `db:"start_km"`
+			EndKm        float64   `db:"end_km"`
+			Distance     float64   `db:"distance"`
+			DurationMin  int       `db:"duration_min"`
+			Purpose      string    `db:"purpose"`
+			Note         string    `db:"note"`
+		}
+		if err := rows.StructScan(&row); err != nil {
+			logger.Errorf("Failed to scan mileage log entry for car %d: %v", carID, err)
+			return nil, err
+		}
+		entries = append(entries, model.MileageLogEntry{
+			DriveID:       row.ID,
+			StartDate:     row.StartDate,
+			EndDate:       row.EndDate,
+			StartAddress:  row.StartAddress,
+			EndAddress:    row.EndAddress,
+			OdometerStart: row.StartKm,
+			OdometerEnd:   row.EndKm,
+			Distance:      row.Distance,
+			DurationMin:   row.DurationMin,
+			Purpose:       row.Purpose,
+			Note:          row.Note,
+		})
+	}
+	if err := rows.Err(); err != nil {
+		logger.Errorf("Failed to read mileage log for car %d: %v", carID, err)
+		return nil, err
+	}
+
+	return entries, nil
+}
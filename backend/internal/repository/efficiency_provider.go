@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"strings"
+
+	"teslamate-cyberui/internal/logger"
+	"teslamate-cyberui/internal/model"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// 合理的能效系数范围 (kWh/km)，超出范围的值视为无效，继续使用下一个来源
+const (
+	minCarEfficiency = 0.05
+	maxCarEfficiency = 0.5
+)
+
+// EfficiencyProvider 车辆能效系数提供者
+// 按优先级依次使用 cars.efficiency、ui_settings 中的手动能效系数、内置车型能效系数表
+type EfficiencyProvider interface {
+	Get(ctx context.Context, carID int16) model.CarEfficiency
+}
+
+type efficiencyProvider struct {
+	db *sqlx.DB
+}
+
+// NewEfficiencyProvider 创建能效系数提供者
+func NewEfficiencyProvider(db *sqlx.DB) EfficiencyProvider {
+	return &efficiencyProvider{db: db}
+}
+
+// Get 获取车辆能效系数，查询失败时退回到默认能效系数
+func (p *efficiencyProvider) Get(ctx context.Context, carID int16) model.CarEfficiency {
+	query := `
+		SELECT c.efficiency, c.model, c.marketing_name, s.value AS setting
+		FROM cars c
+		LEFT JOIN ui_settings s ON s.key = $2
+		WHERE c.id = $1
+	`
+	var row struct {
+		Efficiency    sql.NullFloat64 `db:"efficiency"`
+		Model         sql.NullString  `db:"model"`
+		MarketingName sql.NullString  `db:"marketing_name"`
+		Setting       sql.NullString  `db:"setting"`
+	}
+	if err := p.db.GetContext(ctx, &row, query, carID, model.CarEfficiencyKey(carID)); err != nil && err != sql.ErrNoRows {
+		logger.Errorf("Failed to get efficiency of car %d: %v", carID, err)
+	}
+
+	if row.Efficiency.Valid && validCarEfficiency(row.Efficiency.Float64) {
+		return model.CarEfficiency{Value: row.Efficiency.Float64, Source: model.EfficiencySourceCar}
+	}
+	if row.Setting.Valid {
+		v, err := strconv.ParseFloat(strings.TrimSpace(row.Setting.String), 64)
+		if err == nil && validCarEfficiency(v) {
+			return model.CarEfficiency{Value: v, Source: model.EfficiencySourceSetting}
+		}
+		logger.Warnf("Ignoring invalid %s setting %q", model.CarEfficiencyKey(carID), row.Setting.String)
+	}
+	return model.CarEfficiency{
+		Value:  getEfficiencyByModel(row.Model.String, row.MarketingName.String),
+		Source: model.EfficiencySourceTable,
+	}
+}
+
+func validCarEfficiency(v float64) bool {
+	return v >= minCarEfficiency && v <= maxCarEfficiency
+}
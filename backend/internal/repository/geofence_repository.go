@@ -0,0 +1,252 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"teslamate-cyberui/internal/logger"
+	"teslamate-cyberui/internal/model"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// GeofenceRepository 地理围栏仓储接口
+// 读取接口只查询 TeslaMate 的 geofences 表；写入接口直接修改该表，需由调用方确认已开启写入模式
+type GeofenceRepository interface {
+	List(ctx context.Context, carID *int16) ([]model.GeofenceDetail, error)
+	Get(ctx context.Context, id int64, carID *int16) (*model.GeofenceDetail, error)
+	Create(ctx context.Context, input *model.GeofenceInput) (int64, error)
+	Update(ctx context.Context, id int64, input *model.GeofenceInput) (bool, error)
+	Delete(ctx context.Context, id int64) (bool, error)
+}
+
+type geofenceRepository struct {
+	db *sqlx.DB
+}
+
+// NewGeofenceRepository 创建地理围栏仓储
+func NewGeofenceRepository(db *sqlx.DB) GeofenceRepository {
+	return &geofenceRepository{db: db}
+}
+
+// query 查询围栏及统计，carID 不为空时只统计该车辆的行程和充电
+func (r *geofenceRepository) query(ctx context.Context, id *int64, carID *int16) ([]model.GeofenceDetail, error) {
+	whereClause := "WHERE true"
+	carFilter := ""
+	args := []interface{}{}
+	argIdx := 1
+	if id != nil {
+		whereClause += fmt.Sprintf(" AND g.id = $%d", argIdx)
+		args = append(args, *id)
+		argIdx++
+	}
+	if carID != nil {
+		carFilter = fmt.Sprintf(" AND car_id = $%d", argIdx)
+		args = append(args, *carID)
+		argIdx++
+	}
+
+	query := fmt.Sprintf(`
+		SELECT
+			g.id,
+			g.name,
+			g.latitude::float8 AS latitude,
+			g.longitude::float8 AS longitude,
+			g.radius,
+			COALESCE(g.billing_type::text, '%[3]s') AS billing_type,
+			g.cost_per_unit::float8 AS cost_per_unit,
+			g.session_fee::float8 AS session_fee,
+			g.inserted_at,
+			g.updated_at,
+			COALESCE(d.arrival_count, 0) AS arrival_count,
+			COALESCE(d.departure_count, 0) AS departure_count,
+			COALESCE(c.charge_count, 0) AS charge_count,
+			COALESCE(c.energy_added, 0)::float8 AS energy_added,
+			COALESCE(c.charge_cost, 0)::float8 AS charge_cost,
+			GREATEST(d.last_visit, c.last_visit) AS last_visit
+		FROM geofences g
+		LEFT JOIN (
+			SELECT
+				geofence_id,
+				SUM(arrival) AS arrival_count,
+				SUM(1 - arrival) AS departure_count,
+				MAX(visited_at) FILTER (WHERE arrival = 1) AS last_visit
+			FROM (
+				SELECT end_geofence_id AS geofence_id, 1 AS arrival, end_date AS visited_at
+				FROM drives WHERE end_geofence_id IS NOT NULL AND end_date IS NOT NULL%[1]s
+				UNION ALL
+				SELECT start_geofence_id, 0, start_date
+				FROM drives WHERE start_geofence_id IS NOT NULL%[1]s
+			) v
+			GROUP BY geofence_id
+		) d ON d.geofence_id = g.id
+		LEFT JOIN (
+			SELECT
+				geofence_id,
+				COUNT(*) AS charge_count,
+				SUM(charge_energy_added) AS energy_added,
+				SUM(cost) AS charge_cost,
+				MAX(start_date) AS last_visit
+			FROM charging_processes
+			WHERE geofence_id IS NOT NULL%[1]s
+			GROUP BY geofence_id
+		) c ON c.geofence_id = g.id
+		%[2]s
+		ORDER BY g.name, g.id
+	`, carFilter, whereClause, model.BillingTypePerKWh)
+
+	rows, err := r.db.QueryxContext(ctx, query, args...)
+	if err != nil {
+		logger.Errorf("Failed to get geofences: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	geofences := []model.GeofenceDetail{}
+	for rows.Next() {
+		var row struct {
+			ID             int64           `db:"id"`
+			Name           string          `db:"name"`
+			Latitude       float64         `db:"latitude"`
+			Longitude      float64         `db:"longitude"`
+			Radius         int             `db:"radius"`
+			BillingType    string          `db:"billing_type"`
+			CostPerUnit    sql.NullFloat64 `db:"cost_per_unit"`
+			SessionFee     sql.NullFloat64 `db:"session_fee"`
+			InsertedAt     time.Time       `db:"inserted_at"`
+			UpdatedAt      time.Time       `db:"updated_at"`
+			ArrivalCount   int             `db:"arrival_count"`
+			DepartureCount int             `db:"departure_count"`
+			ChargeCount    int             `db:"charge_count"`
+			EnergyAdded    float64         `db:"energy_added"`
+			ChargeCost     float64         `db:"charge_cost"`
+			LastVisit      sql.NullTime    `db:"last_visit"`
+		}
+		if err := rows.StructScan(&row); err != nil {
+			logger.Warnf("Failed to scan geofence: %v", err)
+			continue
+		}
+
+		g := model.GeofenceDetail{
+			ID:             row.ID,
+			Name:           row.Name,
+			Latitude:       row.Latitude,
+			Longitude:      row.Longitude,
+			Radius:         row.Radius,
+			BillingType:    row.BillingType,
+			InsertedAt:     row.InsertedAt,
+			UpdatedAt:      row.UpdatedAt,
+			ArrivalCount:   row.ArrivalCount,
+			DepartureCount: row.DepartureCount,
+			ChargeCount:    row.ChargeCount,
+			EnergyAdded:    row.EnergyAdded,
+			ChargeCost:     row.ChargeCost,
+		}
+		if row.CostPerUnit.Valid {
+			g.CostPerUnit = &row.CostPerUnit.Float64
+		}
+		if row.SessionFee.Valid {
+			g.SessionFee = &row.SessionFee.Float64
+		}
+		if row.LastVisit.Valid {
+			g.LastVisit = &row.LastVisit.Time
+		}
+		geofences = append(geofences, g)
+	}
+
+	return geofences, nil
+}
+
+// List 获取全部地理围栏及到访、充电统计
+func (r *geofenceRepository) List(ctx context.Context, carID *int16) ([]model.GeofenceDetail, error) {
+	return r.query(ctx, nil, carID)
+}
+
+// Get 获取单个地理围栏，不存在时返回 nil
+func (r *geofenceRepository) Get(ctx context.Context, id int64, carID *int16) (*model.GeofenceDetail, error) {
+	geofences, err := r.query(ctx, &id, carID)
+	if err != nil {
+		return nil, err
+	}
+	if len(geofences) == 0 {
+		return nil, nil
+	}
+	return &geofences[0], nil
+}
+
+// Create 创建地理围栏，时间戳与 TeslaMate 一致使用 UTC
+// 已有的行程和充电不会重新关联围栏，TeslaMate 只在新记录产生时匹配
+func (r *geofenceRepository) Create(ctx context.Context, input *model.GeofenceInput) (int64, error) {
+	query := `
+		INSERT INTO geofences (name, latitude, longitude, radius, billing_type, cost_per_unit, session_fee, inserted_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NOW() AT TIME ZONE 'UTC', NOW() AT TIME ZONE 'UTC')
+		RETURNING id
+	`
+	var id int64
+	err := r.db.QueryRowxContext(ctx, query,
+		input.Name, input.Latitude, input.Longitude, input.Radius, input.BillingType, input.CostPerUnit, input.SessionFee,
+	).Scan(&id)
+	if err != nil {
+		logger.Errorf("Failed to create geofence: %v", err)
+		return 0, err
+	}
+	return id, nil
+}
+
+// Update 更新地理围栏，不存在时返回 false
+func (r *geofenceRepository) Update(ctx context.Context, id int64, input *model.GeofenceInput) (bool, error) {
+	query := `
+		UPDATE geofences SET
+			name = $2, latitude = $3, longitude = $4, radius = $5, billing_type = $6,
+			cost_per_unit = $7, session_fee = $8, updated_at = NOW() AT TIME ZONE 'UTC'
+		WHERE id = $1
+	`
+	res, err := r.db.ExecContext(ctx, query,
+		id, input.Name, input.Latitude, input.Longitude, input.Radius, input.BillingType, input.CostPerUnit, input.SessionFee,
+	)
+	if err != nil {
+		logger.Errorf("Failed to update geofence %d: %v", id, err)
+		return false, err
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
+
+// Delete 删除地理围栏，并在同一事务中解除行程和充电对它的引用
+func (r *geofenceRepository) Delete(ctx context.Context, id int64) (bool, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		logger.Errorf("Failed to begin transaction for deleting geofence %d: %v", id, err)
+		return false, err
+	}
+	defer tx.Rollback()
+
+	statements := []string{
+		`UPDATE drives SET start_geofence_id = NULL WHERE start_geofence_id = $1`,
+		`UPDATE drives SET end_geofence_id = NULL WHERE end_geofence_id = $1`,
+		`UPDATE charging_processes SET geofence_id = NULL WHERE geofence_id = $1`,
+	}
+	for _, stmt := range statements {
+		if _, err := tx.ExecContext(ctx, stmt, id); err != nil {
+			logger.Errorf("Failed to detach geofence %d: %v", id, err)
+			return false, err
+		}
+	}
+
+	res, err := tx.ExecContext(ctx, `DELETE FROM geofences WHERE id = $1`, id)
+	if err != nil {
+		logger.Errorf("Failed to delete geofence %d: %v", id, err)
+		return false, err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return false, nil
+	}
+
+	if err := tx.Commit(); err != nil {
+		logger.Errorf("Failed to commit deleting geofence %d: %v", id, err)
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,59 @@
+package repository
+
+import (
+	"fmt"
+
+	"teslamate-cyberui/internal/model"
+)
+
+// sortColumn 排序字段对应的 SQL 表达式，表达式不能为 NULL；cast 为游标值转换回的类型
+type sortColumn struct {
+	expr string
+	cast string
+}
+
+// keysetPage 根据列表查询参数返回排序值表达式（文本形式，用于生成游标），以及追加在 WHERE 条件之后的
+// 游标条件、ORDER BY 和 LIMIT/OFFSET 子句。排序值相同的记录按 ID 排序保证顺序稳定；
+// 多查询一条记录用于判断是否还有下一页
+func keysetPage(q model.ListQuery, columns map[string]sortColumn, idColumn string, args []interface{}) (string, string, []interface{}) {
+	column, ok := columns[q.SortBy]
+	if !ok {
+		column = columns[model.SortByStartDate]
+	}
+	direction, cmp := "DESC", "<"
+	if q.SortOrder == model.SortAsc {
+		direction, cmp = "ASC", ">"
+	}
+	argIdx := len(args) + 1
+
+	clause := ""
+	if q.Cursor != nil {
+		clause += fmt.Sprintf(" AND (%s, %s) %s ($%d::%s, $%d)", column.expr, idColumn, cmp, argIdx, column.cast, argIdx+1)
+		args = append(args, q.Cursor.Value, q.Cursor.ID)
+		argIdx += 2
+	}
+
+	clause += fmt.Sprintf("\n\t\tORDER BY %s %s, %s %s\n\t\tLIMIT $%d", column.expr, direction, idColumn, direction, argIdx)
+	args = append(args, q.PageSize+1)
+	argIdx++
+	if q.Cursor == nil {
+		clause += fmt.Sprintf(" OFFSET $%d", argIdx)
+		args = append(args, (q.Page-1)*q.PageSize)
+	}
+
+	return column.expr + "::text", clause, args
+}
+
+// nextCursor 查询结果多于 PageSize 条时返回最后一条保留记录的游标，否则返回空字符串
+func nextCursor(q model.ListQuery, keys []string, ids []int64) string {
+	if len(ids) <= q.PageSize {
+		return ""
+	}
+	cursor := model.ListCursor{
+		SortBy:    q.SortBy,
+		SortOrder: q.SortOrder,
+		Value:     keys[q.PageSize-1],
+		ID:        ids[q.PageSize-1],
+	}
+	return cursor.Encode()
+}
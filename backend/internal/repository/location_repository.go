@@ -0,0 +1,325 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"time"
+
+	"teslamate-cyberui/internal/logger"
+	"teslamate-cyberui/internal/model"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// LocationRepository 位置分析仓储接口
+type LocationRepository interface {
+	GetPlaces(ctx context.Context, carID int16, startDate, endDate *time.Time, limit int) ([]model.VisitedPlace, error)
+	GetHeatmap(ctx context.Context, carID int16, startDate, endDate *time.Time, precision int) (*model.LocationHeatmap, error)
+	GetRegions(ctx context.Context, carID int16, startDate, endDate *time.Time, limit int) (*model.RegionSummary, error)
+}
+
+type locationRepository struct {
+	db *sqlx.DB
+}
+
+// NewLocationRepository 创建位置分析仓储
+func NewLocationRepository(db *sqlx.DB) LocationRepository {
+	return &locationRepository{db: db}
+}
+
+// dateFilter 生成作用于 column 的时间筛选条件，参数从 argIdx 开始编号
+func dateFilter(column string, startDate, endDate *time.Time, args []interface{}, argIdx int) (string, []interface{}, int) {
+	clause := ""
+	if startDate != nil {
+		clause += fmt.Sprintf(" AND %s >= $%d", column, argIdx)
+		args = append(args, *startDate)
+		argIdx++
+	}
+	if endDate != nil {
+		clause += fmt.Sprintf(" AND %s <= $%d", column, argIdx)
+		args = append(args, *endDate)
+		argIdx++
+	}
+	return clause, args, argIdx
+}
+
+// GetPlaces 按地理围栏（无围栏时按地址）聚合行程终点和充电记录
+// 停留时长为到达到下一次出发的间隔，在全部行程上计算后再按到达时间筛选
+func (r *locationRepository) GetPlaces(ctx context.Context, carID int16, startDate, endDate *time.Time, limit int) ([]model.VisitedPlace, error) {
+	args := []interface{}{carID}
+	arrivalFilter, args, argIdx := dateFilter("arrived_at", startDate, endDate, args, 2)
+	chargeFilter, args, argIdx := dateFilter("start_date", startDate, endDate, args, argIdx)
+	args = append(args, limit)
+
+	query := fmt.Sprintf(`
+		WITH arrivals AS (
+			SELECT
+				end_date AS arrived_at,
+				LEAD(start_date) OVER (ORDER BY start_date) AS departed_at,
+				end_geofence_id AS geofence_id,
+				end_address_id AS address_id
+			FROM drives
+			WHERE car_id = $1 AND end_date IS NOT NULL
+		),
+		events AS (
+			SELECT
+				geofence_id,
+				CASE WHEN geofence_id IS NULL THEN address_id END AS address_id,
+				arrived_at AS visited_at,
+				EXTRACT(EPOCH FROM (departed_at - arrived_at)) AS dwell_seconds,
+				1 AS visit,
+				0 AS charge,
+				0::float8 AS energy_added
+			FROM arrivals
+			WHERE (geofence_id IS NOT NULL OR address_id IS NOT NULL)%s
+			UNION ALL
+			SELECT
+				geofence_id,
+				CASE WHEN geofence_id IS NULL THEN address_id END AS address_id,
+				start_date AS visited_at,
+				NULL AS dwell_seconds,
+				0 AS visit,
+				1 AS charge,
+				COALESCE(charge_energy_added, 0)::float8 AS energy_added
+			FROM charging_processes
+			WHERE car_id = $1 AND (geofence_id IS NOT NULL OR address_id IS NOT NULL)%s
+		)
+		SELECT
+			e.geofence_id,
+			e.address_id,
+			COALESCE(g.name, a.name, array_to_string((string_to_array(a.display_name, ', '))[1:2], ', '), 'Unknown') AS name,
+			COALESCE(a.display_name, '') AS address,
+			COALESCE(a.city, '') AS city,
+			COALESCE(a.country, '') AS country,
+			COALESCE(g.latitude, a.latitude)::float8 AS latitude,
+			COALESCE(g.longitude, a.longitude)::float8 AS longitude,
+			SUM(e.visit) AS visit_count,
+			SUM(e.charge) AS charge_count,
+			SUM(e.energy_added) AS energy_added,
+			COALESCE(SUM(e.dwell_seconds), 0)::float8 / 60 AS dwell_minutes,
+			MIN(e.visited_at) AS first_visit,
+			MAX(e.visited_at) AS last_visit
+		FROM events e
+		LEFT JOIN geofences g ON g.id = e.geofence_id
+		LEFT JOIN addresses a ON a.id = e.address_id
+		GROUP BY e.geofence_id, e.address_id, g.id, a.id
+		ORDER BY visit_count DESC, charge_count DESC, last_visit DESC
+		LIMIT $%d
+	`, arrivalFilter, chargeFilter, argIdx)
+
+	rows, err := r.db.QueryxContext(ctx, query, args...)
+	if err != nil {
+		logger.Errorf("Failed to get visited places for car %d: %v", carID, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	places := []model.VisitedPlace{}
+	for rows.Next() {
+		var row struct {
+			GeofenceID   sql.NullInt64   `db:"geofence_id"`
+			AddressID    sql.NullInt64   `db:"address_id"`
+			Name         string          `db:"name"`
+			Address      string          `db:"address"`
+			City         string          `db:"city"`
+			Country      string          `db:"country"`
+			Latitude     sql.NullFloat64 `db:"latitude"`
+			Longitude    sql.NullFloat64 `db:"longitude"`
+			VisitCount   int             `db:"visit_count"`
+			ChargeCount  int             `db:"charge_count"`
+			EnergyAdded  float64         `db:"energy_added"`
+			DwellMinutes float64         `db:"dwell_minutes"`
+			FirstVisit   time.Time       `db:"first_visit"`
+			LastVisit    time.Time       `db:"last_visit"`
+		}
+		if err := rows.StructScan(&row); err != nil {
+			logger.Warnf("Failed to scan visited place: %v", err)
+			continue
+		}
+
+		place := model.VisitedPlace{
+			Name:         row.Name,
+			Address:      row.Address,
+			City:         row.City,
+			Country:      row.Country,
+			VisitCount:   row.VisitCount,
+			ChargeCount:  row.ChargeCount,
+			EnergyAdded:  row.EnergyAdded,
+			DwellMinutes: row.DwellMinutes,
+			FirstVisit:   row.FirstVisit,
+			LastVisit:    row.LastVisit,
+		}
+		if row.GeofenceID.Valid {
+			place.GeofenceID = &row.GeofenceID.Int64
+		}
+		if row.AddressID.Valid {
+			place.AddressID = &row.AddressID.Int64
+		}
+		if row.Latitude.Valid && row.Longitude.Valid {
+			place.Latitude = &row.Latitude.Float64
+			place.Longitude = &row.Longitude.Float64
+		}
+		places = append(places, place)
+	}
+
+	return places, nil
+}
+
+// GetHeatmap 获取时间范围内到访过的位置
+// 参考 teslamate-grafana/locations/visited.json 先按分钟取平均坐标，再按 precision 位小数合并为网格
+func (r *locationRepository) GetHeatmap(ctx context.Context, carID int16, startDate, endDate *time.Time, precision int) (*model.LocationHeatmap, error) {
+	args := []interface{}{carID, precision}
+	filter, args, _ := dateFilter("date", startDate, endDate, args, 3)
+
+	query := fmt.Sprintf(`
+		SELECT
+			ROUND(m.latitude, $2)::float8 AS latitude,
+			ROUND(m.longitude, $2)::float8 AS longitude,
+			COUNT(*) AS weight
+		FROM (
+			SELECT
+				DATE_TRUNC('minute', date) AS minute,
+				AVG(latitude) AS latitude,
+				AVG(longitude) AS longitude
+			FROM positions
+			WHERE car_id = $1 AND ideal_battery_range_km IS NOT NULL%s
+			GROUP BY 1
+		) m
+		GROUP BY 1, 2
+	`, filter)
+
+	rows, err := r.db.QueryxContext(ctx, query, args...)
+	if err != nil {
+		logger.Errorf("Failed to get location heatmap for car %d: %v", carID, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	heatmap := &model.LocationHeatmap{Precision: precision, Points: [][3]float64{}}
+	for rows.Next() {
+		var lat, lng float64
+		var weight int
+		if err := rows.Scan(&lat, &lng, &weight); err != nil {
+			logger.Warnf("Failed to scan heatmap point: %v", err)
+			continue
+		}
+		heatmap.Points = append(heatmap.Points, [3]float64{lat, lng, float64(weight)})
+	}
+	heatmap.Count = len(heatmap.Points)
+
+	return heatmap, nil
+}
+
+// GetRegions 统计行程起终点和充电地址所在的城市、省州和国家
+// 参考 teslamate-grafana/locations.json，地址数为不同 address_id 的数量
+func (r *locationRepository) GetRegions(ctx context.Context, carID int16, startDate, endDate *time.Time, limit int) (*model.RegionSummary, error) {
+	args := []interface{}{carID}
+	startFilter, args, argIdx := dateFilter("start_date", startDate, endDate, args, 2)
+	endFilter, args, argIdx := dateFilter("end_date", startDate, endDate, args, argIdx)
+	chargeFilter, args, _ := dateFilter("start_date", startDate, endDate, args, argIdx)
+
+	query := fmt.Sprintf(`
+		WITH ids AS (
+			SELECT start_address_id AS address_id FROM drives WHERE car_id = $1%s
+			UNION ALL
+			SELECT end_address_id FROM drives WHERE car_id = $1 AND end_date IS NOT NULL%s
+			UNION ALL
+			SELECT address_id FROM charging_processes WHERE car_id = $1%s
+		)
+		SELECT
+			COALESCE(a.city, '') AS city,
+			COALESCE(a.state, '') AS state,
+			COALESCE(a.country, '') AS country,
+			COUNT(*) AS visits
+		FROM ids
+		JOIN addresses a ON a.id = ids.address_id
+		GROUP BY a.id
+	`, startFilter, endFilter, chargeFilter)
+
+	rows, err := r.db.QueryxContext(ctx, query, args...)
+	if err != nil {
+		logger.Errorf("Failed to get visited regions for car %d: %v", carID, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	cities := newRegionCounter()
+	states := newRegionCounter()
+	countries := newRegionCounter()
+	summary := &model.RegionSummary{}
+	for rows.Next() {
+		var row struct {
+			City    string `db:"city"`
+			State   string `db:"state"`
+			Country string `db:"country"`
+			Visits  int    `db:"visits"`
+		}
+		if err := rows.StructScan(&row); err != nil {
+			logger.Warnf("Failed to scan visited region: %v", err)
+			continue
+		}
+
+		summary.AddressCount++
+		if row.City != "" {
+			cities.add(model.RegionStat{Name: row.City, State: row.State, Country: row.Country}, row.Visits)
+		}
+		if row.State != "" {
+			states.add(model.RegionStat{Name: row.State, Country: row.Country}, row.Visits)
+		}
+		if row.Country != "" {
+			countries.add(model.RegionStat{Name: row.Country}, row.Visits)
+		}
+	}
+
+	summary.CityCount = len(cities.stats)
+	summary.StateCount = len(states.stats)
+	summary.CountryCount = len(countries.stats)
+	summary.Cities = cities.top(limit)
+	summary.States = states.top(limit)
+	summary.Countries = countries.top(limit)
+
+	return summary, nil
+}
+
+// regionCounter 按名称（及上级区域）累计地址数和到访次数
+type regionCounter struct {
+	stats map[model.RegionStat]*model.RegionStat
+}
+
+func newRegionCounter() *regionCounter {
+	return &regionCounter{stats: make(map[model.RegionStat]*model.RegionStat)}
+}
+
+func (c *regionCounter) add(key model.RegionStat, visits int) {
+	s, ok := c.stats[key]
+	if !ok {
+		stat := key
+		s = &stat
+		c.stats[key] = s
+	}
+	s.Addresses++
+	s.Visits += visits
+}
+
+// top 按地址数、到访次数倒序返回前 limit 项
+func (c *regionCounter) top(limit int) []model.RegionStat {
+	result := make([]model.RegionStat, 0, len(c.stats))
+	for _, s := range c.stats {
+		result = append(result, *s)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Addresses != result[j].Addresses {
+			return result[i].Addresses > result[j].Addresses
+		}
+		if result[i].Visits != result[j].Visits {
+			return result[i].Visits > result[j].Visits
+		}
+		return result[i].Name < result[j].Name
+	})
+	if limit > 0 && len(result) > limit {
+		result = result[:limit]
+	}
+	return result
+}
@@ -0,0 +1,149 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"teslamate-cyberui/internal/logger"
+	"teslamate-cyberui/internal/model"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// NotificationRepository 通知渠道仓储接口
+type NotificationRepository interface {
+	InitTable() error
+	List(ctx context.Context) ([]model.NotificationChannel, error)
+	Get(ctx context.Context, id int64) (*model.NotificationChannel, error)
+	GetByIDs(ctx context.Context, ids []int64) ([]model.NotificationChannel, error)
+	Create(ctx context.Context, ch *model.NotificationChannel) error
+	Update(ctx context.Context, ch *model.NotificationChannel) (bool, error)
+	Delete(ctx context.Context, id int64) (bool, error)
+}
+
+type notificationRepository struct {
+	db *sqlx.DB
+}
+
+// NewNotificationRepository 创建通知渠道仓储
+func NewNotificationRepository(db *sqlx.DB) NotificationRepository {
+	return &notificationRepository{db: db}
+}
+
+// InitTable 创建通知渠道表
+func (r *notificationRepository) InitTable() error {
+	schema := `
+	CREATE TABLE IF NOT EXISTS notification_channels (
+		id SERIAL PRIMARY KEY,
+		name TEXT NOT NULL,
+		type TEXT NOT NULL,
+		config JSONB NOT NULL DEFAULT '{}',
+		title_template TEXT NOT NULL DEFAULT '',
+		body_template TEXT NOT NULL DEFAULT '',
+		enabled BOOLEAN NOT NULL DEFAULT TRUE,
+		created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	);
+	`
+	_, err := r.db.Exec(schema)
+	if err != nil {
+		return fmt.Errorf("failed to create notification_channels table: %w", err)
+	}
+	return nil
+}
+
+const notificationChannelColumns = `id, name, type, config, title_template, body_template, enabled, created_at, updated_at`
+
+// List 获取全部通知渠道
+func (r *notificationRepository) List(ctx context.Context) ([]model.NotificationChannel, error) {
+	channels := []model.NotificationChannel{}
+	query := fmt.Sprintf(`SELECT %s FROM notification_channels ORDER BY id`, notificationChannelColumns)
+	if err := r.db.SelectContext(ctx, &channels, query); err != nil {
+		logger.Errorf("Failed to list notification channels: %v", err)
+		return nil, err
+	}
+	return channels, nil
+}
+
+// Get 获取单个通知渠道，不存在时返回 nil
+func (r *notificationRepository) Get(ctx context.Context, id int64) (*model.NotificationChannel, error) {
+	var ch model.NotificationChannel
+	query := fmt.Sprintf(`SELECT %s FROM notification_channels WHERE id = $1`, notificationChannelColumns)
+	if err := r.db.GetContext(ctx, &ch, query, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		logger.Errorf("Failed to get notification channel %d: %v", id, err)
+		return nil, err
+	}
+	return &ch, nil
+}
+
+// GetByIDs 按 ID 批量获取通知渠道，忽略不存在的 ID
+func (r *notificationRepository) GetByIDs(ctx context.Context, ids []int64) ([]model.NotificationChannel, error) {
+	channels := []model.NotificationChannel{}
+	if len(ids) == 0 {
+		return channels, nil
+	}
+	query, args, err := sqlx.In(fmt.Sprintf(`SELECT %s FROM notification_channels WHERE id IN (?) ORDER BY id`, notificationChannelColumns), ids)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.db.SelectContext(ctx, &channels, r.db.Rebind(query), args...); err != nil {
+		logger.Errorf("Failed to get notification channels %v: %v", ids, err)
+		return nil, err
+	}
+	return channels, nil
+}
+
+// Create 创建通知渠道，回填 ID 和时间
+func (r *notificationRepository) Create(ctx context.Context, ch *model.NotificationChannel) error {
+	query := `
+		INSERT INTO notification_channels (name, type, config, title_template, body_template, enabled)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at, updated_at
+	`
+	err := r.db.QueryRowxContext(ctx, query,
+		ch.Name, ch.Type, ch.Config, ch.TitleTemplate, ch.BodyTemplate, ch.Enabled,
+	).Scan(&ch.ID, &ch.CreatedAt, &ch.UpdatedAt)
+	if err != nil {
+		logger.Errorf("Failed to create notification channel: %v", err)
+		return err
+	}
+	return nil
+}
+
+// Update 更新通知渠道，不存在时返回 false
+func (r *notificationRepository) Update(ctx context.Context, ch *model.NotificationChannel) (bool, error) {
+	query := `
+		UPDATE notification_channels SET
+			name = $2, type = $3, config = $4, title_template = $5, body_template = $6,
+			enabled = $7, updated_at = NOW()
+		WHERE id = $1
+		RETURNING created_at, updated_at
+	`
+	err := r.db.QueryRowxContext(ctx, query,
+		ch.ID, ch.Name, ch.Type, ch.Config, ch.TitleTemplate, ch.BodyTemplate, ch.Enabled,
+	).Scan(&ch.CreatedAt, &ch.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		logger.Errorf("Failed to update notification channel %d: %v", ch.ID, err)
+		return false, err
+	}
+	return true, nil
+}
+
+// Delete 删除通知渠道
+func (r *notificationRepository) Delete(ctx context.Context, id int64) (bool, error) {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM notification_channels WHERE id = $1`, id)
+	if err != nil {
+		logger.Errorf("Failed to delete notification channel %d: %v", id, err)
+		return false, err
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
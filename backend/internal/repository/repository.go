@@ -12,7 +12,17 @@ type Repository struct {
 	Charge    ChargeRepository
 	Drive     DriveRepository
 	Stats     StatsRepository
+	Update    UpdateRepository
+	Location  LocationRepository
+	Geofence  GeofenceRepository
+	Tariff    TariffRepository
+	DriveTag  DriveTagRepository
+	Trip      TripRepository
 	UISetting UISettingRepository
+	Alert     AlertRepository
+	Notify    NotificationRepository
+	// Efficiency 车辆能效系数提供者，能耗相关的仓储共用
+	Efficiency EfficiencyProvider
 }
 
 // NewRepository 创建仓储实例
@@ -22,11 +32,43 @@ func NewRepository(db *sqlx.DB) *Repository {
 		logger.Errorf("Failed to initialize ui_settings table: %v", err)
 	}
 
+	alertRepo := NewAlertRepository(db)
+	if err := alertRepo.InitTable(); err != nil {
+		logger.Errorf("Failed to initialize alert tables: %v", err)
+	}
+
+	notifyRepo := NewNotificationRepository(db)
+	if err := notifyRepo.InitTable(); err != nil {
+		logger.Errorf("Failed to initialize notification_channels table: %v", err)
+	}
+
+	tariffRepo := NewTariffRepository(db)
+	if err := tariffRepo.InitTable(); err != nil {
+		logger.Errorf("Failed to initialize tariffs table: %v", err)
+	}
+
+	driveTagRepo := NewDriveTagRepository(db)
+	if err := driveTagRepo.InitTable(); err != nil {
+		logger.Errorf("Failed to initialize drive_tags table: %v", err)
+	}
+
+	efficiency := NewEfficiencyProvider(db)
+
 	return &Repository{
 		Car:       NewCarRepository(db),
 		Charge:    NewChargeRepository(db),
-		Drive:     NewDriveRepository(db),
-		Stats:     NewStatsRepository(db),
+		Drive:     NewDriveRepository(db, efficiency),
+		Stats:     NewStatsRepository(db, efficiency),
+		Update:    NewUpdateRepository(db, efficiency),
+		Location:  NewLocationRepository(db),
+		Geofence:  NewGeofenceRepository(db),
+		Tariff:    tariffRepo,
+		DriveTag:  driveTagRepo,
+		Trip:      NewTripRepository(db),
 		UISetting: uiSettingRepo,
+		Alert:     alertRepo,
+		Notify:    notifyRepo,
+
+		Efficiency: efficiency,
 	}
 }
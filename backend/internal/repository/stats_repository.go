@@ -3,6 +3,8 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"fmt"
+	"sort"
 	"time"
 
 	"teslamate-cyberui/internal/logger"
@@ -18,33 +20,28 @@ type StatsRepository interface {
 	GetBattery(ctx context.Context, carID int16) (*model.BatteryStats, error)
 	GetSocHistory(ctx context.Context, carID int16, start, end time.Time) ([]model.SocDataPoint, error)
 	GetStatesTimeline(ctx context.Context, carID int16, start, end time.Time) ([]model.StateTimelineItem, error)
+	GetVampireDrain(ctx context.Context, carID int16, startDate, endDate *time.Time, minDuration time.Duration) (*model.VampireDrainStats, error)
+	GetProjectedRange(ctx context.Context, carID int16, startDate, endDate *time.Time, interval string, bucketKm float64) (*model.ProjectedRangeStats, error)
+	GetCapacitySamples(ctx context.Context, carID int16, minSocDelta int) ([]model.BatteryCapacitySample, error)
+	GetEfficiencySamples(ctx context.Context, carID int16, startDate, endDate *time.Time, minDistance float64) (*model.EfficiencySamples, error)
+	GetEnergyUsage(ctx context.Context, carID int16, startDate, endDate *time.Time) (*model.EnergyUsage, error)
 }
 
 type statsRepository struct {
-	db *sqlx.DB
+	db         *sqlx.DB
+	efficiency EfficiencyProvider
 }
 
 // NewStatsRepository 创建统计仓储
-func NewStatsRepository(db *sqlx.DB) StatsRepository {
-	return &statsRepository{db: db}
+func NewStatsRepository(db *sqlx.DB, efficiency EfficiencyProvider) StatsRepository {
+	return &statsRepository{db: db, efficiency: efficiency}
 }
 
 // GetOverview 获取概览统计
 func (r *statsRepository) GetOverview(ctx context.Context, carID int16) (*model.OverviewStats, error) {
-	stats := &model.OverviewStats{}
-
-	// 获取车型信息以计算能效
-	var carModel, carMarketingName sql.NullString
-	modelQuery := `SELECT model, marketing_name FROM cars WHERE id = $1`
-	r.db.QueryRowxContext(ctx, modelQuery, carID).Scan(&carModel, &carMarketingName)
-	carEfficiency := getEfficiencyByModel("", "")
-	if carModel.Valid {
-		mktName := ""
-		if carMarketingName.Valid {
-			mktName = carMarketingName.String
-		}
-		carEfficiency = getEfficiencyByModel(carModel.String, mktName)
-	}
+	// 获取车辆能效系数
+	carEfficiency := r.efficiency.Get(ctx, carID)
+	stats := &model.OverviewStats{EfficiencySource: carEfficiency.Source}
 
 	// 当前里程（总里程）
 	odometerQuery := `
@@ -100,19 +97,18 @@ func (r *statsRepository) GetOverview(ctx context.Context, carID int16) (*model.
 		}
 	}
 
-	// 平均能效：使用行程中的续航消耗 * 车辆能效系数 / 行驶距离
-	// 能效系数根据车型硬编码
-	efficiencyQuery := `
+	// 平均能效：使用行程中的续航消耗 * 车辆能效系数 / 行驶距离，续航类型与能效系数的标定口径一致
+	efficiencyQuery := fmt.Sprintf(`
 		SELECT 
 			COALESCE(SUM(d.distance), 0) as total_distance,
-			COALESCE(SUM(d.start_ideal_range_km - d.end_ideal_range_km), 0) as total_range_used
+			COALESCE(SUM(d.start_%[1]s_range_km - d.end_%[1]s_range_km), 0) as total_range_used
 		FROM drives d
 		WHERE d.car_id = $1 
 			AND d.distance > 0 
-			AND d.start_ideal_range_km IS NOT NULL 
-			AND d.end_ideal_range_km IS NOT NULL
-			AND d.start_ideal_range_km > d.end_ideal_range_km
-	`
+			AND d.start_%[1]s_range_km IS NOT NULL 
+			AND d.end_%[1]s_range_km IS NOT NULL
+			AND d.start_%[1]s_range_km > d.end_%[1]s_range_km
+	`, getPreferredRange(ctx, r.db))
 	var effStats struct {
 		TotalDistance  float64 `db:"total_distance"`
 		TotalRangeUsed float64 `db:"total_range_used"`
@@ -120,7 +116,7 @@ func (r *statsRepository) GetOverview(ctx context.Context, carID int16) (*model.
 	if err := r.db.GetContext(ctx, &effStats, efficiencyQuery, carID); err == nil {
 		if effStats.TotalDistance > 0 && effStats.TotalRangeUsed > 0 {
 			// 能效 = 续航消耗 * 能效系数 / 行驶距离
-			stats.AvgEfficiency = effStats.TotalRangeUsed * carEfficiency / effStats.TotalDistance * 1000 // Wh/km
+			stats.AvgEfficiency = effStats.TotalRangeUsed * carEfficiency.Value / effStats.TotalDistance * 1000 // Wh/km
 		}
 	}
 
@@ -271,167 +267,103 @@ func (r *statsRepository) GetOverview(ctx context.Context, carID int16) (*model.
 
 // GetEfficiency 获取能效统计
 func (r *statsRepository) GetEfficiency(ctx context.Context, carID int16, days int) (*model.EfficiencyStats, error) {
-	stats := &model.EfficiencyStats{}
-
-	// 获取车型信息以计算能效
-	var carModel, carMarketingName sql.NullString
-	modelQuery := `SELECT model, marketing_name FROM cars WHERE id = $1`
-	r.db.QueryRowxContext(ctx, modelQuery, carID).Scan(&carModel, &carMarketingName)
-	carEfficiency := getEfficiencyByModel("", "")
-	if carModel.Valid {
-		mktName := ""
-		if carMarketingName.Valid {
-			mktName = carMarketingName.String
-		}
-		carEfficiency = getEfficiencyByModel(carModel.String, mktName)
-	}
-	// 能效系数 * 1000 = Wh/km系数，用于SQL中计算
-	efficiencyFactor := carEfficiency * 1000
+	// 获取车辆能效系数
+	carEfficiency := r.efficiency.Get(ctx, carID)
+	stats := &model.EfficiencyStats{EfficiencySource: carEfficiency.Source}
+	rangeType := getPreferredRange(ctx, r.db)
+	now := time.Now()
 
 	// 日统计
-	dailyQuery := `
-		SELECT 
-			DATE(start_date) as date,
-			COALESCE(SUM(distance), 0) as distance,
-			COALESCE(SUM(start_ideal_range_km - end_ideal_range_km), 0) as range_used
-		FROM drives
-		WHERE car_id = $1 AND start_date >= $2
-		GROUP BY DATE(start_date)
-		ORDER BY DATE(start_date) DESC
-		LIMIT 30
-	`
-	startDate := time.Now().AddDate(0, 0, -days)
-
-	rows, err := r.db.QueryxContext(ctx, dailyQuery, carID, startDate)
+	rows, err := r.queryRangeUsed(ctx, carID, rangeType, "DATE(start_date)", now.AddDate(0, 0, -days), nil, 30)
 	if err != nil {
 		logger.Errorf("Failed to get daily efficiency: %v", err)
-	} else {
-		defer rows.Close()
-		for rows.Next() {
-			var row struct {
-				Date      time.Time `db:"date"`
-				Distance  float64   `db:"distance"`
-				RangeUsed float64   `db:"range_used"`
-			}
-			if err := rows.StructScan(&row); err != nil {
-				continue
-			}
-
-			point := model.EfficiencyDataPoint{
-				Date:       row.Date.Format("2006-01-02"),
-				Distance:   row.Distance,
-				EnergyUsed: row.RangeUsed * efficiencyFactor / 1000,
-			}
-			if row.Distance > 0 {
-				point.Efficiency = row.RangeUsed * efficiencyFactor / row.Distance
-			}
-			stats.Daily = append(stats.Daily, point)
-		}
+	}
+	for _, row := range rows {
+		stats.Daily = append(stats.Daily, row.point("2006-01-02", carEfficiency.Value))
 	}
 
 	// 周统计
-	weeklyQuery := `
-		SELECT 
-			DATE_TRUNC('week', start_date) as date,
-			COALESCE(SUM(distance), 0) as distance,
-			COALESCE(SUM(start_ideal_range_km - end_ideal_range_km), 0) as range_used
-		FROM drives
-		WHERE car_id = $1 AND start_date >= $2
-		GROUP BY DATE_TRUNC('week', start_date)
-		ORDER BY DATE_TRUNC('week', start_date) DESC
-		LIMIT 12
-	`
-	weekStart := time.Now().AddDate(0, -3, 0)
-
-	rows, err = r.db.QueryxContext(ctx, weeklyQuery, carID, weekStart)
+	rows, err = r.queryRangeUsed(ctx, carID, rangeType, "DATE_TRUNC('week', start_date)", now.AddDate(0, -3, 0), nil, 12)
 	if err != nil {
 		logger.Errorf("Failed to get weekly efficiency: %v", err)
-	} else {
-		defer rows.Close()
-		for rows.Next() {
-			var row struct {
-				Date      time.Time `db:"date"`
-				Distance  float64   `db:"distance"`
-				RangeUsed float64   `db:"range_used"`
-			}
-			if err := rows.StructScan(&row); err != nil {
-				continue
-			}
-
-			point := model.EfficiencyDataPoint{
-				Date:       row.Date.Format("2006-01-02"),
-				Distance:   row.Distance,
-				EnergyUsed: row.RangeUsed * efficiencyFactor / 1000,
-			}
-			if row.Distance > 0 {
-				point.Efficiency = row.RangeUsed * efficiencyFactor / row.Distance
-			}
-			stats.Weekly = append(stats.Weekly, point)
-		}
+	}
+	for _, row := range rows {
+		stats.Weekly = append(stats.Weekly, row.point("2006-01-02", carEfficiency.Value))
 	}
 
 	// 月统计
-	monthlyQuery := `
-		SELECT 
-			DATE_TRUNC('month', start_date) as date,
-			COALESCE(SUM(distance), 0) as distance,
-			COALESCE(SUM(start_ideal_range_km - end_ideal_range_km), 0) as range_used
-		FROM drives
-		WHERE car_id = $1 AND start_date >= $2
-		GROUP BY DATE_TRUNC('month', start_date)
-		ORDER BY DATE_TRUNC('month', start_date) DESC
-		LIMIT 12
-	`
-	monthStart := time.Now().AddDate(-1, 0, 0)
-
-	rows, err = r.db.QueryxContext(ctx, monthlyQuery, carID, monthStart)
+	rows, err = r.queryRangeUsed(ctx, carID, rangeType, "DATE_TRUNC('month', start_date)", now.AddDate(-1, 0, 0), nil, 12)
 	if err != nil {
 		logger.Errorf("Failed to get monthly efficiency: %v", err)
-	} else {
-		defer rows.Close()
-		for rows.Next() {
-			var row struct {
-				Date      time.Time `db:"date"`
-				Distance  float64   `db:"distance"`
-				RangeUsed float64   `db:"range_used"`
-			}
-			if err := rows.StructScan(&row); err != nil {
-				continue
-			}
-
-			point := model.EfficiencyDataPoint{
-				Date:       row.Date.Format("2006-01"),
-				Distance:   row.Distance,
-				EnergyUsed: row.RangeUsed * efficiencyFactor / 1000,
-			}
-			if row.Distance > 0 {
-				point.Efficiency = row.RangeUsed * efficiencyFactor / row.Distance
-			}
-			stats.Monthly = append(stats.Monthly, point)
-		}
+	}
+	for _, row := range rows {
+		stats.Monthly = append(stats.Monthly, row.point("2006-01", carEfficiency.Value))
 	}
 
 	return stats, nil
 }
 
-// GetBattery 获取电池统计
-func (r *statsRepository) GetBattery(ctx context.Context, carID int16) (*model.BatteryStats, error) {
-	stats := &model.BatteryStats{}
+// rangeUsedRow 按时间分组的行程里程和续航消耗
+type rangeUsedRow struct {
+	Date      time.Time `db:"date"`
+	Distance  float64   `db:"distance"`
+	RangeUsed float64   `db:"range_used"`
+}
 
-	// 获取车型信息以计算能效
-	var carModel, carMarketingName sql.NullString
-	modelQuery := `SELECT model, marketing_name FROM cars WHERE id = $1`
-	r.db.QueryRowxContext(ctx, modelQuery, carID).Scan(&carModel, &carMarketingName)
-	carEfficiency := getEfficiencyByModel("", "")
-	if carModel.Valid {
-		mktName := ""
-		if carMarketingName.Valid {
-			mktName = carMarketingName.String
-		}
-		carEfficiency = getEfficiencyByModel(carModel.String, mktName)
+// point 按能效系数（kWh/km）换算为能效数据点
+func (row rangeUsedRow) point(layout string, efficiency float64) model.EfficiencyDataPoint {
+	point := model.EfficiencyDataPoint{
+		Date:       row.Date.Format(layout),
+		Distance:   row.Distance,
+		EnergyUsed: row.RangeUsed * efficiency,
+	}
+	if row.Distance > 0 {
+		point.Efficiency = row.RangeUsed * efficiency * 1000 / row.Distance
+	}
+	return point
+}
+
+// queryRangeUsed 按 groupExpr 分组汇总开始时间不早于 startDate（endDate 非空时不晚于 endDate）的行程，
+// 按时间倒序返回，limit 为 0 时不限制行数。GetEfficiency 和 GetEnergyUsage 共用该查询，保证能效口径一致
+func (r *statsRepository) queryRangeUsed(ctx context.Context, carID int16, rangeType, groupExpr string, startDate time.Time, endDate *time.Time, limit int) ([]rangeUsedRow, error) {
+	whereClause := "WHERE car_id = $1 AND start_date >= $2"
+	args := []interface{}{carID, startDate}
+	if endDate != nil {
+		whereClause += " AND start_date <= $3"
+		args = append(args, *endDate)
+	}
+	limitClause := ""
+	if limit > 0 {
+		limitClause = fmt.Sprintf("LIMIT %d", limit)
+	}
+
+	query := fmt.Sprintf(`
+		SELECT 
+			%[2]s as date,
+			COALESCE(SUM(distance), 0) as distance,
+			COALESCE(SUM(start_%[1]s_range_km - end_%[1]s_range_km), 0) as range_used
+		FROM drives
+		%[3]s
+		GROUP BY %[2]s
+		ORDER BY %[2]s DESC
+		%[4]s
+	`, rangeType, groupExpr, whereClause, limitClause)
+
+	var rows []rangeUsedRow
+	if err := r.db.SelectContext(ctx, &rows, query, args...); err != nil {
+		return nil, err
 	}
+	return rows, nil
+}
+
+// GetBattery 获取电池统计
+func (r *statsRepository) GetBattery(ctx context.Context, carID int16) (*model.BatteryStats, error) {
+	// 获取车辆能效系数
+	carEfficiency := r.efficiency.Get(ctx, carID)
+	stats := &model.BatteryStats{EfficiencySource: carEfficiency.Source}
 	// 能效系数 * 1000 = Wh/km系数
-	efficiencyFactor := carEfficiency * 1000
+	efficiencyFactor := carEfficiency.Value * 1000
+	rangeType := getPreferredRange(ctx, r.db)
 
 	// 获取电池容量历史（基于100%充电记录）
 	query := `
@@ -478,9 +410,13 @@ func (r *statsRepository) GetBattery(ctx context.Context, carID int16) (*model.B
 		}
 		if row.BatteryLevel.Valid {
 			point.BatteryLevel = int(row.BatteryLevel.Int64)
-			// 估算电池容量，根据车型能效系数计算
-			if point.IdealRangeKm > 0 && point.BatteryLevel > 0 {
-				point.EstimatedCapacity = point.IdealRangeKm * efficiencyFactor / 1000 * 100 / float64(point.BatteryLevel)
+			// 估算电池容量，根据车辆能效系数计算，续航类型与能效系数的标定口径一致
+			rangeKm := point.IdealRangeKm
+			if rangeType == "rated" {
+				rangeKm = point.RatedRangeKm
+			}
+			if rangeKm > 0 && point.BatteryLevel > 0 {
+				point.EstimatedCapacity = rangeKm * efficiencyFactor / 1000 * 100 / float64(point.BatteryLevel)
 			}
 		}
 
@@ -635,3 +571,605 @@ func (r *statsRepository) GetStatesTimeline(ctx context.Context, carID int16, st
 
 	return result, nil
 }
+
+// GetVampireDrain 获取停放耗电统计
+// 参考 teslamate-grafana/system/vampire-drain.json：相邻两次行程/充电之间超过 minDuration
+// 且里程基本不变（< 1 km）的时间段视为一次停放
+func (r *statsRepository) GetVampireDrain(ctx context.Context, carID int16, startDate, endDate *time.Time, minDuration time.Duration) (*model.VampireDrainStats, error) {
+	rangeType := getPreferredRange(ctx, r.db)
+	carEfficiency := r.efficiency.Get(ctx, carID)
+
+	args := []interface{}{carID, minDuration.Seconds()}
+	argIdx := 3
+	chargeFilter, driveFilter := "", ""
+	if startDate != nil {
+		chargeFilter += fmt.Sprintf(" AND c.start_date >= $%d", argIdx)
+		driveFilter += fmt.Sprintf(" AND d.start_date >= $%d", argIdx)
+		args = append(args, *startDate)
+		argIdx++
+	}
+	if endDate != nil {
+		chargeFilter += fmt.Sprintf(" AND c.start_date <= $%d", argIdx)
+		driveFilter += fmt.Sprintf(" AND d.start_date <= $%d", argIdx)
+		args = append(args, *endDate)
+		argIdx++
+	}
+
+	query := fmt.Sprintf(`
+		WITH merge AS (
+			SELECT
+				c.start_date,
+				c.end_date,
+				c.start_%[1]s_range_km AS start_range,
+				c.end_%[1]s_range_km AS end_range,
+				c.start_battery_level,
+				c.end_battery_level,
+				p.usable_battery_level AS start_usable_battery_level,
+				p.odometer AS start_km,
+				p.odometer AS end_km,
+				c.geofence_id AS end_geofence_id,
+				c.address_id AS end_address_id
+			FROM charging_processes c
+			JOIN positions p ON c.position_id = p.id
+			WHERE c.car_id = $1 AND c.end_date IS NOT NULL%[2]s
+			UNION ALL
+			SELECT
+				d.start_date,
+				d.end_date,
+				d.start_%[1]s_range_km AS start_range,
+				d.end_%[1]s_range_km AS end_range,
+				sp.battery_level AS start_battery_level,
+				ep.battery_level AS end_battery_level,
+				sp.usable_battery_level AS start_usable_battery_level,
+				d.start_km,
+				d.end_km,
+				d.end_geofence_id,
+				d.end_address_id
+			FROM drives d
+			JOIN positions sp ON d.start_position_id = sp.id
+			JOIN positions ep ON d.end_position_id = ep.id
+			WHERE d.car_id = $1 AND d.end_date IS NOT NULL%[3]s
+		),
+		v AS (
+			SELECT
+				lag(t.end_date) OVER w AS start_date,
+				t.start_date AS end_date,
+				lag(t.end_range) OVER w AS start_range,
+				t.start_range AS end_range,
+				lag(t.end_km) OVER w AS start_km,
+				t.start_km AS end_km,
+				lag(t.end_battery_level) OVER w AS start_battery_level,
+				t.start_battery_level AS end_battery_level,
+				t.start_battery_level > COALESCE(t.start_usable_battery_level, t.start_battery_level) AS has_reduced_range,
+				lag(t.end_geofence_id) OVER w AS geofence_id,
+				lag(t.end_address_id) OVER w AS address_id
+			FROM merge t
+			WINDOW w AS (ORDER BY t.start_date ASC)
+		)
+		SELECT
+			v.start_date,
+			v.end_date,
+			EXTRACT(EPOCH FROM (v.end_date - v.start_date))::float8 AS duration,
+			v.start_battery_level,
+			v.end_battery_level,
+			(v.start_range - v.end_range)::float8 AS range_lost,
+			COALESCE(v.has_reduced_range, false) AS has_reduced_range,
+			v.geofence_id,
+			COALESCE(g.name, a.display_name, 'Unknown') AS location,
+			COALESCE((
+				SELECT EXTRACT(EPOCH FROM SUM(LEAST(COALESCE(s.end_date, v.end_date), v.end_date) - GREATEST(s.start_date, v.start_date)))
+				FROM states s
+				WHERE s.car_id = $1 AND s.state = 'asleep'
+					AND s.start_date < v.end_date AND COALESCE(s.end_date, v.end_date) > v.start_date
+			), 0)::float8 AS asleep,
+			COALESCE((
+				SELECT EXTRACT(EPOCH FROM SUM(LEAST(COALESCE(s.end_date, v.end_date), v.end_date) - GREATEST(s.start_date, v.start_date)))
+				FROM states s
+				WHERE s.car_id = $1 AND s.state = 'offline'
+					AND s.start_date < v.end_date AND COALESCE(s.end_date, v.end_date) > v.start_date
+			), 0)::float8 AS offline
+		FROM v
+		LEFT JOIN geofences g ON v.geofence_id = g.id
+		LEFT JOIN addresses a ON v.address_id = a.id
+		WHERE
+			v.start_date IS NOT NULL
+			AND EXTRACT(EPOCH FROM (v.end_date - v.start_date)) > $2
+			AND v.start_range - v.end_range >= 0
+			AND v.end_km - v.start_km < 1
+		ORDER BY v.start_date DESC
+	`, rangeType, chargeFilter, driveFilter)
+
+	rows, err := r.db.QueryxContext(ctx, query, args...)
+	if err != nil {
+		logger.Errorf("Failed to get vampire drain for car %d: %v", carID, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	stats := &model.VampireDrainStats{
+		Periods:          []model.VampireDrainPeriod{},
+		Daily:            []model.VampireDrainTotal{},
+		ByGeofence:       []model.VampireDrainGeofence{},
+		EfficiencySource: carEfficiency.Source,
+	}
+	total := &drainAccumulator{}
+	daily := make(map[string]*drainAccumulator)
+	geofences := make(map[int64]*drainAccumulator)
+	geofenceNames := make(map[int64]string)
+
+	for rows.Next() {
+		var row struct {
+			StartDate         time.Time       `db:"start_date"`
+			EndDate           time.Time       `db:"end_date"`
+			Duration          float64         `db:"duration"`
+			StartBatteryLevel sql.NullInt64   `db:"start_battery_level"`
+			EndBatteryLevel   sql.NullInt64   `db:"end_battery_level"`
+			RangeLost         sql.NullFloat64 `db:"range_lost"`
+			HasReducedRange   bool            `db:"has_reduced_range"`
+			GeofenceID        sql.NullInt64   `db:"geofence_id"`
+			Location          string          `db:"location"`
+			Asleep            float64         `db:"asleep"`
+			Offline           float64         `db:"offline"`
+		}
+		if err := rows.StructScan(&row); err != nil {
+			logger.Warnf("Failed to scan vampire drain period: %v", err)
+			continue
+		}
+		if row.Duration <= 0 {
+			continue
+		}
+
+		period := model.VampireDrainPeriod{
+			StartDate:    row.StartDate,
+			EndDate:      row.EndDate,
+			DurationMin:  int(row.Duration / 60),
+			StartSoc:     int(row.StartBatteryLevel.Int64),
+			EndSoc:       int(row.EndBatteryLevel.Int64),
+			ReducedRange: row.HasReducedRange,
+			Location:     row.Location,
+		}
+		if row.StartBatteryLevel.Valid && row.EndBatteryLevel.Valid && period.StartSoc > period.EndSoc {
+			period.SocLost = period.StartSoc - period.EndSoc
+		}
+		if !row.HasReducedRange && row.RangeLost.Valid {
+			hours := row.Duration / 3600
+			rangeLost := row.RangeLost.Float64
+			energyLost := rangeLost * carEfficiency.Value
+			avgPower := energyLost / hours * 1000
+			perHour := rangeLost / hours
+			period.RangeLost = &rangeLost
+			period.EnergyLost = &energyLost
+			period.AvgPower = &avgPower
+			period.RangeLostPerHour = &perHour
+		}
+		period.AsleepPercent = clampPercent(row.Asleep / row.Duration * 100)
+		period.OfflinePercent = clampPercent(row.Offline / row.Duration * 100)
+		period.OnlinePercent = clampPercent(100 - period.AsleepPercent - period.OfflinePercent)
+		if row.GeofenceID.Valid {
+			id := row.GeofenceID.Int64
+			period.GeofenceID = &id
+		}
+		stats.Periods = append(stats.Periods, period)
+
+		// 汇总
+		total.add(&period, 1)
+		var geofenceKey int64
+		if period.GeofenceID != nil {
+			geofenceKey = *period.GeofenceID
+			geofenceNames[geofenceKey] = period.Location
+		}
+		if geofences[geofenceKey] == nil {
+			geofences[geofenceKey] = &drainAccumulator{}
+		}
+		geofences[geofenceKey].add(&period, 1)
+
+		// 按本地自然日拆分跨天的停放
+		for dayStart := startOfDay(period.StartDate); dayStart.Before(period.EndDate); dayStart = dayStart.AddDate(0, 0, 1) {
+			from, to := period.StartDate, period.EndDate
+			if dayStart.After(from) {
+				from = dayStart
+			}
+			if next := dayStart.AddDate(0, 0, 1); next.Before(to) {
+				to = next
+			}
+			share := to.Sub(from).Seconds() / row.Duration
+			if share <= 0 {
+				continue
+			}
+			key := dayStart.Format("2006-01-02")
+			if daily[key] == nil {
+				daily[key] = &drainAccumulator{}
+			}
+			daily[key].add(&period, share)
+		}
+	}
+
+	stats.Total = total.total()
+	days := make([]string, 0, len(daily))
+	for day := range daily {
+		days = append(days, day)
+	}
+	sort.Strings(days)
+	for _, day := range days {
+		t := daily[day].total()
+		t.Date = day
+		stats.Daily = append(stats.Daily, t)
+	}
+	for id, acc := range geofences {
+		item := model.VampireDrainGeofence{Name: "Other", VampireDrainTotal: acc.total()}
+		if id != 0 {
+			geofenceID := id
+			item.GeofenceID = &geofenceID
+			item.Name = geofenceNames[id]
+		}
+		stats.ByGeofence = append(stats.ByGeofence, item)
+	}
+	sort.Slice(stats.ByGeofence, func(i, j int) bool {
+		return stats.ByGeofence[i].EnergyLost > stats.ByGeofence[j].EnergyLost
+	})
+
+	return stats, nil
+}
+
+// drainAccumulator 累加停放耗电，share 为停放计入该汇总的比例
+type drainAccumulator struct {
+	periods       int
+	seconds       float64
+	asleepSeconds float64
+	socLost       float64
+	rangeLost     float64
+	energyLost    float64
+	// energySeconds 有能耗数据的停放时长，用于计算平均功率
+	energySeconds float64
+}
+
+func (a *drainAccumulator) add(p *model.VampireDrainPeriod, share float64) {
+	seconds := p.EndDate.Sub(p.StartDate).Seconds() * share
+	a.periods++
+	a.seconds += seconds
+	a.asleepSeconds += seconds * (p.AsleepPercent + p.OfflinePercent) / 100
+	a.socLost += float64(p.SocLost) * share
+	if p.EnergyLost != nil {
+		a.rangeLost += *p.RangeLost * share
+		a.energyLost += *p.EnergyLost * share
+		a.energySeconds += seconds
+	}
+}
+
+func (a *drainAccumulator) total() model.VampireDrainTotal {
+	t := model.VampireDrainTotal{
+		Periods:     a.periods,
+		DurationMin: a.seconds / 60,
+		SocLost:     a.socLost,
+		RangeLost:   a.rangeLost,
+		EnergyLost:  a.energyLost,
+	}
+	if a.energySeconds > 0 {
+		t.AvgPower = a.energyLost / (a.energySeconds / 3600) * 1000
+	}
+	if a.seconds > 0 {
+		t.AsleepPercent = clampPercent(a.asleepSeconds / a.seconds * 100)
+	}
+	return t
+}
+
+// startOfDay 返回 t 所在本地自然日的零点
+func startOfDay(t time.Time) time.Time {
+	local := t.In(time.Local)
+	return time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, time.Local)
+}
+
+func clampPercent(v float64) float64 {
+	if v < 0 {
+		return 0
+	}
+	if v > 100 {
+		return 100
+	}
+	return v
+}
+
+// GetProjectedRange 获取预估满电续航历史
+// 参考 teslamate-grafana/battery-charging/projected-range.json：使用全部 positions 和 charges 样本，
+// 按 usable_battery_level 折算 100% 续航。bucketKm > 0 时按里程分组，否则按 interval（day/week/month）分组
+func (r *statsRepository) GetProjectedRange(ctx context.Context, carID int16, startDate, endDate *time.Time, interval string, bucketKm float64) (*model.ProjectedRangeStats, error) {
+	rangeType := getPreferredRange(ctx, r.db)
+	stats := &model.ProjectedRangeStats{
+		PreferredRange: rangeType,
+		Points:         []model.ProjectedRangePoint{},
+	}
+
+	args := []interface{}{carID}
+	argIdx := 2
+	positionFilter, chargeFilter := "", ""
+	if startDate != nil {
+		positionFilter += fmt.Sprintf(" AND date >= $%d", argIdx)
+		chargeFilter += fmt.Sprintf(" AND c.date >= $%d", argIdx)
+		args = append(args, *startDate)
+		argIdx++
+	}
+	if endDate != nil {
+		positionFilter += fmt.Sprintf(" AND date <= $%d", argIdx)
+		chargeFilter += fmt.Sprintf(" AND c.date <= $%d", argIdx)
+		args = append(args, *endDate)
+		argIdx++
+	}
+
+	var bucketExpr, dataFilter string
+	if bucketKm > 0 {
+		stats.XAxis = "mileage"
+		stats.BucketKm = bucketKm
+		bucketExpr = fmt.Sprintf("FLOOR(odometer / $%d)", argIdx)
+		dataFilter = "WHERE odometer IS NOT NULL"
+		args = append(args, bucketKm)
+	} else {
+		stats.XAxis = "time"
+		stats.Interval = interval
+		bucketExpr = fmt.Sprintf("DATE_TRUNC($%d, date)", argIdx)
+		args = append(args, interval)
+	}
+
+	query := fmt.Sprintf(`
+		SELECT
+			%[2]s AS bucket,
+			(SUM(%[1]s_battery_range_km) / NULLIF(SUM(COALESCE(usable_battery_level, battery_level)), 0) * 100)::float8 AS projected_range,
+			AVG(battery_level)::float8 AS battery_level,
+			AVG(COALESCE(usable_battery_level, battery_level))::float8 AS usable_battery_level,
+			AVG(odometer)::float8 AS mileage,
+			AVG(outside_temp)::float8 AS outside_temp,
+			COUNT(*) AS samples,
+			MIN(date) AS first_date
+		FROM (
+			SELECT battery_level, usable_battery_level, date,
+				rated_battery_range_km, ideal_battery_range_km, outside_temp, odometer
+			FROM positions
+			WHERE car_id = $1 AND ideal_battery_range_km IS NOT NULL%[3]s
+			UNION ALL
+			SELECT c.battery_level, COALESCE(c.usable_battery_level, c.battery_level), c.date,
+				c.rated_battery_range_km, c.ideal_battery_range_km, c.outside_temp, pos.odometer
+			FROM charges c
+			JOIN charging_processes p ON p.id = c.charging_process_id
+			LEFT JOIN positions pos ON pos.id = p.position_id
+			WHERE p.car_id = $1%[4]s
+		) AS data
+		%[5]s
+		GROUP BY 1
+		HAVING SUM(%[1]s_battery_range_km) / NULLIF(SUM(COALESCE(usable_battery_level, battery_level)), 0) IS NOT NULL
+		ORDER BY 1 ASC
+	`, rangeType, bucketExpr, positionFilter, chargeFilter, dataFilter)
+
+	rows, err := r.db.QueryxContext(ctx, query, args...)
+	if err != nil {
+		logger.Errorf("Failed to get projected range for car %d: %v", carID, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var row struct {
+			Bucket             interface{}     `db:"bucket"`
+			ProjectedRange     float64         `db:"projected_range"`
+			BatteryLevel       float64         `db:"battery_level"`
+			UsableBatteryLevel float64         `db:"usable_battery_level"`
+			Mileage            sql.NullFloat64 `db:"mileage"`
+			OutsideTemp        sql.NullFloat64 `db:"outside_temp"`
+			Samples            int             `db:"samples"`
+			FirstDate          time.Time       `db:"first_date"`
+		}
+		if err := rows.StructScan(&row); err != nil {
+			logger.Warnf("Failed to scan projected range point: %v", err)
+			continue
+		}
+
+		point := model.ProjectedRangePoint{
+			Date:               row.FirstDate,
+			ProjectedRange:     row.ProjectedRange,
+			BatteryLevel:       row.BatteryLevel,
+			UsableBatteryLevel: row.UsableBatteryLevel,
+			Samples:            row.Samples,
+		}
+		if t, ok := row.Bucket.(time.Time); ok {
+			point.Date = t
+		}
+		if row.Mileage.Valid {
+			point.Mileage = &row.Mileage.Float64
+		}
+		if row.OutsideTemp.Valid {
+			point.OutsideTemp = &row.OutsideTemp.Float64
+		}
+		stats.Points = append(stats.Points, point)
+	}
+
+	return stats, nil
+}
+
+// GetCapacitySamples 获取由每次充电的充入电量和 SOC 变化估算的电池容量样本
+// 只统计 SOC 变化不小于 minSocDelta 的已完成充电，车龄从该车第一条行程或充电记录算起
+func (r *statsRepository) GetCapacitySamples(ctx context.Context, carID int16, minSocDelta int) ([]model.BatteryCapacitySample, error) {
+	query := `
+		SELECT
+			cp.id,
+			cp.end_date,
+			cp.charge_energy_added::float8 AS energy_added,
+			cp.start_battery_level,
+			cp.end_battery_level,
+			p.odometer,
+			(EXTRACT(EPOCH FROM (cp.end_date - f.first_date)) / 86400)::float8 AS age_days
+		FROM charging_processes cp
+		LEFT JOIN positions p ON p.id = cp.position_id
+		CROSS JOIN (
+			SELECT LEAST(
+				(SELECT MIN(start_date) FROM drives WHERE car_id = $1),
+				(SELECT MIN(start_date) FROM charging_processes WHERE car_id = $1)
+			) AS first_date
+		) f
+		WHERE cp.car_id = $1
+			AND cp.end_date IS NOT NULL
+			AND cp.charge_energy_added > 0
+			AND cp.end_battery_level - cp.start_battery_level >= $2
+		ORDER BY cp.end_date ASC
+	`
+
+	rows, err := r.db.QueryxContext(ctx, query, carID, minSocDelta)
+	if err != nil {
+		logger.Errorf("Failed to get capacity samples for car %d: %v", carID, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	samples := []model.BatteryCapacitySample{}
+	for rows.Next() {
+		var row struct {
+			ID                int64           `db:"id"`
+			EndDate           time.Time       `db:"end_date"`
+			EnergyAdded       float64         `db:"energy_added"`
+			StartBatteryLevel int             `db:"start_battery_level"`
+			EndBatteryLevel   int             `db:"end_battery_level"`
+			Odometer          sql.NullFloat64 `db:"odometer"`
+			AgeDays           float64         `db:"age_days"`
+		}
+		if err := rows.StructScan(&row); err != nil {
+			logger.Warnf("Failed to scan capacity sample: %v", err)
+			continue
+		}
+
+		sample := model.BatteryCapacitySample{
+			ChargeID:    row.ID,
+			Date:        row.EndDate,
+			AgeDays:     row.AgeDays,
+			EnergyAdded: row.EnergyAdded,
+			StartSoc:    row.StartBatteryLevel,
+			EndSoc:      row.EndBatteryLevel,
+			Capacity:    row.EnergyAdded / float64(row.EndBatteryLevel-row.StartBatteryLevel) * 100,
+		}
+		if row.Odometer.Valid {
+			sample.Odometer = &row.Odometer.Float64
+		}
+		samples = append(samples, sample)
+	}
+
+	return samples, nil
+}
+
+// GetEfficiencySamples 获取能耗因素分析使用的行程样本
+// 参考 teslamate-grafana/driving/efficiency.json：能耗由行程的续航消耗（按 TeslaMate 设置使用 ideal / rated）乘以车辆能效系数得到，
+// 累计爬升和空调开启占比来自行程的轨迹点，只统计行驶距离不小于 minDistance 的行程
+func (r *statsRepository) GetEfficiencySamples(ctx context.Context, carID int16, startDate, endDate *time.Time, minDistance float64) (*model.EfficiencySamples, error) {
+	rangeType := getPreferredRange(ctx, r.db)
+	carEfficiency := r.efficiency.Get(ctx, carID)
+
+	whereClause := "WHERE d.car_id = $1 AND d.distance >= $2"
+	args := []interface{}{carID, minDistance}
+	argIdx := 3
+	if startDate != nil {
+		whereClause += fmt.Sprintf(" AND d.start_date >= $%d", argIdx)
+		args = append(args, *startDate)
+		argIdx++
+	}
+	if endDate != nil {
+		whereClause += fmt.Sprintf(" AND d.start_date <= $%d", argIdx)
+		args = append(args, *endDate)
+	}
+
+	query := fmt.Sprintf(`
+		SELECT
+			d.id,
+			d.distance::float8 AS distance,
+			(d.start_%[1]s_range_km - d.end_%[1]s_range_km)::float8 AS range_used,
+			d.outside_temp_avg::float8 AS outside_temp,
+			CASE WHEN d.duration_min > 0 THEN (d.distance / d.duration_min * 60)::float8 END AS speed_avg,
+			elev.gain AS elevation_gain,
+			clim.ratio AS climate_on_ratio
+		FROM drives d
+		LEFT JOIN LATERAL (
+			SELECT SUM(GREATEST(diff, 0))::float8 AS gain
+			FROM (
+				SELECT elevation - LAG(elevation) OVER (ORDER BY date) AS diff
+				FROM positions
+				WHERE drive_id = d.id AND elevation IS NOT NULL
+			) e
+		) elev ON true
+		LEFT JOIN LATERAL (
+			SELECT AVG(CASE WHEN is_climate_on THEN 1 ELSE 0 END)::float8 AS ratio
+			FROM positions
+			WHERE drive_id = d.id AND is_climate_on IS NOT NULL
+		) clim ON true
+		%[2]s
+			AND d.start_%[1]s_range_km IS NOT NULL
+			AND d.end_%[1]s_range_km IS NOT NULL
+		ORDER BY d.start_date ASC
+	`, rangeType, whereClause)
+
+	rows, err := r.db.QueryxContext(ctx, query, args...)
+	if err != nil {
+		logger.Errorf("Failed to get efficiency samples for car %d: %v", carID, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := &model.EfficiencySamples{
+		PreferredRange:   rangeType,
+		EfficiencySource: carEfficiency.Source,
+		Samples:          []model.EfficiencySample{},
+	}
+	for rows.Next() {
+		var row struct {
+			ID             int64           `db:"id"`
+			Distance       float64         `db:"distance"`
+			RangeUsed      float64         `db:"range_used"`
+			OutsideTemp    sql.NullFloat64 `db:"outside_temp"`
+			SpeedAvg       sql.NullFloat64 `db:"speed_avg"`
+			ElevationGain  sql.NullFloat64 `db:"elevation_gain"`
+			ClimateOnRatio sql.NullFloat64 `db:"climate_on_ratio"`
+		}
+		if err := rows.StructScan(&row); err != nil {
+			logger.Warnf("Failed to scan efficiency sample: %v", err)
+			continue
+		}
+
+		sample := model.EfficiencySample{
+			DriveID:    row.ID,
+			Distance:   row.Distance,
+			EnergyUsed: row.RangeUsed * carEfficiency.Value,
+		}
+		if row.OutsideTemp.Valid {
+			sample.OutsideTemp = &row.OutsideTemp.Float64
+		}
+		if row.SpeedAvg.Valid {
+			sample.SpeedAvg = &row.SpeedAvg.Float64
+		}
+		if row.ElevationGain.Valid {
+			sample.ElevationGain = &row.ElevationGain.Float64
+		}
+		if row.ClimateOnRatio.Valid {
+			sample.ClimateOnRatio = &row.ClimateOnRatio.Float64
+		}
+		result.Samples = append(result.Samples, sample)
+	}
+
+	return result, nil
+}
+
+// GetEnergyUsage 统计开始时间在范围内的行程的总里程和能耗，与 GetEfficiency 使用同一查询，
+// 时间范围与 DriveRepository.GetStatsSummary 一致
+func (r *statsRepository) GetEnergyUsage(ctx context.Context, carID int16, startDate, endDate *time.Time) (*model.EnergyUsage, error) {
+	rangeType := getPreferredRange(ctx, r.db)
+	carEfficiency := r.efficiency.Get(ctx, carID)
+
+	var from time.Time
+	if startDate != nil {
+		from = *startDate
+	}
+	rows, err := r.queryRangeUsed(ctx, carID, rangeType, "DATE(start_date)", from, endDate, 0)
+	if err != nil {
+		logger.Errorf("Failed to get energy usage for car %d: %v", carID, err)
+		return nil, err
+	}
+
+	usage := &model.EnergyUsage{EfficiencySource: carEfficiency.Source}
+	for _, row := range rows {
+		usage.Distance += row.Distance
+		usage.EnergyUsed += row.point("2006-01-02", carEfficiency.Value).EnergyUsed
+	}
+	return usage, nil
+}
@@ -0,0 +1,141 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"teslamate-cyberui/internal/logger"
+	"teslamate-cyberui/internal/model"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// TariffRepository 电价方案仓储接口
+type TariffRepository interface {
+	InitTable() error
+	List(ctx context.Context) ([]model.Tariff, error)
+	Get(ctx context.Context, id int64) (*model.Tariff, error)
+	Create(ctx context.Context, t *model.Tariff) error
+	Update(ctx context.Context, t *model.Tariff) (bool, error)
+	Delete(ctx context.Context, id int64) (bool, error)
+}
+
+type tariffRepository struct {
+	db *sqlx.DB
+}
+
+// NewTariffRepository 创建电价方案仓储
+func NewTariffRepository(db *sqlx.DB) TariffRepository {
+	return &tariffRepository{db: db}
+}
+
+// InitTable 创建电价方案表
+// geofence_id 不设外键，围栏由 TeslaMate 管理，删除围栏后方案不再匹配任何充电
+func (r *tariffRepository) InitTable() error {
+	schema := `
+	CREATE TABLE IF NOT EXISTS tariffs (
+		id SERIAL PRIMARY KEY,
+		name TEXT NOT NULL,
+		geofence_id INTEGER,
+		currency TEXT NOT NULL DEFAULT '',
+		price_per_kwh DOUBLE PRECISION NOT NULL DEFAULT 0,
+		session_fee DOUBLE PRECISION NOT NULL DEFAULT 0,
+		periods JSONB NOT NULL DEFAULT '[]',
+		timezone TEXT NOT NULL DEFAULT '',
+		valid_from TIMESTAMPTZ,
+		valid_until TIMESTAMPTZ,
+		enabled BOOLEAN NOT NULL DEFAULT TRUE,
+		created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	);
+	`
+	_, err := r.db.Exec(schema)
+	if err != nil {
+		return fmt.Errorf("failed to create tariffs table: %w", err)
+	}
+	return nil
+}
+
+const tariffColumns = `id, name, geofence_id, currency, price_per_kwh, session_fee, periods,
+	timezone, valid_from, valid_until, enabled, created_at, updated_at`
+
+// List 获取全部电价方案
+func (r *tariffRepository) List(ctx context.Context) ([]model.Tariff, error) {
+	tariffs := []model.Tariff{}
+	query := fmt.Sprintf(`SELECT %s FROM tariffs ORDER BY id`, tariffColumns)
+	if err := r.db.SelectContext(ctx, &tariffs, query); err != nil {
+		logger.Errorf("Failed to list tariffs: %v", err)
+		return nil, err
+	}
+	return tariffs, nil
+}
+
+// Get 获取单个电价方案，不存在时返回 nil
+func (r *tariffRepository) Get(ctx context.Context, id int64) (*model.Tariff, error) {
+	var t model.Tariff
+	query := fmt.Sprintf(`SELECT %s FROM tariffs WHERE id = $1`, tariffColumns)
+	if err := r.db.GetContext(ctx, &t, query, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		logger.Errorf("Failed to get tariff %d: %v", id, err)
+		return nil, err
+	}
+	return &t, nil
+}
+
+// Create 创建电价方案，回填 ID 和时间
+func (r *tariffRepository) Create(ctx context.Context, t *model.Tariff) error {
+	query := `
+		INSERT INTO tariffs (name, geofence_id, currency, price_per_kwh, session_fee, periods,
+			timezone, valid_from, valid_until, enabled)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		RETURNING id, created_at, updated_at
+	`
+	err := r.db.QueryRowxContext(ctx, query,
+		t.Name, t.GeofenceID, t.Currency, t.PricePerKWh, t.SessionFee, t.Periods,
+		t.Timezone, t.ValidFrom, t.ValidUntil, t.Enabled,
+	).Scan(&t.ID, &t.CreatedAt, &t.UpdatedAt)
+	if err != nil {
+		logger.Errorf("Failed to create tariff: %v", err)
+		return err
+	}
+	return nil
+}
+
+// Update 更新电价方案，不存在时返回 false
+func (r *tariffRepository) Update(ctx context.Context, t *model.Tariff) (bool, error) {
+	query := `
+		UPDATE tariffs SET
+			name = $2, geofence_id = $3, currency = $4, price_per_kwh = $5, session_fee = $6,
+			periods = $7, timezone = $8, valid_from = $9, valid_until = $10, enabled = $11,
+			updated_at = NOW()
+		WHERE id = $1
+		RETURNING created_at, updated_at
+	`
+	err := r.db.QueryRowxContext(ctx, query,
+		t.ID, t.Name, t.GeofenceID, t.Currency, t.PricePerKWh, t.SessionFee,
+		t.Periods, t.Timezone, t.ValidFrom, t.ValidUntil, t.Enabled,
+	).Scan(&t.CreatedAt, &t.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		logger.Errorf("Failed to update tariff %d: %v", t.ID, err)
+		return false, err
+	}
+	return true, nil
+}
+
+// Delete 删除电价方案，已写回的充电费用不受影响
+func (r *tariffRepository) Delete(ctx context.Context, id int64) (bool, error) {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM tariffs WHERE id = $1`, id)
+	if err != nil {
+		logger.Errorf("Failed to delete tariff %d: %v", id, err)
+		return false, err
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
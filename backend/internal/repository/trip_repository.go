@@ -0,0 +1,276 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"teslamate-cyberui/internal/logger"
+	"teslamate-cyberui/internal/model"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// TripRepository 旅程数据仓储接口，旅程本身不落库，由行程和充电记录实时分组得到
+type TripRepository interface {
+	GetActivities(ctx context.Context, carID int16, startDate, endDate *time.Time) ([]model.TripActivity, error)
+	GetDriveActivity(ctx context.Context, driveID int64) (*model.TripActivity, error)
+	GetTrack(ctx context.Context, driveIDs []int64, pointsPerDrive int) ([]model.DrivePosition, error)
+}
+
+type tripRepository struct {
+	db *sqlx.DB
+}
+
+// NewTripRepository 创建旅程仓储
+func NewTripRepository(db *sqlx.DB) TripRepository {
+	return &tripRepository{db: db}
+}
+
+// tripActivityRow 行程和充电共用的查询结果
+type tripActivityRow struct {
+	Type              string          `db:"type"`
+	ID                int64           `db:"id"`
+	CarID             int16           `db:"car_id"`
+	StartDate         time.Time       `db:"start_date"`
+	EndDate           time.Time       `db:"end_date"`
+	StartLocation     string          `db:"start_location"`
+	EndLocation       string          `db:"end_location"`
+	StartGeofenceID   sql.NullInt64   `db:"start_geofence_id"`
+	EndGeofenceID     sql.NullInt64   `db:"end_geofence_id"`
+	Latitude          sql.NullFloat64 `db:"latitude"`
+	Longitude         sql.NullFloat64 `db:"longitude"`
+	Distance          float64         `db:"distance"`
+	DurationMin       int             `db:"duration_min"`
+	RangeUsed         sql.NullFloat64 `db:"range_used"`
+	EnergyAdded       float64         `db:"energy_added"`
+	Cost              sql.NullFloat64 `db:"cost"`
+	StartBatteryLevel sql.NullInt64   `db:"start_battery_level"`
+	EndBatteryLevel   sql.NullInt64   `db:"end_battery_level"`
+}
+
+func (row *tripActivityRow) activity() model.TripActivity {
+	a := model.TripActivity{
+		Type:          row.Type,
+		ID:            row.ID,
+		CarID:         row.CarID,
+		StartDate:     row.StartDate,
+		EndDate:       row.EndDate,
+		StartLocation: row.StartLocation,
+		EndLocation:   row.EndLocation,
+		Distance:      row.Distance,
+		DurationMin:   row.DurationMin,
+		RangeUsed:     row.RangeUsed.Float64,
+		EnergyAdded:   row.EnergyAdded,
+	}
+	if row.StartGeofenceID.Valid {
+		a.StartGeofenceID = &row.StartGeofenceID.Int64
+	}
+	if row.EndGeofenceID.Valid {
+		a.EndGeofenceID = &row.EndGeofenceID.Int64
+	}
+	if row.Latitude.Valid && row.Longitude.Valid {
+		a.Latitude = &row.Latitude.Float64
+		a.Longitude = &row.Longitude.Float64
+	}
+	if row.Cost.Valid {
+		a.Cost = &row.Cost.Float64
+	}
+	if row.StartBatteryLevel.Valid {
+		level := int(row.StartBatteryLevel.Int64)
+		a.StartBatteryLevel = &level
+	}
+	if row.EndBatteryLevel.Valid {
+		level := int(row.EndBatteryLevel.Int64)
+		a.EndBatteryLevel = &level
+	}
+	return a
+}
+
+// tripDriveQuery 以旅程活动的形式查询已结束的行程，%[1]s 为续航类型，%[2]s 为附加条件
+const tripDriveQuery = `
+	SELECT
+		'drive' AS type,
+		d.id,
+		d.car_id,
+		d.start_date,
+		d.end_date,
+		COALESCE(sg.name, sa.display_name, 'Unknown') AS start_location,
+		COALESCE(eg.name, ea.display_name, 'Unknown') AS end_location,
+		d.start_geofence_id,
+		d.end_geofence_id,
+		ep.latitude,
+		ep.longitude,
+		COALESCE(d.distance, 0)::float8 AS distance,
+		COALESCE(d.duration_min, 0)::int AS duration_min,
+		(d.start_%[1]s_range_km - d.end_%[1]s_range_km)::float8 AS range_used,
+		0::float8 AS energy_added,
+		NULL::float8 AS cost,
+		sp.battery_level::int AS start_battery_level,
+		ep.battery_level::int AS end_battery_level
+	FROM drives d
+	LEFT JOIN addresses sa ON sa.id = d.start_address_id
+	LEFT JOIN addresses ea ON ea.id = d.end_address_id
+	LEFT JOIN geofences sg ON sg.id = d.start_geofence_id
+	LEFT JOIN geofences eg ON eg.id = d.end_geofence_id
+	LEFT JOIN positions sp ON sp.id = d.start_position_id
+	LEFT JOIN positions ep ON ep.id = d.end_position_id
+	WHERE d.end_date IS NOT NULL%[2]s
+`
+
+// tripChargeQuery 以旅程活动的形式查询已结束的充电，%[1]s 为附加条件
+const tripChargeQuery = `
+	SELECT
+		'charge' AS type,
+		cp.id,
+		cp.car_id,
+		cp.start_date,
+		cp.end_date,
+		COALESCE(g.name, a.display_name, 'Unknown') AS start_location,
+		COALESCE(g.name, a.display_name, 'Unknown') AS end_location,
+		cp.geofence_id AS start_geofence_id,
+		cp.geofence_id AS end_geofence_id,
+		p.latitude,
+		p.longitude,
+		0::float8 AS distance,
+		COALESCE(cp.duration_min, 0)::int AS duration_min,
+		NULL::float8 AS range_used,
+		COALESCE(cp.charge_energy_added, 0)::float8 AS energy_added,
+		cp.cost::float8 AS cost,
+		cp.start_battery_level::int AS start_battery_level,
+		cp.end_battery_level::int AS end_battery_level
+	FROM charging_processes cp
+	LEFT JOIN addresses a ON a.id = cp.address_id
+	LEFT JOIN geofences g ON g.id = cp.geofence_id
+	LEFT JOIN positions p ON p.id = cp.position_id
+	WHERE cp.end_date IS NOT NULL%[1]s
+`
+
+// GetActivities 获取时间范围内已结束的行程和充电，按开始时间升序
+func (r *tripRepository) GetActivities(ctx context.Context, carID int16, startDate, endDate *time.Time) ([]model.TripActivity, error) {
+	rangeType := getPreferredRange(ctx, r.db)
+
+	driveFilter, chargeFilter := " AND d.car_id = $1", " AND cp.car_id = $1"
+	args := []interface{}{carID}
+	argIdx := 2
+	if startDate != nil {
+		driveFilter += fmt.Sprintf(" AND d.start_date >= $%d", argIdx)
+		chargeFilter += fmt.Sprintf(" AND cp.start_date >= $%d", argIdx)
+		args = append(args, *startDate)
+		argIdx++
+	}
+	if endDate != nil {
+		driveFilter += fmt.Sprintf(" AND d.start_date <= $%d", argIdx)
+		chargeFilter += fmt.Sprintf(" AND cp.start_date <= $%d", argIdx)
+		args = append(args, *endDate)
+	}
+
+	query := fmt.Sprintf(tripDriveQuery, rangeType, driveFilter) +
+		"UNION ALL" + fmt.Sprintf(tripChargeQuery, chargeFilter) +
+		"ORDER BY start_date ASC"
+
+	rows, err := r.db.QueryxContext(ctx, query, args...)
+	if err != nil {
+		logger.Errorf("Failed to get trip activities for car %d: %v", carID, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	activities := []model.TripActivity{}
+	for rows.Next() {
+		var row tripActivityRow
+		if err := rows.StructScan(&row); err != nil {
+			logger.Warnf("Failed to scan trip activity: %v", err)
+			continue
+		}
+		activities = append(activities, row.activity())
+	}
+
+	return activities, nil
+}
+
+// GetDriveActivity 以旅程活动的形式获取单次行程，不存在或未结束时返回 nil
+func (r *tripRepository) GetDriveActivity(ctx context.Context, driveID int64) (*model.TripActivity, error) {
+	rangeType := getPreferredRange(ctx, r.db)
+	query := fmt.Sprintf(tripDriveQuery, rangeType, " AND d.id = $1")
+
+	var row tripActivityRow
+	if err := r.db.GetContext(ctx, &row, query, driveID); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		logger.Errorf("Failed to get drive %d for trip: %v", driveID, err)
+		return nil, err
+	}
+	activity := row.activity()
+	return &activity, nil
+}
+
+// GetTrack 获取多次行程合并后的轨迹，按时间排序，每次行程最多抽取约 pointsPerDrive 个点（保留首尾）
+func (r *tripRepository) GetTrack(ctx context.Context, driveIDs []int64, pointsPerDrive int) ([]model.DrivePosition, error) {
+	track := []model.DrivePosition{}
+	if len(driveIDs) == 0 {
+		return track, nil
+	}
+
+	query := `
+		WITH numbered AS (
+			SELECT
+				date,
+				latitude,
+				longitude,
+				COALESCE(speed, 0) as speed,
+				COALESCE(power, 0) as power,
+				COALESCE(battery_level, 0) as battery_level,
+				elevation,
+				ROW_NUMBER() OVER (PARTITION BY drive_id ORDER BY date) as rn,
+				COUNT(*) OVER (PARTITION BY drive_id) as total
+			FROM positions
+			WHERE drive_id = ANY($1)
+		)
+		SELECT date, latitude, longitude, speed, power, battery_level, elevation
+		FROM numbered
+		WHERE rn = 1 OR rn = total OR rn % GREATEST(1, total / $2) = 0
+		ORDER BY date ASC
+	`
+
+	rows, err := r.db.QueryxContext(ctx, query, pq.Array(driveIDs), pointsPerDrive)
+	if err != nil {
+		logger.Errorf("Failed to get trip track: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var row struct {
+			Date         time.Time     `db:"date"`
+			Latitude     float64       `db:"latitude"`
+			Longitude    float64       `db:"longitude"`
+			Speed        int           `db:"speed"`
+			Power        int           `db:"power"`
+			BatteryLevel int           `db:"battery_level"`
+			Elevation    sql.NullInt64 `db:"elevation"`
+		}
+		if err := rows.StructScan(&row); err != nil {
+			continue
+		}
+
+		pos := model.DrivePosition{
+			Date:         row.Date,
+			Latitude:     row.Latitude,
+			Longitude:    row.Longitude,
+			Speed:        row.Speed,
+			Power:        row.Power,
+			BatteryLevel: row.BatteryLevel,
+		}
+		if row.Elevation.Valid {
+			elev := int(row.Elevation.Int64)
+			pos.Elevation = &elev
+		}
+		track = append(track, pos)
+	}
+
+	return track, nil
+}
@@ -0,0 +1,229 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"teslamate-cyberui/internal/analytics"
+	"teslamate-cyberui/internal/logger"
+	"teslamate-cyberui/internal/model"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// UpdateRepository 软件更新仓储接口
+type UpdateRepository interface {
+	GetHistory(ctx context.Context, carID int16, startDate, endDate *time.Time) (*model.UpdateHistory, error)
+}
+
+type updateRepository struct {
+	db         *sqlx.DB
+	efficiency EfficiencyProvider
+}
+
+// NewUpdateRepository 创建软件更新仓储
+func NewUpdateRepository(db *sqlx.DB, efficiency EfficiencyProvider) UpdateRepository {
+	return &updateRepository{db: db, efficiency: efficiency}
+}
+
+// GetHistory 获取软件更新历史，以及每个版本生效期间（本次更新开始到下次更新开始）的行驶和充电统计
+// 参考 teslamate-grafana/system/updates.json，时间筛选作用于更新开始时间
+func (r *updateRepository) GetHistory(ctx context.Context, carID int16, startDate, endDate *time.Time) (*model.UpdateHistory, error) {
+	rangeType := getPreferredRange(ctx, r.db)
+	carEfficiency := r.efficiency.Get(ctx, carID)
+
+	whereClause := "WHERE true"
+	args := []interface{}{carID}
+	argIdx := 2
+	if startDate != nil {
+		whereClause += fmt.Sprintf(" AND u.start_date >= $%d", argIdx)
+		args = append(args, *startDate)
+		argIdx++
+	}
+	if endDate != nil {
+		whereClause += fmt.Sprintf(" AND u.start_date <= $%d", argIdx)
+		args = append(args, *endDate)
+		argIdx++
+	}
+
+	query := fmt.Sprintf(`
+		WITH u AS (
+			SELECT
+				id,
+				start_date,
+				end_date,
+				version,
+				LAG(start_date) OVER (ORDER BY start_date) AS prev_start_date,
+				LEAD(start_date) OVER (ORDER BY start_date) AS next_start_date
+			FROM updates
+			WHERE car_id = $1
+		)
+		SELECT
+			u.id,
+			u.start_date,
+			u.end_date,
+			COALESCE(u.version, '') AS version,
+			u.prev_start_date,
+			u.next_start_date,
+			COALESCE(d.drive_count, 0) AS drive_count,
+			COALESCE(d.distance, 0)::float8 AS distance,
+			COALESCE(d.range_used, 0)::float8 AS range_used,
+			COALESCE(d.range_distance, 0)::float8 AS range_distance,
+			COALESCE(c.charge_count, 0) AS charge_count,
+			COALESCE(c.energy_added, 0)::float8 AS energy_added
+		FROM u
+		LEFT JOIN LATERAL (
+			SELECT
+				COUNT(*) AS drive_count,
+				SUM(distance) AS distance,
+				SUM(start_%[1]s_range_km - end_%[1]s_range_km) AS range_used,
+				SUM(distance) FILTER (WHERE start_%[1]s_range_km IS NOT NULL AND end_%[1]s_range_km IS NOT NULL) AS range_distance
+			FROM drives
+			WHERE car_id = $1 AND end_date IS NOT NULL
+				AND start_date >= u.start_date
+				AND (u.next_start_date IS NULL OR start_date < u.next_start_date)
+		) d ON true
+		LEFT JOIN LATERAL (
+			SELECT
+				COUNT(*) AS charge_count,
+				SUM(charge_energy_added) AS energy_added
+			FROM charging_processes
+			WHERE car_id = $1 AND end_date IS NOT NULL
+				AND start_date >= u.start_date
+				AND (u.next_start_date IS NULL OR start_date < u.next_start_date)
+		) c ON true
+		%[2]s
+		ORDER BY u.start_date DESC
+	`, rangeType, whereClause)
+
+	rows, err := r.db.QueryxContext(ctx, query, args...)
+	if err != nil {
+		logger.Errorf("Failed to get update history for car %d: %v", carID, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	history := &model.UpdateHistory{
+		Items:            []model.SoftwareUpdate{},
+		Versions:         []model.VersionStats{},
+		EfficiencySource: carEfficiency.Source,
+	}
+	now := time.Now().UTC()
+	var gaps []float64
+	for rows.Next() {
+		var row struct {
+			ID            int64        `db:"id"`
+			StartDate     time.Time    `db:"start_date"`
+			EndDate       sql.NullTime `db:"end_date"`
+			Version       string       `db:"version"`
+			PrevStartDate sql.NullTime `db:"prev_start_date"`
+			NextStartDate sql.NullTime `db:"next_start_date"`
+			DriveCount    int          `db:"drive_count"`
+			Distance      float64      `db:"distance"`
+			RangeUsed     float64      `db:"range_used"`
+			RangeDistance float64      `db:"range_distance"`
+			ChargeCount   int          `db:"charge_count"`
+			EnergyAdded   float64      `db:"energy_added"`
+		}
+		if err := rows.StructScan(&row); err != nil {
+			logger.Warnf("Failed to scan software update: %v", err)
+			continue
+		}
+
+		item := model.SoftwareUpdate{
+			ID:          row.ID,
+			Version:     shortVersion(row.Version),
+			FullVersion: row.Version,
+			StartDate:   row.StartDate,
+			VersionUsage: model.VersionUsage{
+				DriveCount:  row.DriveCount,
+				Distance:    row.Distance,
+				EnergyUsed:  row.RangeUsed * carEfficiency.Value,
+				ChargeCount: row.ChargeCount,
+				EnergyAdded: row.EnergyAdded,
+			},
+		}
+		if row.EndDate.Valid {
+			item.EndDate = &row.EndDate.Time
+			minutes := row.EndDate.Time.Sub(row.StartDate).Minutes()
+			item.InstallDurationMin = &minutes
+		}
+		if row.PrevStartDate.Valid {
+			days := row.StartDate.Sub(row.PrevStartDate.Time).Hours() / 24
+			item.SinceLastUpdateDays = &days
+			gaps = append(gaps, days)
+		}
+		activeUntil := now
+		if row.NextStartDate.Valid {
+			item.ActiveUntil = &row.NextStartDate.Time
+			activeUntil = row.NextStartDate.Time
+		}
+		item.ActiveDays = activeUntil.Sub(row.StartDate).Hours() / 24
+		if row.RangeDistance > 0 {
+			e := row.RangeUsed * carEfficiency.Value * 1000 / row.RangeDistance
+			item.Efficiency = &e
+		}
+		history.Items = append(history.Items, item)
+	}
+
+	history.Count = len(history.Items)
+	if len(gaps) > 0 {
+		median := analytics.Median(gaps)
+		history.MedianDaysBetween = &median
+	}
+	history.Versions = groupVersions(history.Items)
+
+	return history, nil
+}
+
+// groupVersions 按版本号合并统计，结果按首次安装时间倒序
+// 能耗按有续航数据的行驶距离加权
+func groupVersions(items []model.SoftwareUpdate) []model.VersionStats {
+	byVersion := make(map[string]*model.VersionStats)
+	rangeDistance := make(map[string]float64)
+	for _, item := range items {
+		v, ok := byVersion[item.Version]
+		if !ok {
+			v = &model.VersionStats{Version: item.Version, FirstSeen: item.StartDate}
+			byVersion[item.Version] = v
+		}
+		if item.StartDate.Before(v.FirstSeen) {
+			v.FirstSeen = item.StartDate
+		}
+		v.Installs++
+		v.ActiveDays += item.ActiveDays
+		v.DriveCount += item.DriveCount
+		v.Distance += item.Distance
+		v.EnergyUsed += item.EnergyUsed
+		v.ChargeCount += item.ChargeCount
+		v.EnergyAdded += item.EnergyAdded
+		if item.Efficiency != nil && *item.Efficiency > 0 {
+			rangeDistance[item.Version] += item.EnergyUsed * 1000 / *item.Efficiency
+		}
+	}
+
+	versions := make([]model.VersionStats, 0, len(byVersion))
+	for version, v := range byVersion {
+		if d := rangeDistance[version]; d > 0 {
+			e := v.EnergyUsed * 1000 / d
+			v.Efficiency = &e
+		}
+		versions = append(versions, *v)
+	}
+	sort.Slice(versions, func(i, j int) bool {
+		return versions[i].FirstSeen.After(versions[j].FirstSeen)
+	})
+	return versions
+}
+
+// shortVersion 去掉版本号后的构建哈希，如 "2024.8.7 c5ba4c3" -> "2024.8.7"
+func shortVersion(version string) string {
+	if fields := strings.Fields(version); len(fields) > 0 {
+		return fields[0]
+	}
+	return version
+}
@@ -0,0 +1,154 @@
+package tariff
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"teslamate-cyberui/internal/logger"
+	"teslamate-cyberui/internal/model"
+	"teslamate-cyberui/internal/repository"
+)
+
+const (
+	// sampleBatchSize 每次查询电量采样的充电数，避免一次加载过多采样
+	sampleBatchSize = 200
+	// maxCost charging_processes.cost 为 numeric(6,2)，超过该值无法写回
+	maxCost = 9999.99
+)
+
+// Estimator 根据电价方案计算充电费用，并可写回 TeslaMate
+type Estimator struct {
+	repo *repository.Repository
+}
+
+// NewEstimator 创建费用计算器
+func NewEstimator(repo *repository.Repository) *Estimator {
+	return &Estimator{repo: repo}
+}
+
+// EstimateCharge 计算单次充电的费用，充电不存在或未结束时返回 nil
+func (e *Estimator) EstimateCharge(ctx context.Context, chargeID int64) (*model.ChargeCostEstimate, error) {
+	record, err := e.repo.Charge.GetBillingRecord(ctx, chargeID)
+	if err != nil || record == nil {
+		return nil, err
+	}
+	tariffs, err := e.repo.Tariff.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	samples, err := e.repo.Charge.GetEnergySamples(ctx, []int64{chargeID})
+	if err != nil {
+		return nil, err
+	}
+	estimate := estimate(tariffs, *record, samples[chargeID])
+	return &estimate, nil
+}
+
+// Recalculate 计算时间范围内每次充电的费用
+// apply 为 false 时只预览；为 true 时写回 charging_processes.cost。
+// 已有费用的充电只有 overwrite 为 true 时才会被覆盖，预览时同样标记出将被跳过的记录
+func (e *Estimator) Recalculate(ctx context.Context, carID int16, startDate, endDate *time.Time, overwrite, apply bool) (*model.ChargeCostRecalculation, error) {
+	tariffs, err := e.repo.Tariff.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	records, err := e.repo.Charge.GetBillingRecords(ctx, carID, startDate, endDate)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &model.ChargeCostRecalculation{
+		StartDate: startDate,
+		EndDate:   endDate,
+		Overwrite: overwrite,
+		Applied:   apply,
+		Totals:    map[string]float64{},
+		Items:     make([]model.ChargeCostEstimate, 0, len(records)),
+	}
+
+	for from := 0; from < len(records); from += sampleBatchSize {
+		batch := records[from:min(from+sampleBatchSize, len(records))]
+		ids := make([]int64, len(batch))
+		for i, r := range batch {
+			ids[i] = r.ID
+		}
+		samples, err := e.repo.Charge.GetEnergySamples(ctx, ids)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, record := range batch {
+			item := estimate(tariffs, record, samples[record.ID])
+			if item.Cost != nil {
+				result.Totals[item.Currency] = round(result.Totals[item.Currency]+*item.Cost, 2)
+			}
+			if item.Skipped == "" {
+				switch {
+				case record.Cost != nil && !overwrite:
+					item.Skipped = model.CostSkipHasCost
+				case record.Cost != nil && math.Abs(*record.Cost-*item.Cost) < 0.005:
+					item.Skipped = model.CostSkipUnchanged
+				case *item.Cost > maxCost:
+					item.Skipped = model.CostSkipWriteError
+				}
+			}
+			if item.Skipped == "" {
+				if apply {
+					if _, err := e.repo.Charge.UpdateCost(ctx, record.ID, item.Cost); err != nil {
+						logger.Errorf("Failed to write cost for charge %d: %v", record.ID, err)
+						item.Skipped = model.CostSkipWriteError
+					} else {
+						item.Updated = true
+						result.UpdatedCount++
+					}
+				} else {
+					result.UpdatedCount++
+				}
+			}
+			result.Items = append(result.Items, item)
+		}
+	}
+
+	result.Count = len(result.Items)
+	if apply {
+		logger.Infof("Recalculated charge costs for car %d: %d of %d charges updated", carID, result.UpdatedCount, result.Count)
+	}
+	return result, nil
+}
+
+// estimate 为一次充电选择电价方案并计算费用
+func estimate(tariffs []model.Tariff, record model.ChargeBilling, samples []model.ChargeEnergySample) model.ChargeCostEstimate {
+	item := model.ChargeCostEstimate{
+		ChargeID:     record.ID,
+		StartDate:    record.StartDate,
+		EndDate:      record.EndDate,
+		Location:     record.Location,
+		GeofenceID:   record.GeofenceID,
+		EnergyBilled: record.EnergyAdded,
+		CurrentCost:  record.Cost,
+		Breakdown:    []model.ChargeCostBreakdown{},
+	}
+	if record.EnergyUsed != nil && *record.EnergyUsed > item.EnergyBilled {
+		item.EnergyBilled = *record.EnergyUsed
+	}
+
+	t := Select(tariffs, record.GeofenceID, record.StartDate)
+	if t == nil {
+		item.Skipped = model.CostSkipNoTariff
+		return item
+	}
+	item.TariffID = &t.ID
+	item.TariffName = t.Name
+	item.Currency = t.Currency
+	item.SessionFee = t.SessionFee
+	if item.EnergyBilled <= 0 {
+		item.Skipped = model.CostSkipNoEnergy
+		return item
+	}
+
+	cost, breakdown := Compute(t, samples, item.EnergyBilled, record.StartDate, record.EndDate)
+	item.Cost = &cost
+	item.Breakdown = breakdown
+	return item
+}
@@ -0,0 +1,287 @@
+// Package tariff 按分时电价方案计算充电费用：根据充电过程中的电量采样把电量分摊到各电价时段
+package tariff
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"teslamate-cyberui/internal/model"
+)
+
+// BasePeriod 不在任何时段内的电量在明细中的名称
+const BasePeriod = "base"
+
+// Validate 校验电价方案
+func Validate(t *model.Tariff) error {
+	t.Name = strings.TrimSpace(t.Name)
+	if t.Name == "" {
+		return errors.New("name is required")
+	}
+	t.Currency = strings.TrimSpace(t.Currency)
+	if len(t.Currency) > 8 {
+		return errors.New("currency must be at most 8 characters")
+	}
+	if t.PricePerKWh < 0 || t.SessionFee < 0 {
+		return errors.New("pricePerKwh and sessionFee must not be negative")
+	}
+	if t.ValidFrom != nil && t.ValidUntil != nil && !t.ValidUntil.After(*t.ValidFrom) {
+		return errors.New("validUntil must be after validFrom")
+	}
+	t.Timezone = strings.TrimSpace(t.Timezone)
+	if t.Timezone != "" {
+		if _, err := time.LoadLocation(t.Timezone); err != nil {
+			return fmt.Errorf("unknown timezone %q", t.Timezone)
+		}
+	}
+	for i, p := range t.Periods {
+		name := p.Name
+		if name == "" {
+			return fmt.Errorf("period #%d: name is required", i+1)
+		}
+		if name == BasePeriod {
+			return fmt.Errorf("period %s: name %q is reserved", name, BasePeriod)
+		}
+		start, err := parseClock(p.Start)
+		if err != nil {
+			return fmt.Errorf("period %s: start: %v", name, err)
+		}
+		end, err := parseClock(p.End)
+		if err != nil {
+			return fmt.Errorf("period %s: end: %v", name, err)
+		}
+		if start == end {
+			return fmt.Errorf("period %s: start and end must differ", name)
+		}
+		for _, d := range p.Weekdays {
+			if d < 0 || d > 6 {
+				return fmt.Errorf("period %s: weekdays must be between 0 (Sunday) and 6", name)
+			}
+		}
+		if p.PricePerKWh < 0 {
+			return fmt.Errorf("period %s: pricePerKwh must not be negative", name)
+		}
+	}
+	return nil
+}
+
+// location 返回方案时段使用的时区，Timezone 为空或无法识别时使用服务器时区
+func location(t *model.Tariff) *time.Location {
+	if t.Timezone == "" {
+		return time.Local
+	}
+	loc, err := time.LoadLocation(t.Timezone)
+	if err != nil {
+		return time.Local
+	}
+	return loc
+}
+
+// parseClock 解析 HH:MM，返回从零点开始的分钟数
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(s))
+	if err != nil {
+		return 0, fmt.Errorf("expected HH:MM, got %q", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// Select 为充电选择电价方案：只考虑已启用且在 at 时生效的方案，
+// 围栏专属方案优先于默认方案，同类方案中生效时间最晚的优先；没有可用方案时返回 nil
+func Select(tariffs []model.Tariff, geofenceID *int64, at time.Time) *model.Tariff {
+	var best *model.Tariff
+	bestScore := -1
+	for i := range tariffs {
+		t := &tariffs[i]
+		if !t.Enabled {
+			continue
+		}
+		if t.ValidFrom != nil && at.Before(*t.ValidFrom) {
+			continue
+		}
+		if t.ValidUntil != nil && !at.Before(*t.ValidUntil) {
+			continue
+		}
+		score := 0
+		if t.GeofenceID != nil {
+			if geofenceID == nil || *t.GeofenceID != *geofenceID {
+				continue
+			}
+			score = 1
+		}
+		if best == nil || score > bestScore || (score == bestScore && newer(t, best)) {
+			best, bestScore = t, score
+		}
+	}
+	return best
+}
+
+// newer 判断 a 是否比 b 更晚生效，生效时间相同时 ID 大的优先
+func newer(a, b *model.Tariff) bool {
+	switch {
+	case a.ValidFrom == nil && b.ValidFrom == nil:
+		return a.ID > b.ID
+	case a.ValidFrom == nil:
+		return false
+	case b.ValidFrom == nil:
+		return true
+	case a.ValidFrom.Equal(*b.ValidFrom):
+		return a.ID > b.ID
+	default:
+		return a.ValidFrom.After(*b.ValidFrom)
+	}
+}
+
+// interval 一段时间内充入的电量
+type interval struct {
+	from, to time.Time
+	energy   float64
+}
+
+// Compute 按电价方案计算一次充电的费用
+// 相邻采样之间的电量增量按时间均匀分摊，跨越时段边界时按时长拆分；各段电量再按比例缩放到计费电量 billed，
+// 使充电损耗也按当时的电价计费。没有有效采样时把计费电量均匀分摊到整个充电过程。
+// 时段按方案的 Timezone 判断，为空时按服务器时区。
+// 返回含单次服务费的总费用（保留两位小数）和按时段汇总的明细
+func Compute(t *model.Tariff, samples []model.ChargeEnergySample, billed float64, start, end time.Time) (float64, []model.ChargeCostBreakdown) {
+	loc := location(t)
+	var intervals []interval
+	var sampled float64
+	if len(samples) > 0 && samples[0].EnergyAdded > 0 && samples[0].Date.After(start) {
+		intervals = append(intervals, interval{start, samples[0].Date, samples[0].EnergyAdded})
+		sampled += samples[0].EnergyAdded
+	}
+	for i := 1; i < len(samples); i++ {
+		delta := samples[i].EnergyAdded - samples[i-1].EnergyAdded
+		if delta <= 0 {
+			continue
+		}
+		intervals = append(intervals, interval{samples[i-1].Date, samples[i].Date, delta})
+		sampled += delta
+	}
+	if sampled <= 0 {
+		intervals = []interval{{start, end, billed}}
+		sampled = billed
+	}
+
+	breakdown := []model.ChargeCostBreakdown{}
+	index := make(map[string]int)
+	add := func(at time.Time, energy float64) {
+		name, price := priceAt(t, loc, at)
+		i, ok := index[name]
+		if !ok {
+			i = len(breakdown)
+			index[name] = i
+			breakdown = append(breakdown, model.ChargeCostBreakdown{Period: name, PricePerKWh: price})
+		}
+		breakdown[i].Energy += energy
+		breakdown[i].Cost += energy * price
+	}
+
+	scale := 0.0
+	if sampled > 0 {
+		scale = billed / sampled
+	}
+	for _, iv := range intervals {
+		energy := iv.energy * scale
+		if !iv.to.After(iv.from) {
+			add(iv.from, energy)
+			continue
+		}
+		total := iv.to.Sub(iv.from).Seconds()
+		for cur := iv.from; cur.Before(iv.to); {
+			next := nextBoundary(t, loc, cur)
+			if next.After(iv.to) {
+				next = iv.to
+			}
+			add(cur, energy*next.Sub(cur).Seconds()/total)
+			cur = next
+		}
+	}
+
+	cost := t.SessionFee
+	for i := range breakdown {
+		cost += breakdown[i].Cost
+		breakdown[i].Energy = round(breakdown[i].Energy, 3)
+		breakdown[i].Cost = round(breakdown[i].Cost, 2)
+	}
+	return round(cost, 2), breakdown
+}
+
+// priceAt 返回 at 时刻（按时区 loc）所在时段的名称和电价
+// 跨越午夜的时段在次日凌晨的部分按开始那天的星期判断
+func priceAt(t *model.Tariff, loc *time.Location, at time.Time) (string, float64) {
+	local := at.In(loc)
+	m := local.Hour()*60 + local.Minute()
+	weekday := int(local.Weekday())
+	for _, p := range t.Periods {
+		start, err1 := parseClock(p.Start)
+		end, err2 := parseClock(p.End)
+		if err1 != nil || err2 != nil || start == end {
+			continue
+		}
+		day := weekday
+		switch {
+		case start < end:
+			if m < start || m >= end {
+				continue
+			}
+		case m >= start:
+		case m < end:
+			day = (weekday + 6) % 7
+		default:
+			continue
+		}
+		if onWeekday(p.Weekdays, day) {
+			return p.Name, p.PricePerKWh
+		}
+	}
+	return BasePeriod, t.PricePerKWh
+}
+
+func onWeekday(weekdays []int, day int) bool {
+	if len(weekdays) == 0 {
+		return true
+	}
+	for _, d := range weekdays {
+		if d == day {
+			return true
+		}
+	}
+	return false
+}
+
+// nextBoundary 返回 after 之后最近的时段边界（时区 loc 中各时段的起止时刻以及零点），
+// 两个边界之间的电价不变
+func nextBoundary(t *model.Tariff, loc *time.Location, after time.Time) time.Time {
+	local := after.In(loc)
+	y, mo, d := local.Date()
+	minutes := []int{0}
+	for _, p := range t.Periods {
+		if start, err := parseClock(p.Start); err == nil {
+			minutes = append(minutes, start)
+		}
+		if end, err := parseClock(p.End); err == nil {
+			minutes = append(minutes, end)
+		}
+	}
+
+	var next time.Time
+	for _, m := range minutes {
+		candidate := time.Date(y, mo, d, m/60, m%60, 0, 0, loc)
+		if !candidate.After(local) {
+			candidate = time.Date(y, mo, d+1, m/60, m%60, 0, 0, loc)
+		}
+		if next.IsZero() || candidate.Before(next) {
+			next = candidate
+		}
+	}
+	return next
+}
+
+func round(v float64, digits int) float64 {
+	p := math.Pow(10, float64(digits))
+	return math.Round(v*p) / p
+}
@@ -0,0 +1,264 @@
+package tariff
+
+import (
+	"math"
+	"testing"
+	"time"
+	_ "time/tzdata"
+
+	"teslamate-cyberui/internal/model"
+)
+
+// testTariff 基础电价 0.30，夜间 22:00-06:00 为 0.10，周五晚 20:00 至次日 02:00 为 0.05
+func testTariff() *model.Tariff {
+	return &model.Tariff{
+		Name:        "home",
+		PricePerKWh: 0.30,
+		SessionFee:  1.5,
+		Timezone:    "Europe/Berlin",
+		Periods: model.TariffPeriods{
+			{Name: "friday-night", Start: "20:00", End: "02:00", Weekdays: []int{5}, PricePerKWh: 0.05},
+			{Name: "night", Start: "22:00", End: "06:00", PricePerKWh: 0.10},
+		},
+	}
+}
+
+// berlin 按柏林时间解析 "2006-01-02 15:04"
+func berlin(t *testing.T, s string) time.Time {
+	t.Helper()
+	loc, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatalf("load location: %v", err)
+	}
+	v, err := time.ParseInLocation("2006-01-02 15:04", s, loc)
+	if err != nil {
+		t.Fatalf("parse %q: %v", s, err)
+	}
+	return v
+}
+
+func TestPriceAt(t *testing.T) {
+	tariff := testTariff()
+	loc := location(tariff)
+	// 2024-01-04 为周四，2024-01-05 为周五
+	tests := []struct {
+		at     string
+		period string
+		price  float64
+	}{
+		{"2024-01-04 12:00", BasePeriod, 0.30},
+		{"2024-01-04 21:59", BasePeriod, 0.30},
+		{"2024-01-04 22:00", "night", 0.10},
+		{"2024-01-04 23:30", "night", 0.10},
+		{"2024-01-05 00:00", "night", 0.10},
+		{"2024-01-05 05:59", "night", 0.10},
+		{"2024-01-05 06:00", BasePeriod, 0.30},
+		// 周五 01:00 属于周四开始的时段，不是周五晚的时段
+		{"2024-01-05 01:00", "night", 0.10},
+		{"2024-01-05 20:00", "friday-night", 0.05},
+		{"2024-01-05 23:00", "friday-night", 0.05},
+		// 周五开始的时段延续到周六凌晨
+		{"2024-01-06 01:59", "friday-night", 0.05},
+		{"2024-01-06 02:00", "night", 0.10},
+		{"2024-01-06 20:00", BasePeriod, 0.30},
+	}
+	for _, tt := range tests {
+		period, price := priceAt(tariff, loc, berlin(t, tt.at))
+		if period != tt.period || price != tt.price {
+			t.Errorf("priceAt(%s) = %s %.2f, want %s %.2f", tt.at, period, price, tt.period, tt.price)
+		}
+	}
+}
+
+func TestPriceAtUsesTariffTimezone(t *testing.T) {
+	tariff := testTariff()
+	// 21:30 UTC 为柏林时间 22:30（冬令时 UTC+1）
+	at := time.Date(2024, 1, 4, 21, 30, 0, 0, time.UTC)
+	if period, _ := priceAt(tariff, location(tariff), at); period != "night" {
+		t.Errorf("period = %s, want night", period)
+	}
+	tariff.Timezone = "UTC"
+	if period, _ := priceAt(tariff, location(tariff), at); period != BasePeriod {
+		t.Errorf("period = %s, want %s", period, BasePeriod)
+	}
+}
+
+func TestNextBoundary(t *testing.T) {
+	tariff := testTariff()
+	loc := location(tariff)
+	tests := []struct{ after, want string }{
+		{"2024-01-04 12:00", "2024-01-04 20:00"},
+		{"2024-01-04 20:00", "2024-01-04 22:00"},
+		{"2024-01-04 23:00", "2024-01-05 00:00"},
+		{"2024-01-05 00:00", "2024-01-05 02:00"},
+		{"2024-01-05 02:00", "2024-01-05 06:00"},
+		{"2024-01-05 06:00", "2024-01-05 20:00"},
+	}
+	for _, tt := range tests {
+		if got, want := nextBoundary(tariff, loc, berlin(t, tt.after)), berlin(t, tt.want); !got.Equal(want) {
+			t.Errorf("nextBoundary(%s) = %s, want %s", tt.after, got.In(loc), want)
+		}
+	}
+}
+
+func TestCompute(t *testing.T) {
+	tests := []struct {
+		name      string
+		samples   [][2]interface{}
+		billed    float64
+		start     string
+		end       string
+		cost      float64
+		breakdown map[string][2]float64 // 时段 -> 电量、费用
+	}{
+		{
+			// 21:30-22:30 的 10 kWh 按时长平分到基础电价和夜间电价
+			name:      "split across boundary",
+			samples:   [][2]interface{}{{"2024-01-04 21:30", 0.0}, {"2024-01-04 22:30", 10.0}},
+			billed:    10,
+			start:     "2024-01-04 21:30",
+			end:       "2024-01-04 22:30",
+			cost:      1.5 + 5*0.30 + 5*0.10,
+			breakdown: map[string][2]float64{BasePeriod: {5, 1.5}, "night": {5, 0.5}},
+		},
+		{
+			// 计费电量大于采样电量时，损耗按各段比例计费
+			name:      "billed energy scales samples",
+			samples:   [][2]interface{}{{"2024-01-04 21:30", 0.0}, {"2024-01-04 22:30", 10.0}},
+			billed:    12,
+			start:     "2024-01-04 21:30",
+			end:       "2024-01-04 22:30",
+			cost:      1.5 + 6*0.30 + 6*0.10,
+			breakdown: map[string][2]float64{BasePeriod: {6, 1.8}, "night": {6, 0.6}},
+		},
+		{
+			// 跨越午夜的夜间时段，00:00 边界两侧电价相同
+			name:      "period across midnight",
+			samples:   [][2]interface{}{{"2024-01-04 23:00", 0.0}, {"2024-01-05 01:00", 8.0}},
+			billed:    8,
+			start:     "2024-01-04 23:00",
+			end:       "2024-01-05 01:00",
+			cost:      1.5 + 8*0.10,
+			breakdown: map[string][2]float64{"night": {8, 0.8}},
+		},
+		{
+			// 周五晚的时段延续到周六 02:00，之后为夜间电价
+			name:      "weekday period past midnight",
+			samples:   [][2]interface{}{{"2024-01-06 01:00", 0.0}, {"2024-01-06 03:00", 10.0}},
+			billed:    10,
+			start:     "2024-01-06 01:00",
+			end:       "2024-01-06 03:00",
+			cost:      1.5 + 5*0.05 + 5*0.10,
+			breakdown: map[string][2]float64{"friday-night": {5, 0.25}, "night": {5, 0.5}},
+		},
+		{
+			// 首个采样之前的电量从充电开始时间算起，电量回落的采样被忽略
+			name:      "energy before first sample and decreasing samples",
+			samples:   [][2]interface{}{{"2024-01-04 22:00", 4.0}, {"2024-01-04 23:00", 3.0}, {"2024-01-04 23:30", 9.0}},
+			billed:    10,
+			start:     "2024-01-04 21:00",
+			end:       "2024-01-04 23:30",
+			cost:      1.5 + 4*0.30 + 6*0.10,
+			breakdown: map[string][2]float64{BasePeriod: {4, 1.2}, "night": {6, 0.6}},
+		},
+		{
+			// 没有采样时按整个充电过程均匀分摊
+			name:      "no samples",
+			billed:    9,
+			start:     "2024-01-04 19:00",
+			end:       "2024-01-04 22:00",
+			cost:      1.5 + 9*0.30,
+			breakdown: map[string][2]float64{BasePeriod: {9, 2.7}},
+		},
+		{
+			name:      "session fee only",
+			billed:    0,
+			start:     "2024-01-04 12:00",
+			end:       "2024-01-04 12:00",
+			cost:      1.5,
+			breakdown: map[string][2]float64{BasePeriod: {0, 0}},
+		},
+	}
+	for _, tt := range tests {
+		var samples []model.ChargeEnergySample
+		for _, s := range tt.samples {
+			samples = append(samples, model.ChargeEnergySample{Date: berlin(t, s[0].(string)), EnergyAdded: s[1].(float64)})
+		}
+		cost, breakdown := Compute(testTariff(), samples, tt.billed, berlin(t, tt.start), berlin(t, tt.end))
+		if math.Abs(cost-tt.cost) > 0.001 {
+			t.Errorf("%s: cost = %.2f, want %.2f", tt.name, cost, tt.cost)
+		}
+		if len(breakdown) != len(tt.breakdown) {
+			t.Errorf("%s: breakdown = %+v, want %v", tt.name, breakdown, tt.breakdown)
+			continue
+		}
+		for _, b := range breakdown {
+			want, ok := tt.breakdown[b.Period]
+			if !ok || math.Abs(b.Energy-want[0]) > 0.001 || math.Abs(b.Cost-want[1]) > 0.001 {
+				t.Errorf("%s: period %s = %.3f kWh %.2f, want %v", tt.name, b.Period, b.Energy, b.Cost, want)
+			}
+		}
+	}
+}
+
+func TestSelect(t *testing.T) {
+	home, work := int64(1), int64(2)
+	jan := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	jun := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	tariffs := []model.Tariff{
+		{ID: 1, Name: "default", Enabled: true},
+		{ID: 2, Name: "default 2024", Enabled: true, ValidFrom: &jan},
+		{ID: 3, Name: "home", Enabled: true, GeofenceID: &home, ValidUntil: &jun},
+		{ID: 4, Name: "home disabled", Enabled: false, GeofenceID: &home},
+		{ID: 5, Name: "work a", Enabled: true, GeofenceID: &work, ValidFrom: &jan},
+		{ID: 6, Name: "work b", Enabled: true, GeofenceID: &work, ValidFrom: &jan},
+	}
+	tests := []struct {
+		name     string
+		geofence *int64
+		at       time.Time
+		want     int64
+	}{
+		{"newest default wins", nil, jun, 2},
+		{"default before validFrom", nil, jan.AddDate(0, 0, -1), 1},
+		{"geofence tariff beats default", &home, jan, 3},
+		{"validUntil is exclusive", &home, jun, 2},
+		{"same validFrom prefers higher id", &work, jun, 6},
+		{"unknown geofence uses default", new(int64), jun, 2},
+	}
+	for _, tt := range tests {
+		got := Select(tariffs, tt.geofence, tt.at)
+		if got == nil || got.ID != tt.want {
+			t.Errorf("%s: Select = %+v, want tariff %d", tt.name, got, tt.want)
+		}
+	}
+
+	if got := Select(tariffs[3:4], &home, jun); got != nil {
+		t.Errorf("Select with only disabled tariffs = %+v, want nil", got)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(*model.Tariff)
+		ok     bool
+	}{
+		{"valid", func(*model.Tariff) {}, true},
+		{"server timezone", func(t *model.Tariff) { t.Timezone = " " }, true},
+		{"missing name", func(t *model.Tariff) { t.Name = "" }, false},
+		{"unknown timezone", func(t *model.Tariff) { t.Timezone = "Mars/Olympus" }, false},
+		{"negative price", func(t *model.Tariff) { t.PricePerKWh = -1 }, false},
+		{"reserved period name", func(t *model.Tariff) { t.Periods[0].Name = BasePeriod }, false},
+		{"empty period", func(t *model.Tariff) { t.Periods[0].End = t.Periods[0].Start }, false},
+		{"bad clock", func(t *model.Tariff) { t.Periods[0].Start = "25:00" }, false},
+		{"bad weekday", func(t *model.Tariff) { t.Periods[0].Weekdays = []int{7} }, false},
+	}
+	for _, tt := range tests {
+		tariff := testTariff()
+		tt.modify(tariff)
+		if err := Validate(tariff); (err == nil) != tt.ok {
+			t.Errorf("%s: Validate = %v, want ok=%v", tt.name, err, tt.ok)
+		}
+	}
+}
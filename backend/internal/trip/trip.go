@@ -0,0 +1,296 @@
+// Package trip 将连续的行程和充电合并为旅程（如长途自驾），旅程不落库，每次请求时由行程和充电记录实时分组
+package trip
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sort"
+	"time"
+
+	"teslamate-cyberui/internal/model"
+	"teslamate-cyberui/internal/repository"
+)
+
+// 分组参数默认值
+const (
+	DefaultMaxGap      = 6 * time.Hour
+	DefaultMinDistance = 50.0
+)
+
+const (
+	// searchSpan 在查询时间范围两侧额外加载活动的跨度，使跨越查询边界的旅程也能完整分组，超过该长度的旅程可能被截断
+	searchSpan = 30 * 24 * time.Hour
+	// trackPoints 旅程合并轨迹的目标点数，按行程数平均分配
+	trackPoints = 2000
+	// minTrackPointsPerDrive 每次行程至少抽取的轨迹点数
+	minTrackPointsPerDrive = 20
+	// minParkMin 时间线中记录停留的最短时长（分钟）
+	minParkMin = 5
+)
+
+// ErrTripNotFound 行程不存在或不属于任何旅程
+var ErrTripNotFound = errors.New("trip not found")
+
+// Options 旅程分组参数
+type Options struct {
+	// MaxGap 相邻两段活动之间允许的最长停留，超过时断开旅程
+	MaxGap time.Duration
+	// MinDistance 旅程的最短总里程 (km)，用于过滤日常通勤
+	MinDistance float64
+	// BoundaryGeofences 边界地理围栏（如家、公司），到达时结束旅程
+	BoundaryGeofences []int64
+}
+
+func (o *Options) boundary(geofenceID *int64) bool {
+	if geofenceID == nil {
+		return false
+	}
+	for _, id := range o.BoundaryGeofences {
+		if id == *geofenceID {
+			return true
+		}
+	}
+	return false
+}
+
+// Group 将按开始时间排序的活动分组为旅程，返回每个旅程包含的活动
+// 规则：相邻两段活动的间隔超过 MaxGap 时断开；到达边界围栏的行程结束当前旅程，从边界围栏出发的行程开始新旅程；
+// 边界围栏内的充电（如在家充电）不属于任何旅程；不包含行程或总里程小于 MinDistance 的分组会被丢弃
+func Group(activities []model.TripActivity, opts Options) [][]model.TripActivity {
+	var groups [][]model.TripActivity
+	var current []model.TripActivity
+	var lastEnd time.Time
+
+	flush := func() {
+		if len(current) == 0 {
+			return
+		}
+		var distance float64
+		drives := 0
+		for _, a := range current {
+			if a.Type == model.TripItemDrive {
+				drives++
+				distance += a.Distance
+			}
+		}
+		if drives > 0 && distance >= opts.MinDistance {
+			groups = append(groups, current)
+		}
+		current = nil
+	}
+
+	for _, a := range activities {
+		if a.Type == model.TripItemCharge && opts.boundary(a.StartGeofenceID) {
+			flush()
+			continue
+		}
+		if len(current) > 0 {
+			if a.StartDate.Sub(lastEnd) > opts.MaxGap ||
+				(a.Type == model.TripItemDrive && opts.boundary(a.StartGeofenceID)) {
+				flush()
+			}
+		}
+
+		current = append(current, a)
+		if len(current) == 1 || a.EndDate.After(lastEnd) {
+			lastEnd = a.EndDate
+		}
+
+		if a.Type == model.TripItemDrive && opts.boundary(a.EndGeofenceID) {
+			flush()
+		}
+	}
+	flush()
+
+	return groups
+}
+
+// Summarize 计算一个旅程的汇总，能耗由续航消耗乘以车辆能效系数得到
+func Summarize(group []model.TripActivity, efficiency model.CarEfficiency) model.Trip {
+	first, last := group[0], group[len(group)-1]
+	t := model.Trip{
+		StartDate:         first.StartDate,
+		EndDate:           last.EndDate,
+		EfficiencySource:  efficiency.Source,
+		StartBatteryLevel: first.StartBatteryLevel,
+		EndBatteryLevel:   last.EndBatteryLevel,
+	}
+
+	var cost float64
+	hasCost := false
+	for _, a := range group {
+		if a.EndDate.After(t.EndDate) {
+			t.EndDate = a.EndDate
+		}
+		switch a.Type {
+		case model.TripItemDrive:
+			if t.DriveCount == 0 {
+				t.ID = a.ID
+				t.StartLocation = a.StartLocation
+			}
+			t.EndLocation = a.EndLocation
+			t.DriveCount++
+			t.Distance += a.Distance
+			t.DriveDurationMin += a.DurationMin
+			t.EnergyUsed += a.RangeUsed * efficiency.Value
+		case model.TripItemCharge:
+			t.ChargeCount++
+			t.ChargeDurationMin += a.DurationMin
+			t.EnergyAdded += a.EnergyAdded
+			if a.Cost != nil {
+				cost += *a.Cost
+				hasCost = true
+			}
+		}
+	}
+
+	t.DurationMin = minutes(t.EndDate.Sub(t.StartDate))
+	t.ParkDurationMin = max(t.DurationMin-t.DriveDurationMin-t.ChargeDurationMin, 0)
+	if hasCost {
+		t.Cost = &cost
+	}
+	if t.Distance > 0 {
+		t.Efficiency = t.EnergyUsed / t.Distance * 1000
+	}
+	return t
+}
+
+// Timeline 生成旅程的时间线，相邻两段活动之间超过 minParkMin 的间隔记为一次停留
+func Timeline(group []model.TripActivity, efficiency model.CarEfficiency) []model.TripTimelineItem {
+	items := make([]model.TripTimelineItem, 0, len(group)*2)
+	for i, a := range group {
+		if i > 0 {
+			prev := group[i-1]
+			if gap := minutes(a.StartDate.Sub(prev.EndDate)); gap >= minParkMin {
+				items = append(items, model.TripTimelineItem{
+					Type:        model.TripItemPark,
+					StartDate:   prev.EndDate,
+					EndDate:     a.StartDate,
+					DurationMin: gap,
+					Location:    prev.EndLocation,
+					Latitude:    prev.Latitude,
+					Longitude:   prev.Longitude,
+				})
+			}
+		}
+
+		item := model.TripTimelineItem{
+			Type:              a.Type,
+			ID:                a.ID,
+			StartDate:         a.StartDate,
+			EndDate:           a.EndDate,
+			DurationMin:       a.DurationMin,
+			StartBatteryLevel: a.StartBatteryLevel,
+			EndBatteryLevel:   a.EndBatteryLevel,
+		}
+		if a.Type == model.TripItemDrive {
+			item.StartLocation = a.StartLocation
+			item.EndLocation = a.EndLocation
+			item.Distance = a.Distance
+			item.EnergyUsed = a.RangeUsed * efficiency.Value
+		} else {
+			item.Location = a.StartLocation
+			item.Latitude = a.Latitude
+			item.Longitude = a.Longitude
+			item.EnergyAdded = a.EnergyAdded
+			item.Cost = a.Cost
+		}
+		items = append(items, item)
+	}
+	return items
+}
+
+// List 获取开始时间在范围内的旅程，按开始时间倒序分页
+func List(ctx context.Context, repo *repository.Repository, carID int16, startDate, endDate *time.Time, opts Options, page, pageSize int) (*model.ListResponse[model.Trip], error) {
+	var searchStart, searchEnd *time.Time
+	if startDate != nil {
+		t := startDate.Add(-searchSpan)
+		searchStart = &t
+	}
+	if endDate != nil {
+		t := endDate.Add(searchSpan)
+		searchEnd = &t
+	}
+
+	activities, err := repo.Trip.GetActivities(ctx, carID, searchStart, searchEnd)
+	if err != nil {
+		return nil, err
+	}
+	efficiency := repo.Efficiency.Get(ctx, carID)
+
+	trips := []model.Trip{}
+	for _, group := range Group(activities, opts) {
+		t := Summarize(group, efficiency)
+		if startDate != nil && t.StartDate.Before(*startDate) {
+			continue
+		}
+		if endDate != nil && t.StartDate.After(*endDate) {
+			continue
+		}
+		trips = append(trips, t)
+	}
+	sort.Slice(trips, func(i, j int) bool { return trips[i].StartDate.After(trips[j].StartDate) })
+
+	total := len(trips)
+	from := min((page-1)*pageSize, total)
+	to := min(from+pageSize, total)
+	return &model.ListResponse[model.Trip]{
+		Items: trips[from:to],
+		Pagination: model.Pagination{
+			Page:     page,
+			PageSize: pageSize,
+			Total:    &total,
+		},
+	}, nil
+}
+
+// Get 获取包含指定行程的旅程详情，行程不存在或不属于任何旅程时返回 ErrTripNotFound
+// 旅程 ID 为其第一次行程的 ID，传入旅程中其他行程的 ID 也会返回整个旅程
+func Get(ctx context.Context, repo *repository.Repository, driveID int64, opts Options) (*model.TripDetail, error) {
+	drive, err := repo.Trip.GetDriveActivity(ctx, driveID)
+	if err != nil {
+		return nil, err
+	}
+	if drive == nil {
+		return nil, ErrTripNotFound
+	}
+
+	searchStart := drive.StartDate.Add(-searchSpan)
+	searchEnd := drive.StartDate.Add(searchSpan)
+	activities, err := repo.Trip.GetActivities(ctx, drive.CarID, &searchStart, &searchEnd)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, group := range Group(activities, opts) {
+		var driveIDs []int64
+		found := false
+		for _, a := range group {
+			if a.Type == model.TripItemDrive {
+				driveIDs = append(driveIDs, a.ID)
+				found = found || a.ID == driveID
+			}
+		}
+		if !found {
+			continue
+		}
+
+		efficiency := repo.Efficiency.Get(ctx, drive.CarID)
+		track, err := repo.Trip.GetTrack(ctx, driveIDs, max(trackPoints/len(driveIDs), minTrackPointsPerDrive))
+		if err != nil {
+			return nil, err
+		}
+		return &model.TripDetail{
+			Trip:     Summarize(group, efficiency),
+			Timeline: Timeline(group, efficiency),
+			Track:    track,
+		}, nil
+	}
+
+	return nil, ErrTripNotFound
+}
+
+func minutes(d time.Duration) int {
+	return int(math.Round(d.Minutes()))
+}
@@ -0,0 +1,208 @@
+package trip
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"teslamate-cyberui/internal/model"
+)
+
+const home int64 = 1
+
+var base = time.Date(2024, 7, 1, 8, 0, 0, 0, time.UTC)
+
+// drive 构造从 base 起第 start 小时开始、持续 hours 小时的行程
+func drive(id int64, start, hours, distance float64, from, to *int64) model.TripActivity {
+	s := base.Add(time.Duration(start * float64(time.Hour)))
+	return model.TripActivity{
+		Type:            model.TripItemDrive,
+		ID:              id,
+		StartDate:       s,
+		EndDate:         s.Add(time.Duration(hours * float64(time.Hour))),
+		StartGeofenceID: from,
+		EndGeofenceID:   to,
+		Distance:        distance,
+		DurationMin:     int(hours * 60),
+		RangeUsed:       distance * 1.2,
+	}
+}
+
+// charge 构造从 base 起第 start 小时开始、持续 hours 小时的充电
+func charge(id int64, start, hours float64, geofence *int64) model.TripActivity {
+	s := base.Add(time.Duration(start * float64(time.Hour)))
+	return model.TripActivity{
+		Type:            model.TripItemCharge,
+		ID:              id,
+		StartDate:       s,
+		EndDate:         s.Add(time.Duration(hours * float64(time.Hour))),
+		StartGeofenceID: geofence,
+		EndGeofenceID:   geofence,
+		DurationMin:     int(hours * 60),
+		EnergyAdded:     30,
+	}
+}
+
+// ids 返回每个分组中活动的 ID
+func ids(groups [][]model.TripActivity) [][]int64 {
+	out := [][]int64{}
+	for _, g := range groups {
+		var group []int64
+		for _, a := range g {
+			group = append(group, a.ID)
+		}
+		out = append(out, group)
+	}
+	return out
+}
+
+func TestGroup(t *testing.T) {
+	h := home
+	opts := Options{MaxGap: 6 * time.Hour, MinDistance: 50, BoundaryGeofences: []int64{home}}
+	tests := []struct {
+		name       string
+		activities []model.TripActivity
+		want       [][]int64
+	}{
+		{
+			name:       "drives and charges within the gap form one trip",
+			activities: []model.TripActivity{drive(1, 0, 2, 150, nil, nil), charge(10, 2.5, 0.5, nil), drive(2, 3.5, 2, 150, nil, nil)},
+			want:       [][]int64{{1, 10, 2}},
+		},
+		{
+			name:       "gap equal to MaxGap does not split",
+			activities: []model.TripActivity{drive(1, 0, 1, 60, nil, nil), drive(2, 7, 1, 60, nil, nil)},
+			want:       [][]int64{{1, 2}},
+		},
+		{
+			name:       "gap longer than MaxGap splits",
+			activities: []model.TripActivity{drive(1, 0, 1, 60, nil, nil), drive(2, 7.5, 1, 60, nil, nil)},
+			want:       [][]int64{{1}, {2}},
+		},
+		{
+			name: "gap is measured from the latest end",
+			// 充电在行程 1 结束前就已结束，间隔从行程 1 的结束时间算起
+			activities: []model.TripActivity{drive(1, 0, 5, 60, nil, nil), charge(10, 1, 1, nil), drive(2, 10, 1, 60, nil, nil)},
+			want:       [][]int64{{1, 10, 2}},
+		},
+		{
+			name:       "arriving at a boundary ends the trip",
+			activities: []model.TripActivity{drive(1, 0, 2, 100, nil, &h), drive(2, 3, 2, 100, nil, nil)},
+			want:       [][]int64{{1}, {2}},
+		},
+		{
+			name:       "leaving a boundary starts a new trip",
+			activities: []model.TripActivity{drive(1, 0, 2, 100, nil, nil), drive(2, 3, 2, 100, &h, nil)},
+			want:       [][]int64{{1}, {2}},
+		},
+		{
+			name:       "round trip from home",
+			activities: []model.TripActivity{drive(1, 0, 2, 100, &h, nil), drive(2, 4, 2, 100, nil, &h), drive(3, 7, 1, 80, &h, nil)},
+			want:       [][]int64{{1, 2}, {3}},
+		},
+		{
+			name:       "charges inside a boundary are dropped and split trips",
+			activities: []model.TripActivity{drive(1, 0, 1, 60, nil, nil), charge(10, 1.5, 1, &h), drive(2, 3, 1, 60, nil, nil)},
+			want:       [][]int64{{1}, {2}},
+		},
+		{
+			name:       "trips shorter than MinDistance are dropped",
+			activities: []model.TripActivity{drive(1, 0, 1, 20, nil, nil), drive(2, 1.5, 1, 20, nil, nil), drive(3, 10, 1, 5, nil, nil)},
+			want:       [][]int64{},
+		},
+		{
+			name:       "MinDistance counts the whole trip",
+			activities: []model.TripActivity{drive(1, 0, 1, 30, nil, nil), drive(2, 1.5, 1, 30, nil, nil)},
+			want:       [][]int64{{1, 2}},
+		},
+		{
+			name:       "groups without drives are dropped",
+			activities: []model.TripActivity{charge(10, 0, 1, nil), drive(1, 20, 1, 80, nil, nil)},
+			want:       [][]int64{{1}},
+		},
+	}
+	for _, tt := range tests {
+		got := ids(Group(tt.activities, opts))
+		if !slices.EqualFunc(got, tt.want, slices.Equal[[]int64]) {
+			t.Errorf("%s: Group = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	level := func(v int) *int { return &v }
+	cost := 12.5
+
+	d1 := drive(1, 0, 2, 150, nil, nil)
+	d1.StartLocation, d1.EndLocation = "Home", "Service Area"
+	d1.StartBatteryLevel, d1.EndBatteryLevel = level(90), level(40)
+	c := charge(10, 2.5, 0.5, nil)
+	c.Cost = &cost
+	c2 := charge(11, 6, 0.25, nil)
+	d2 := drive(2, 3.5, 2, 100, nil, nil)
+	d2.StartLocation, d2.EndLocation = "Service Area", "Hotel"
+	d2.StartBatteryLevel, d2.EndBatteryLevel = level(80), level(45)
+
+	trip := Summarize([]model.TripActivity{d1, c, d2, c2}, model.CarEfficiency{Value: 0.15, Source: "car"})
+
+	if trip.ID != 1 || trip.StartLocation != "Home" || trip.EndLocation != "Hotel" {
+		t.Errorf("id/locations = %d %q -> %q", trip.ID, trip.StartLocation, trip.EndLocation)
+	}
+	if !trip.StartDate.Equal(base) || !trip.EndDate.Equal(base.Add(6*time.Hour+15*time.Minute)) {
+		t.Errorf("dates = %s - %s", trip.StartDate, trip.EndDate)
+	}
+	if trip.DriveCount != 2 || trip.ChargeCount != 2 || trip.Distance != 250 {
+		t.Errorf("counts = %d drives, %d charges, %v km", trip.DriveCount, trip.ChargeCount, trip.Distance)
+	}
+	// 375 分钟 = 240 行驶 + 45 充电 + 90 停留
+	if trip.DurationMin != 375 || trip.DriveDurationMin != 240 || trip.ChargeDurationMin != 45 || trip.ParkDurationMin != 90 {
+		t.Errorf("durations = %d total, %d drive, %d charge, %d park",
+			trip.DurationMin, trip.DriveDurationMin, trip.ChargeDurationMin, trip.ParkDurationMin)
+	}
+	// 续航消耗 250 * 1.2 = 300 km，乘以 0.15 kWh/km
+	if !approxEqual(trip.EnergyUsed, 45) || !approxEqual(trip.Efficiency, 180) || trip.EfficiencySource != "car" {
+		t.Errorf("energy = %v kWh, efficiency = %v Wh/km (%s)", trip.EnergyUsed, trip.Efficiency, trip.EfficiencySource)
+	}
+	if trip.EnergyAdded != 60 || trip.Cost == nil || *trip.Cost != 12.5 {
+		t.Errorf("energy added = %v, cost = %v", trip.EnergyAdded, trip.Cost)
+	}
+	// 起止电量取第一段和最后一段活动，最后一段充电没有电量记录
+	if trip.StartBatteryLevel == nil || *trip.StartBatteryLevel != 90 || trip.EndBatteryLevel != nil {
+		t.Errorf("battery = %v -> %v", trip.StartBatteryLevel, trip.EndBatteryLevel)
+	}
+
+	noCost := Summarize([]model.TripActivity{d1, charge(12, 2.5, 0.5, nil)}, model.CarEfficiency{Value: 0.15})
+	if noCost.Cost != nil {
+		t.Errorf("cost = %v, want nil without charge costs", *noCost.Cost)
+	}
+}
+
+func TestTimeline(t *testing.T) {
+	d1 := drive(1, 0, 1, 80, nil, nil)
+	d1.EndLocation = "Service Area"
+	// 充电在行程结束 3 分钟后开始，不记为停留
+	c := charge(10, 1.05, 0.5, nil)
+	c.StartLocation, c.EndLocation = "Service Area", "Service Area"
+	d2 := drive(2, 2, 1, 80, nil, nil)
+
+	items := Timeline([]model.TripActivity{d1, c, d2}, model.CarEfficiency{Value: 0.15})
+	var types []string
+	for _, item := range items {
+		types = append(types, item.Type)
+	}
+	want := []string{model.TripItemDrive, model.TripItemCharge, model.TripItemPark, model.TripItemDrive}
+	if !slices.Equal(types, want) {
+		t.Fatalf("timeline types = %v, want %v", types, want)
+	}
+	if park := items[2]; park.DurationMin != 27 || park.Location != "Service Area" || !park.StartDate.Equal(c.EndDate) {
+		t.Errorf("park = %+v", park)
+	}
+	if !approxEqual(items[0].EnergyUsed, 80*1.2*0.15) || items[1].Location != "Service Area" || items[1].EnergyAdded != 30 {
+		t.Errorf("drive energy = %v, charge = %+v", items[0].EnergyUsed, items[1])
+	}
+}
+
+func approxEqual(a, b float64) bool {
+	d := a - b
+	return d < 1e-9 && d > -1e-9
+}